@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"flag"
 	"lfg/config"
 	"lfg/core"
 	"lfg/pkg/types"
@@ -13,6 +14,9 @@ import (
 )
 
 func main() {
+	replan := flag.Bool("replan", false, "ignore persisted plans and plan every agent again")
+	flag.Parse()
+
 	configureLog(config.Env.EnvName)
 
 	// init context for graceful shutdown
@@ -24,27 +28,44 @@ func main() {
 	if err != nil {
 		log.Fatalf("fail to load config: %v", err)
 	}
+	if *replan {
+		for _, agentConfig := range config.AgentConfigs {
+			agentConfig.ForceReplan()
+		}
+	}
 
 	// trap signal for graceful shutdown
 	setupSignalHandler(cancel)
 
+	// 🌩️ fiber: rest API module
+	// @dev: started before bootstrap so plans can be approved through the API
+	fApp := core.SetupFiberApp()
+	go func() {
+		<-rootCtx.Done()
+		core.ShutdownFiberApp(fApp)
+	}()
+	apiDoneC := make(chan struct{})
+	go func() {
+		defer close(apiDoneC)
+		if err := fApp.Listen(":3000"); err != nil {
+			log.Panic(err)
+		}
+	}()
+
 	// 📊 core: lfg module
 	err = core.Bootstrap(rootCtx, *config)
 	if err != nil {
 		log.Panicf("fail to bootstrap app: %v", err)
 	}
-	go func() {
-		if err := core.Run(rootCtx); err != nil {
-			log.Errorf("Runtime error: %v", err)
-			cancel()
-		}
-	}()
-
-	// 🌩️ fiber: rest API module
-	fApp := core.SetupFiberApp()
-	if err := fApp.Listen(":3000"); err != nil {
-		log.Panic(err)
+	if err := core.Run(rootCtx); err != nil {
+		log.Errorf("Runtime error: %v", err)
+		cancel()
 	}
+
+	// wait for the API to shutdown
+	<-rootCtx.Done()
+	<-apiDoneC
+	log.Info("😴 shutdown gracefully")
 }
 
 func configureLog(envName types.EnvName) {
@@ -21,7 +21,13 @@ type NotificationConfig struct {
 }
 
 type PersistenceConfig struct {
-	// not implemented
+	Storage   types.StorageName `yaml:"storage"`   // `local` (default) | `s3`
+	Dir       string            `yaml:"dir"`       // local only; default `data`
+	Bucket    string            `yaml:"bucket"`    // s3 only
+	Prefix    string            `yaml:"prefix"`    // s3 only; optional key prefix
+	EnvPrefix string            `yaml:"envPrefix"` // s3 only; prefix matching `<prefix>_ACCESS_KEY` & `<prefix>_SECRET_KEY` in .env
+
+	SnapshotInterval string `yaml:"snapshotInterval"` // agent memory snapshot interval e.g. `1m` (default); also snapshotted on shutdown
 }
 
 type DatabaseConfig struct {
@@ -34,11 +40,90 @@ type ExchangeConfig struct {
 	Futures      bool               `yaml:"futures"`
 	SubAccountId uint               `yaml:"subAccountId"` // optional
 	IsCross      bool               `yaml:"isCross"`
+
+	MarketRefreshInterval string `yaml:"marketRefreshInterval"` // optional; markets reload interval, default 1h
+
+	Paper *PaperConfig `yaml:"paper"` // dummy only
+}
+
+// PaperConfig simulates trading on the market data of a real exchange
+type PaperConfig struct {
+	Source      types.ExchangeName `yaml:"source"`      // `bnf` | `hpl`; market data source, authenticated with envPrefix
+	Balance     float64            `yaml:"balance"`     // initial balance in USD, default 10000
+	SlippagePct float64            `yaml:"slippagePct"` // market order slippage (e.g. 0.0005 means 0.05% aka 5bps)
 }
 
 type AgentConfig struct {
-	Exchange []*string `yaml:"exchange"`
-	Prompt   string    `yaml:"prompt"`
+	Exchange     []*string        `yaml:"exchange"`
+	Prompt       string           `yaml:"prompt"`
+	Mode         types.AgentMode  `yaml:"mode"`         // `plan` (default, fixed task pipeline) | `tools` (runtime model calls the tasks on each run)
+	MaxSteps     int              `yaml:"maxSteps"`     // tools only; max model calls per run, default 10
+	MaxToolCalls int              `yaml:"maxToolCalls"` // tools only; max tool calls per run, default 20
+	Schedule     *ScheduleConfig  `yaml:"schedule"`     // optional; the task pipeline runs once if neither schedule nor triggers are set
+	Triggers     []*TriggerConfig `yaml:"triggers"`     // optional; runs the task pipeline on exchange stream events
+	Planning     *PlanningConfig  `yaml:"planning"`     // optional; defaults to interactive planning
+	Planner      *LLMConfig       `yaml:"planner"`      // optional; model generating & refining the plan
+	Runtime      *LLMConfig       `yaml:"runtime"`      // optional; model used by AI tasks while running the plan
+	Risk         *RiskConfig      `yaml:"risk"`         // optional; limits checked before any order of the agent reaches the exchange
+}
+
+type LLMConfig struct {
+	Provider    types.LLMProvider `yaml:"provider"`    // `openai` (default, any OpenAI compatible API) | `scripted`
+	Model       string            `yaml:"model"`       // default `openai/gpt-4o-mini`
+	Temperature *float64          `yaml:"temperature"` // default 0.23
+	BaseUrl     string            `yaml:"baseUrl"`     // default $OPENAI_BASE_URL or openrouter
+	ApiKeyEnv   string            `yaml:"apiKeyEnv"`   // env holding the API key, default `OPENAI_API_KEY`
+	Script      string            `yaml:"script"`      // scripted only; path to the response script
+	InputPrice  float64           `yaml:"inputPrice"`  // USD per 1M prompt tokens for cost estimates, default for known models
+	OutputPrice float64           `yaml:"outputPrice"` // USD per 1M completion tokens for cost estimates, default for known models
+}
+
+// RiskConfig limits the orders opening or increasing positions; 0 (or empty) disables a limit.
+// Exits (reduce-only orders & closing positions) are never rejected.
+type RiskConfig struct {
+	MaxOrderNotional float64  `yaml:"maxOrderNotional"` // max USD value of a single order (or batch)
+	MaxTotalNotional float64  `yaml:"maxTotalNotional"` // max USD value of open positions & resting orders, including the new order
+	MaxLeverage      int      `yaml:"maxLeverage"`      // max leverage of an order
+	MaxOpenPositions int      `yaml:"maxOpenPositions"` // max symbols with an open position at the same time
+	MaxDailyLoss     float64  `yaml:"maxDailyLoss"`     // USD realized loss since 00:00 UTC after which entries are rejected
+	EntryCooldown    string   `yaml:"entryCooldown"`    // min time between two entries e.g. `15m`
+	AllowedSymbols   []string `yaml:"allowedSymbols"`   // universal symbols the agent may enter e.g. `BTC_USD`; any if empty
+}
+
+type PlanningConfig struct {
+	Mode            types.PlanningMode `yaml:"mode"`            // `interactive` (default) | `auto` | `approval` | `failFast`
+	MaxRefineCount  int                `yaml:"maxRefineCount"`  // max refinement rounds, default 3
+	ApprovalTimeout string             `yaml:"approvalTimeout"` // approval only; e.g. `30m`, waits indefinitely if omitted
+	ForceReplan     bool               `yaml:"forceReplan"`     // ignore the persisted plan and plan again
+}
+
+type ScheduleConfig struct {
+	Every         string `yaml:"every"`         // fixed interval e.g. `15m`, `1h`, `90s`
+	Cron          string `yaml:"cron"`          // 5-field cron expression evaluated in UTC e.g. `*/15 * * * *`
+	AlignToCandle bool   `yaml:"alignToCandle"` // align `every` ticks to candle close (multiples of `every` since UTC midnight)
+	Delay         string `yaml:"delay"`         // optional settle delay after each tick e.g. `2s`
+	RunOnStart    bool   `yaml:"runOnStart"`    // run the pipeline once immediately before the first tick
+}
+
+type TriggerConfig struct {
+	Type      types.TriggerType    `yaml:"type"`      // `klineClose` | `markPriceCross` | `orderFill` | `marketListing`
+	Exchange  string               `yaml:"exchange"`  // exchange id, must be one of the agent's exchanges
+	Symbol    string               `yaml:"symbol"`    // universal symbol e.g. `BTC_USD`; optional on marketListing (any market)
+	Interval  types.Interval       `yaml:"interval"`  // klineClose only
+	Level     float64              `yaml:"level"`     // markPriceCross only
+	Direction types.CrossDirection `yaml:"direction"` // markPriceCross only: `up` | `down` | `any` (default)
+}
+
+// ForceReplan makes the agent ignore its persisted plan on startup
+func (c *AgentConfig) ForceReplan() {
+	if c.Planning == nil {
+		c.Planning = &PlanningConfig{}
+	}
+	c.Planning.ForceReplan = true
+}
+
+func (c *PlanningConfig) GetForceReplan() bool {
+	return c != nil && c.ForceReplan
 }
 
 func LoadConfig(envName types.EnvName) (*Config, error) {
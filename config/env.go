@@ -2,7 +2,7 @@ package config
 
 import (
 	"lfg/pkg/types"
-	"lfg/pkg/utils"
+	"os"
 
 	"strings"
 
@@ -17,7 +17,8 @@ type Environment struct {
 
 func init() {
 	godotenv.Load()
-	switch env := strings.ToLower(utils.LoadEnv("ENVIRONMENT")); env {
+	// @dev: an unset ENVIRONMENT falls back to local (testnets) e.g. when running the tests
+	switch env := strings.ToLower(os.Getenv("ENVIRONMENT")); env {
 	case "prod", "production":
 		Env.EnvName = types.EnvProd
 	case "dev", "staging":
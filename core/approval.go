@@ -0,0 +1,77 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"lfg/pkg/ai"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Approvals holds plans waiting for approval through the REST API
+var Approvals = &restApprover{
+	pending: make(map[string]*pendingApproval),
+}
+
+type pendingApproval struct {
+	AgentId     string           `json:"agentId"`
+	Plan        ai.ExecutionPlan `json:"plan"`
+	RequestedAt time.Time        `json:"requestedAt"`
+
+	resultC chan ai.PlanApproval
+}
+
+type restApprover struct {
+	mu      sync.Mutex
+	pending map[string]*pendingApproval // agentId -> pending approval
+}
+
+func (r *restApprover) RequestApproval(ctx context.Context, agentId string, plan ai.ExecutionPlan) (ai.PlanApproval, error) {
+	p := &pendingApproval{
+		AgentId:     agentId,
+		Plan:        plan,
+		RequestedAt: time.Now(),
+		resultC:     make(chan ai.PlanApproval, 1),
+	}
+	r.mu.Lock()
+	r.pending[agentId] = p
+	r.mu.Unlock()
+	defer func() {
+		r.mu.Lock()
+		if r.pending[agentId] == p {
+			delete(r.pending, agentId)
+		}
+		r.mu.Unlock()
+	}()
+
+	log.WithFields(log.Fields{"agent": agentId}).
+		Infof("📝 plan awaiting approval: POST /agents/%s/plan/approve or /agents/%s/plan/reject", agentId, agentId)
+
+	select {
+	case approval := <-p.resultC:
+		return approval, nil
+	case <-ctx.Done():
+		return ai.PlanApproval{}, ctx.Err()
+	}
+}
+
+func (r *restApprover) Get(agentId string) (*pendingApproval, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	p, exists := r.pending[agentId]
+	return p, exists
+}
+
+func (r *restApprover) Resolve(agentId string, approval ai.PlanApproval) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	p, exists := r.pending[agentId]
+	if !exists {
+		return fmt.Errorf("no plan awaiting approval for agent %v", agentId)
+	}
+	delete(r.pending, agentId)
+	p.resultC <- approval
+	return nil
+}
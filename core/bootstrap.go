@@ -4,6 +4,11 @@ import (
 	"context"
 	"fmt"
 	"lfg/config"
+	"lfg/pkg/ai"
+	"lfg/pkg/journal"
+	"lfg/pkg/storage"
+	"sync"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 )
@@ -13,30 +18,75 @@ func Bootstrap(ctx context.Context, config config.Config) error {
 
 	// register exchanges
 	for exchgId, exchgConfig := range config.ExchangeConfigs {
-		RegisterExchange(exchgId, exchgConfig)
+		RegisterExchange(ctx, exchgId, exchgConfig)
 		log.Infof("exchange '%v' registered", exchgId)
+		refreshInterval := MARKET_REFRESH_DEFAULT_INTERVAL
+		if exchgConfig.MarketRefreshInterval != "" {
+			var err error
+			refreshInterval, err = time.ParseDuration(exchgConfig.MarketRefreshInterval)
+			if err != nil || refreshInterval <= 0 {
+				return fmt.Errorf("invalid market refresh interval '%v' of exchange %v", exchgConfig.MarketRefreshInterval, exchgId)
+			}
+		}
+		if exchg, exists := Exchanges[exchgId]; exists {
+			go runMarketRefresh(ctx, exchgId, *exchg, refreshInterval)
+		}
 	}
 
-	// register agents and plan tasks
+	// register agents
 	for agentId, agentConfig := range config.AgentConfigs {
-		exchanges := make([]string, len(agentConfig.Exchange))
-		for i, e := range agentConfig.Exchange {
-			exchanges[i] = *e
-		}
-		if err := RegisterAgent(agentId, agentConfig.Prompt, exchanges); err != nil {
+		if err := RegisterAgent(agentId, agentConfig); err != nil {
 			return fmt.Errorf("failed to register agent %v: %w", agentId, err)
 		}
 		log.Infof("agent '%v' registered", agentId)
+	}
 
-		agent, exists := Agents[agentId]
-		if !exists {
-			return fmt.Errorf("agent %v not found", agentId)
+	// setup persistence
+	var store storage.Store
+	if config.Persistence != nil {
+		var err error
+		store, err = storage.New(config.Persistence)
+		if err != nil {
+			return fmt.Errorf("failed to setup persistence: %w", err)
 		}
-
-		if err := agent.Plan(ctx); err != nil {
-			return fmt.Errorf("failed to plan tasks for agent %v: %w", agentId, err)
+		if config.Persistence.SnapshotInterval != "" {
+			snapshotInterval, err = time.ParseDuration(config.Persistence.SnapshotInterval)
+			if err != nil || snapshotInterval <= 0 {
+				return fmt.Errorf("invalid snapshot interval '%v'", config.Persistence.SnapshotInterval)
+			}
 		}
-		log.Infof("agent %v tasks planned successfully: %v steps", agentId, len(agent.Tasks))
+		log.Infof("persistence enabled (%v)", config.Persistence.Storage)
+	}
+	setJournal(journal.New(store))
+	for _, agent := range Agents {
+		agent.EnableJournal(Journal)
+	}
+
+	// plan tasks of all agents concurrently
+	var wg sync.WaitGroup
+	errChan := make(chan error, len(Agents))
+	for agentId, agent := range Agents {
+		wg.Add(1)
+		go func(agentId string, agent *ai.Agent) {
+			defer wg.Done()
+			agent.Approver = Approvals
+			agent.Store = store
+			if err := agent.Plan(ctx); err != nil {
+				errChan <- fmt.Errorf("failed to plan tasks for agent %v: %w", agentId, err)
+				return
+			}
+			log.Infof("agent %v tasks planned successfully: %v steps", agentId, len(agent.Tasks))
+		}(agentId, agent)
+	}
+	wg.Wait()
+	close(errChan)
+
+	var errs []error
+	for err := range errChan {
+		errs = append(errs, err)
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("errors during planning: %v", errs)
 	}
 	return nil
 }
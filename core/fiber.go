@@ -1,6 +1,11 @@
 package core
 
 import (
+	"fmt"
+	"lfg/pkg/ai"
+	"lfg/pkg/journal"
+	"lfg/pkg/types"
+
 	"github.com/gofiber/fiber/v2"
 )
 
@@ -13,9 +18,100 @@ func SetupFiberApp() *fiber.App {
 		return c.JSON(fiber.Map{"success": true, "data": nil})
 	})
 
+	// plan approval
+	app.Get("/agents/:agentId/plan", getAgentPlan)
+	app.Post("/agents/:agentId/plan/approve", approveAgentPlan)
+	app.Post("/agents/:agentId/plan/reject", rejectAgentPlan)
+
+	// agent memory
+	app.Get("/agents/:agentId/memory", getAgentMemory)
+	app.Get("/agents/:agentId/memory/history", getAgentMemoryHistory)
+
+	// agent journal
+	app.Get("/agents/:agentId/journal", getAgentJournal)
+
 	return app
 }
 
 func ShutdownFiberApp(app *fiber.App) {
 	_ = app.Shutdown()
 }
+
+func errorResponse(c *fiber.Ctx, status int, err error) error {
+	return c.Status(status).JSON(fiber.Map{"success": false, "error": err.Error()})
+}
+
+func getAgentPlan(c *fiber.Ctx) error {
+	agentId := c.Params("agentId")
+	if pending, exists := Approvals.Get(agentId); exists {
+		return c.JSON(fiber.Map{"success": true, "data": fiber.Map{"status": "pending", "plan": pending.Plan, "requestedAt": pending.RequestedAt}})
+	}
+	agent, exists := getAgent(agentId)
+	if !exists {
+		return errorResponse(c, fiber.StatusNotFound, fmt.Errorf("agent %v not found", agentId))
+	}
+	plan := agent.GetExecutionPlan()
+	if plan == nil {
+		return c.JSON(fiber.Map{"success": true, "data": fiber.Map{"status": "planning", "plan": nil}})
+	}
+	return c.JSON(fiber.Map{"success": true, "data": fiber.Map{"status": "approved", "plan": plan}})
+}
+
+func approveAgentPlan(c *fiber.Ctx) error {
+	if err := Approvals.Resolve(c.Params("agentId"), ai.PlanApproval{Approved: true}); err != nil {
+		return errorResponse(c, fiber.StatusNotFound, err)
+	}
+	return c.JSON(fiber.Map{"success": true, "data": nil})
+}
+
+func rejectAgentPlan(c *fiber.Ctx) error {
+	var body struct {
+		Comment string `json:"comment"`
+	}
+	if err := c.BodyParser(&body); err != nil || body.Comment == "" {
+		return errorResponse(c, fiber.StatusBadRequest, fmt.Errorf("rejection requires a json body with a non-empty 'comment'"))
+	}
+	if err := Approvals.Resolve(c.Params("agentId"), ai.PlanApproval{Approved: false, Comment: body.Comment}); err != nil {
+		return errorResponse(c, fiber.StatusNotFound, err)
+	}
+	return c.JSON(fiber.Map{"success": true, "data": nil})
+}
+
+func getAgentMemory(c *fiber.Ctx) error {
+	agent, exists := getAgent(c.Params("agentId"))
+	if !exists {
+		return errorResponse(c, fiber.StatusNotFound, fmt.Errorf("agent %v not found", c.Params("agentId")))
+	}
+	return c.JSON(fiber.Map{"success": true, "data": agent.Memory.Snapshot()})
+}
+
+// query: `key` (optional, all keys if omitted), `limit` (optional, default 50)
+func getAgentMemoryHistory(c *fiber.Ctx) error {
+	agent, exists := getAgent(c.Params("agentId"))
+	if !exists {
+		return errorResponse(c, fiber.StatusNotFound, fmt.Errorf("agent %v not found", c.Params("agentId")))
+	}
+	limit := c.QueryInt("limit", 50)
+	return c.JSON(fiber.Map{"success": true, "data": agent.Memory.History(c.Query("key"), limit)})
+}
+
+// query: `runId`, `task`, `kind` (`llm` | `memory` | `order`) (all optional), `limit` (optional, default 100);
+// usage totals the model calls of the returned entries
+func getAgentJournal(c *fiber.Ctx) error {
+	agentId := c.Params("agentId")
+	if _, exists := getAgent(agentId); !exists {
+		return errorResponse(c, fiber.StatusNotFound, fmt.Errorf("agent %v not found", agentId))
+	}
+	agentJournal := getJournal()
+	if agentJournal == nil {
+		return errorResponse(c, fiber.StatusServiceUnavailable, fmt.Errorf("journal is not ready"))
+	}
+	entries := agentJournal.Query(journal.Filter{
+		AgentId: agentId,
+		RunId:   c.Query("runId"),
+		Task:    c.Query("task"),
+		Kind:    types.JournalKind(c.Query("kind")),
+		Limit:   c.QueryInt("limit", 100),
+	})
+	return c.JSON(fiber.Map{"success": true, "data": fiber.Map{"entries": entries, "usage": journal.Summarize(entries)}})
+}
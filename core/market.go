@@ -0,0 +1,36 @@
+package core
+
+import (
+	"context"
+	"lfg/pkg/exchange"
+	"lfg/pkg/types"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const MARKET_REFRESH_DEFAULT_INTERVAL = time.Hour
+
+// runMarketRefresh reloads the markets of the exchange every `interval` until ctx is cancelled;
+// listings & delistings are logged, agents subscribe to them through `marketListing` triggers
+func runMarketRefresh(ctx context.Context, exchgId string, exchg exchange.Exchange, interval time.Duration) {
+	logger := log.WithFields(log.Fields{
+		"exchange": exchgId,
+	})
+	exchg.SubscribeMarketEvents(ctx, func(event types.MarketEvent) {
+		logger.Infof("market %v %v", event.LocSymbol, event.Type)
+	})
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := exchg.RefreshMarkets(); err != nil {
+				logger.Warnf("fail to refresh markets: %v", err)
+			}
+		}
+	}
+}
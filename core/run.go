@@ -4,24 +4,54 @@ import (
 	"context"
 	"fmt"
 	"lfg/pkg/ai"
+	"lfg/pkg/schedule"
 	"sync"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 )
 
+// Run executes every agent's task pipeline; scheduled and triggered agents keep
+// running until ctx is cancelled, agents with neither run once
 func Run(ctx context.Context) error {
 	log.Info("🦿 Running...")
 
 	var wg sync.WaitGroup
 	errChan := make(chan error, len(Agents))
-	for _, agent := range Agents {
+	for agentId, agent := range Agents {
 		wg.Add(1)
-		go func(agent *ai.Agent) {
+		go func(agent *ai.Agent, sched schedule.Schedule) {
 			defer wg.Done()
-			if err := agent.Execute(ctx); err != nil {
-				errChan <- err
+			if agent.Store != nil {
+				defer snapshotMemory(agent) // on shutdown, after the last run
 			}
-		}(agent)
+			triggers := agent.Config.Triggers
+			if sched == nil && len(triggers) == 0 {
+				result, err := agent.Execute(ctx)
+				log.WithFields(log.Fields{"agent": agent.Id}).Info(result.Summary())
+				if err != nil {
+					errChan <- err
+				}
+				return
+			}
+
+			var runWg sync.WaitGroup
+			defer runWg.Wait() // wait for in-flight runs before returning
+			if agent.Store != nil {
+				go runSnapshots(ctx, agent)
+			}
+			if len(triggers) > 0 {
+				if err := subscribeTriggers(ctx, agent, triggers, &runWg); err != nil {
+					errChan <- fmt.Errorf("agent %v: %w", agent.Id, err)
+					return
+				}
+			}
+			if sched != nil {
+				runSchedule(ctx, agent, sched, &runWg)
+			} else {
+				<-ctx.Done()
+			}
+		}(agent, Schedules[agentId])
 	}
 	go func() {
 		wg.Wait()
@@ -38,3 +68,46 @@ func Run(ctx context.Context) error {
 	}
 	return nil
 }
+
+// runSchedule re-executes the agent pipeline on every tick until ctx is cancelled.
+// A tick that fires while the previous run is still in progress is skipped.
+func runSchedule(ctx context.Context, agent *ai.Agent, sched schedule.Schedule, runWg *sync.WaitGroup) {
+	logger := log.WithFields(log.Fields{
+		"agent": agent.Id,
+	})
+
+	tick := func(tickTime time.Time) {
+		runWg.Add(1)
+		go func() {
+			defer runWg.Done()
+			result, executed, err := agent.TryExecute(ctx)
+			if !executed {
+				logger.Warnf("skip tick %v: previous run still in progress", tickTime.Format(time.RFC3339))
+				return
+			}
+			logger.Info(result.Summary())
+			if err != nil {
+				logger.Errorf("scheduled run failed: %v", err)
+			}
+		}()
+	}
+
+	if agent.Config.Schedule.RunOnStart {
+		tick(time.Now())
+	}
+
+	for {
+		next := sched.Next(time.Now())
+		logger.Debugf("next run at %v", next.Format(time.RFC3339))
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			logger.Info("💤 schedule stopped")
+			return
+		case tickTime := <-timer.C:
+			tick(tickTime)
+		}
+	}
+}
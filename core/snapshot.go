@@ -0,0 +1,40 @@
+package core
+
+import (
+	"context"
+	"lfg/pkg/ai"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const SNAPSHOT_DEFAULT_INTERVAL = time.Minute
+
+// interval of agent memory snapshots, set by Bootstrap when persistence is configured
+var snapshotInterval = SNAPSHOT_DEFAULT_INTERVAL
+
+// runSnapshots periodically snapshots the agent memory until ctx is cancelled
+func runSnapshots(ctx context.Context, agent *ai.Agent) {
+	ticker := time.NewTicker(snapshotInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			snapshotMemory(agent)
+		}
+	}
+}
+
+// a snapshot due while the agent is running is skipped, the next tick takes it
+func snapshotMemory(agent *ai.Agent) {
+	saved, err := agent.SaveMemory()
+	if err != nil {
+		log.WithFields(log.Fields{"agent": agent.Id}).Errorf("fail to snapshot memory: %v", err)
+		return
+	}
+	if !saved && agent.Store != nil {
+		log.WithFields(log.Fields{"agent": agent.Id}).Debug("skip memory snapshot: run in progress")
+	}
+}
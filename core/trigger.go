@@ -0,0 +1,170 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"lfg/config"
+	"lfg/pkg/ai"
+	"lfg/pkg/exchange"
+	"lfg/pkg/stream"
+	"lfg/pkg/types"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const TRIGGER_MAX_DELAY_MS = 5000 // drop stream events older than this
+
+func validateTrigger(trigger *config.TriggerConfig, agentConfig *config.AgentConfig) error {
+	found := false
+	for _, exchangeId := range agentConfig.Exchange {
+		found = found || *exchangeId == trigger.Exchange
+	}
+	if !found {
+		return fmt.Errorf("trigger exchange '%v' is not one of the agent exchanges", trigger.Exchange)
+	}
+	if trigger.Symbol == "" && trigger.Type != types.TriggerMarketListing {
+		return fmt.Errorf("trigger symbol is required")
+	}
+	switch trigger.Type {
+	case types.TriggerKLineClose:
+		if trigger.Interval == "" {
+			return fmt.Errorf("%v trigger requires interval", trigger.Type)
+		}
+	case types.TriggerMarkPriceCross:
+		if trigger.Level <= 0 {
+			return fmt.Errorf("%v trigger requires a positive level", trigger.Type)
+		}
+		switch trigger.Direction {
+		case "":
+			trigger.Direction = types.CrossDirectionAny
+		case types.CrossDirectionUp, types.CrossDirectionDown, types.CrossDirectionAny:
+		default:
+			return fmt.Errorf("unknown cross direction: %v", trigger.Direction)
+		}
+	case types.TriggerOrderFill, types.TriggerMarketListing:
+	default:
+		return fmt.Errorf("unknown trigger type: %v", trigger.Type)
+	}
+	return nil
+}
+
+// subscribeTriggers wires every agent trigger to its exchange stream; each matching event
+// runs the task pipeline in the background (tracked by runWg), skipping if a run is in progress
+func subscribeTriggers(ctx context.Context, agent *ai.Agent, triggers []*config.TriggerConfig, runWg *sync.WaitGroup) error {
+	logger := log.WithFields(log.Fields{
+		"agent": agent.Id,
+	})
+
+	fire := func(evt ai.TriggerEvent) {
+		runWg.Add(1)
+		go func() {
+			defer runWg.Done()
+			result, executed, err := agent.TryExecuteOnTrigger(ctx, evt)
+			if !executed {
+				logger.Warnf("skip %v trigger on %v: previous run still in progress", evt.Type, evt.Symbol)
+				return
+			}
+			if result != nil {
+				logger.Info(result.Summary())
+			}
+			if err != nil {
+				logger.Errorf("triggered run failed: %v", err)
+			}
+		}()
+	}
+
+	for _, trigger := range triggers {
+		exchg, exists := Exchanges[trigger.Exchange]
+		if !exists {
+			return fmt.Errorf("trigger exchange '%v' not registered", trigger.Exchange)
+		}
+		if err := subscribeTrigger(ctx, *exchg, trigger, fire); err != nil {
+			return fmt.Errorf("fail to subscribe %v trigger on %v %v: %w", trigger.Type, trigger.Exchange, trigger.Symbol, err)
+		}
+		logger.Infof("%v trigger subscribed on %v %v", trigger.Type, trigger.Exchange, trigger.Symbol)
+	}
+	return nil
+}
+
+func subscribeTrigger(ctx context.Context, exchg exchange.Exchange, trigger *config.TriggerConfig, fire func(ai.TriggerEvent)) error {
+	newEvent := func() ai.TriggerEvent {
+		return ai.TriggerEvent{
+			Type:       trigger.Type,
+			ExchangeId: trigger.Exchange,
+			Symbol:     trigger.Symbol,
+		}
+	}
+
+	switch trigger.Type {
+	case types.TriggerKLineClose:
+		// @dev: stream events are updates of the open kline; a change of open time
+		// means the previous kline has closed, so its last update is the closed kline
+		var mu sync.Mutex
+		var lastKLine *types.KLineEvent
+		_, err := exchg.SubscribeKLineStream(ctx, trigger.Symbol, trigger.Interval, nil, func(_ stream.Stream, kLine types.KLineEvent) {
+			mu.Lock()
+			defer mu.Unlock()
+			closed := lastKLine
+			lastKLine = &kLine
+			if closed == nil || !kLine.OpenTime.After(closed.OpenTime) {
+				return
+			}
+			evt := newEvent()
+			evt.Time = closed.CloseTime
+			evt.KLine = closed
+			fire(evt)
+		}, nil, TRIGGER_MAX_DELAY_MS)
+		return err
+
+	case types.TriggerMarkPriceCross:
+		var mu sync.Mutex
+		lastPrice := 0.0
+		_, err := exchg.SubscribeMarkPriceStream(ctx, trigger.Symbol, nil, func(_ stream.Stream, markPrice types.MarkPriceEvent) {
+			mu.Lock()
+			defer mu.Unlock()
+			prevPrice := lastPrice
+			lastPrice = markPrice.Price
+			if prevPrice == 0 {
+				return
+			}
+			crossUp := prevPrice < trigger.Level && markPrice.Price >= trigger.Level
+			crossDown := prevPrice > trigger.Level && markPrice.Price <= trigger.Level
+			if (crossUp && trigger.Direction != types.CrossDirectionDown) || (crossDown && trigger.Direction != types.CrossDirectionUp) {
+				evt := newEvent()
+				evt.Time = markPrice.Time
+				evt.MarkPrice = &markPrice
+				fire(evt)
+			}
+		}, nil, TRIGGER_MAX_DELAY_MS)
+		return err
+
+	case types.TriggerOrderFill:
+		_, err := exchg.SubscribeOrderStream(ctx, trigger.Symbol, nil, func(_ stream.Stream, order types.OrderEvent) {
+			if order.OrderStatus != types.OrderStatusFilled {
+				return
+			}
+			evt := newEvent()
+			evt.Time = order.Time
+			evt.Order = &order
+			fire(evt)
+		}, nil)
+		return err
+
+	case types.TriggerMarketListing:
+		exchg.SubscribeMarketEvents(ctx, func(market types.MarketEvent) {
+			if trigger.Symbol != "" && market.Symbol != trigger.Symbol {
+				return
+			}
+			evt := newEvent()
+			evt.Symbol = market.Symbol
+			evt.Time = market.Time
+			evt.Market = &market
+			fire(evt)
+		})
+		return nil
+
+	default:
+		return fmt.Errorf("unknown trigger type: %v", trigger.Type)
+	}
+}
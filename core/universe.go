@@ -1,36 +1,96 @@
 package core
 
 import (
+	"context"
+	"fmt"
 	"lfg/config"
 	"lfg/pkg/ai"
 	"lfg/pkg/exchange"
+	"lfg/pkg/journal"
+	"lfg/pkg/risk"
+	"lfg/pkg/schedule"
+	"sync"
 )
 
 var Exchanges map[string]*exchange.Exchange
 var Agents map[string]*ai.Agent
+var AgentConfigs map[string]*config.AgentConfig
+var Schedules map[string]schedule.Schedule // agentId -> schedule; agents without schedule run once
+var Journal *journal.Journal               // decisions of all agents, set on bootstrap
+
+// @dev: the REST API is served while bootstrapping, its handlers read Agents & Journal
+// through getAgent & getJournal while they are written under agentsMu
+var agentsMu sync.RWMutex
 
 func init() {
 	Exchanges = make(map[string]*exchange.Exchange)
 	Agents = make(map[string]*ai.Agent)
+	AgentConfigs = make(map[string]*config.AgentConfig)
+	Schedules = make(map[string]schedule.Schedule)
 }
 
-func RegisterAgent(agentId string, prompt string, exchangeIds []string) error {
+func RegisterAgent(agentId string, agentConfig *config.AgentConfig) error {
 	agentExchanges := make(map[string]*exchange.Exchange)
-	for _, exchangeId := range exchangeIds {
-		if exchange, exists := Exchanges[exchangeId]; exists {
-			agentExchanges[exchangeId] = exchange
+	for _, exchangeId := range agentConfig.Exchange {
+		exchg, exists := Exchanges[*exchangeId]
+		if !exists {
+			continue
+		}
+		if agentConfig.Risk != nil {
+			guard, err := risk.NewGuard(agentId, *exchg, agentConfig.Risk)
+			if err != nil {
+				return fmt.Errorf("invalid risk policy on %v: %w", *exchangeId, err)
+			}
+			var guarded exchange.Exchange = guard
+			exchg = &guarded
+		}
+		agentExchanges[*exchangeId] = exchg
+	}
+	for _, trigger := range agentConfig.Triggers {
+		if err := validateTrigger(trigger, agentConfig); err != nil {
+			return fmt.Errorf("invalid trigger: %w", err)
+		}
+	}
+	if agentConfig.Schedule != nil {
+		sched, err := schedule.New(agentConfig.Schedule)
+		if err != nil {
+			return fmt.Errorf("invalid schedule: %w", err)
 		}
+		Schedules[agentId] = sched
 	}
-	agent, err := ai.NewAgent(agentId, prompt, agentExchanges)
+	agent, err := ai.NewAgent(agentId, agentConfig, agentExchanges)
 	if err != nil {
 		return err
 	}
+	agentsMu.Lock()
 	Agents[agentId] = agent
+	agentsMu.Unlock()
+	AgentConfigs[agentId] = agentConfig
 	return nil
 }
 
-func RegisterExchange(exchgId string, exchgConfig *config.ExchangeConfig) error {
-	exchange, err := exchange.NewExchange(exchgId, exchgConfig)
+func getAgent(agentId string) (*ai.Agent, bool) {
+	agentsMu.RLock()
+	defer agentsMu.RUnlock()
+	agent, exists := Agents[agentId]
+	return agent, exists
+}
+
+func setJournal(j *journal.Journal) {
+	agentsMu.Lock()
+	defer agentsMu.Unlock()
+	Journal = j
+}
+
+// nil until bootstrap sets it up
+func getJournal() *journal.Journal {
+	agentsMu.RLock()
+	defer agentsMu.RUnlock()
+	return Journal
+}
+
+func RegisterExchange(ctx context.Context, exchgId string, exchgConfig *config.ExchangeConfig) error {
+	exchange, err := exchange.NewExchange(ctx, exchgId, exchgConfig)
 	if err != nil {
 		return err
 	}
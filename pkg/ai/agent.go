@@ -1,40 +1,57 @@
 package ai
 
 import (
-	"bufio"
 	"context"
 	"encoding/json"
 	"fmt"
-	"os"
+	"strings"
+	"sync"
+	"time"
 
+	"lfg/config"
 	"lfg/pkg/exchange"
+	"lfg/pkg/journal"
+	"lfg/pkg/llm"
+	"lfg/pkg/storage"
+	"lfg/pkg/types"
 
-	"github.com/openai/openai-go"
 	log "github.com/sirupsen/logrus"
 )
 
 type Agent struct {
-	Id     string
-	Prompt string
-	Memory *AgentMemory
-	Tasks  []AgentTask
+	Id            string
+	Prompt        string
+	Config        *config.AgentConfig
+	Memory        *AgentMemory
+	Tasks         []AgentTask
+	ExecutionPlan *ExecutionPlan // approved plan, nil until planning succeeds; read it through GetExecutionPlan while the agent may be planning
 
+	Planner  llm.Client        // model generating & refining the plan
+	Approver PlanApprover      // required by planning mode `approval`
+	Store    storage.Store     // optional; persists the approved plan across restarts
+	Journal  *journal.Recorder // optional; records model calls, memory writes & orders, see EnableJournal
+
+	planMu sync.RWMutex // guards ExecutionPlan, read by the REST API during planning
+	execMu sync.Mutex   // held while the task pipeline is running
 	logger *log.Entry
 }
 
-func NewAgent(agentId string, prompt string, exchanges map[string]*exchange.Exchange) (*Agent, error) {
-	err := InitOpenAIClient()
+func NewAgent(agentId string, agentConfig *config.AgentConfig, exchanges map[string]*exchange.Exchange) (*Agent, error) {
+	planner, err := llm.New(agentConfig.Planner)
+	if err != nil {
+		return nil, fmt.Errorf("fail to create planner llm: %w", err)
+	}
+	runtime, err := llm.New(agentConfig.Runtime)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("fail to create runtime llm: %w", err)
 	}
 
 	agent := &Agent{
-		Id:     agentId,
-		Prompt: prompt,
-		Memory: &AgentMemory{
-			Exchanges: exchanges,
-			Data:      make(map[string]any),
-		},
+		Id:      agentId,
+		Prompt:  agentConfig.Prompt,
+		Config:  agentConfig,
+		Memory:  NewAgentMemory(exchanges, runtime),
+		Planner: planner,
 		logger: log.WithFields(log.Fields{
 			"agent": agentId,
 		}),
@@ -43,29 +60,69 @@ func NewAgent(agentId string, prompt string, exchanges map[string]*exchange.Exch
 }
 
 func (a *Agent) Plan(ctx context.Context) error {
+	switch a.Config.Mode {
+	case "", types.AgentModePlan:
+	case types.AgentModeTools:
+		// @dev: there is no plan to approve in tools mode, so a gated planning mode would be silently bypassed
+		if planning := a.Config.Planning; planning != nil && planning.Mode != "" && planning.Mode != types.PlanningModeAuto {
+			return fmt.Errorf("planning mode %v is not supported in %v mode, the tool calls are not approved; use %v or omit it", planning.Mode, types.AgentModeTools, types.PlanningModeAuto)
+		}
+		return a.setupToolsMode()
+	default:
+		return fmt.Errorf("unknown agent mode: %v", a.Config.Mode)
+	}
+
+	planId := "plan-" + newRunId()
+	planner := withJournalScope(a.Planner, planId, "planner")
+	a.Journal.StartRun(planId)
+	defer a.Journal.EndRun(planId)
+
 	// setup variables
+	mode, maxRefineCount, approvalTimeout, err := a.planningSettings()
+	if err != nil {
+		return err
+	}
+	if mode == types.PlanningModeApproval && a.Approver == nil {
+		return fmt.Errorf("planning mode %v requires an approver", mode)
+	}
 	refined := false
-	prevMessages := []openai.ChatCompletionMessageParamUnion{}
+	prevMessages := []llm.Message{}
 	refineCount := 0
-	maxRefineCount := 3
+
+	// reuse the persisted plan if the prompt is unchanged
+	if a.Store != nil && !a.Config.Planning.GetForceReplan() {
+		stored, err := a.loadStoredPlan()
+		if err != nil {
+			return err
+		}
+		if stored != nil {
+			violations := a.validatePlan(stored.Plan)
+			if len(violations) == 0 {
+				a.logger.Infof("Reusing plan stored at %v", stored.CreatedAt.Format(time.RFC3339))
+				return a.applyPlan(stored.Plan)
+			}
+			a.logger.Warnf("stored plan is no longer valid, planning again:\n%v", strings.Join(violations, "\n"))
+		}
+	}
 
 	// get available exchanges id
 	availableExchangesId := []string{}
 	for key := range a.Memory.Exchanges {
 		availableExchangesId = append(availableExchangesId, key)
 	}
+	runContext := getRunContextDescription(a.Config)
 
 	// generate execution plan
-	a.logger.Println("Starting planning...")
+	a.logger.Infof("Starting planning (mode: %v)...", mode)
 	var plan ExecutionPlan
 	var refinedFeedback Feedback
 	var userNoComment bool = false
 
-	// refine execution plan until it is correct or max refine count is reached
+	// refine execution plan until it is correct (and approved) or max refine count is reached
 	for !refined && refineCount <= maxRefineCount {
 		var err error
 		// generate execution plan
-		plan, err = GenerateExecutionPlan(ctx, OpenAIClient, availableExchangesId, a.Prompt, prevMessages)
+		plan, err = GenerateExecutionPlan(ctx, planner, availableExchangesId, runContext, a.Prompt, prevMessages)
 		if err != nil {
 			return err
 		}
@@ -76,22 +133,36 @@ func (a *Agent) Plan(ctx context.Context) error {
 		// log initial reasoning and plan
 		a.logger.Infof("Round %v: \n%v\n", refineCount+1, GetReadablePlan(plan))
 
+		// statically validate the plan before spending a refiner call on it
+		if violations := a.validatePlan(plan); len(violations) > 0 {
+			feedback := "The plan is invalid:\n- " + strings.Join(violations, "\n- ")
+			a.logger.Warnf("Validation #%v: \n%v\n", refineCount+1, feedback)
+			refinedFeedback = Feedback{Type: "INVALID", Feedback: feedback}
+			if mode == types.PlanningModeFailFast {
+				return fmt.Errorf("plan is not valid in fail fast mode: %v", feedback)
+			}
+			prevMessages = append(prevMessages, llm.Message{Role: llm.RoleAssistant, Content: string(jsonPlan)})
+			prevMessages = append(prevMessages, llm.Message{Role: llm.RoleUser, Content: feedback})
+			refineCount++
+			continue
+		}
+
 		// wait for user comment through stdin
 		userComment := "NO COMMENT"
-		if !userNoComment {
-			reader := bufio.NewReader(os.Stdin)
-			fmt.Println("Enter your comment (leave blank for no comment):")
-			userComment, err = reader.ReadString('\n')
+		if mode == types.PlanningModeInteractive && !userNoComment {
+			comment, err := readStdinComment(a.Id)
 			if err != nil {
 				return err
 			}
-			if userComment == "" {
+			if comment == "" {
 				userNoComment = true
+			} else {
+				userComment = comment
 			}
 		}
 
 		// refine execution plan
-		refinedFeedback, err = RefineExecutionPlan(ctx, OpenAIClient, availableExchangesId, a.Prompt, plan, userComment)
+		refinedFeedback, err = RefineExecutionPlan(ctx, planner, availableExchangesId, runContext, a.Prompt, plan, userComment)
 		if err != nil {
 			return err
 		}
@@ -104,51 +175,201 @@ func (a *Agent) Plan(ctx context.Context) error {
 			a.logger.Warn("NOT_ENOUGH_TOOLS")
 			break
 		} else if refinedFeedback.Type == "CORRECT" {
-			refined = true
-			a.logger.Infof("Refined Successfully...")
+			if mode != types.PlanningModeApproval {
+				refined = true
+				a.logger.Infof("Refined Successfully...")
+				continue
+			}
+			approval, err := a.requestApproval(ctx, plan, approvalTimeout)
+			if err != nil {
+				return err
+			}
+			if approval.Approved {
+				refined = true
+				a.logger.Infof("Refined and approved successfully...")
+				continue
+			}
+			a.logger.Warnf("Plan rejected: %v", approval.Comment)
+			refinedFeedback = Feedback{Type: "REJECTED", Feedback: approval.Comment}
+			prevMessages = append(prevMessages, llm.Message{Role: llm.RoleAssistant, Content: string(jsonPlan)})
+			prevMessages = append(prevMessages, llm.Message{Role: llm.RoleUser, Content: "The user rejected this plan: " + approval.Comment})
+			refineCount++
 		} else {
+			if mode == types.PlanningModeFailFast {
+				return fmt.Errorf("plan is not correct in fail fast mode: [%v] %v", refinedFeedback.Type, refinedFeedback.Feedback)
+			}
 			refined = false
-			prevMessages = append(prevMessages, openai.AssistantMessage(string(jsonPlan)))
-			prevMessages = append(prevMessages, openai.UserMessage(refinedFeedback.Feedback))
+			prevMessages = append(prevMessages, llm.Message{Role: llm.RoleAssistant, Content: string(jsonPlan)})
+			prevMessages = append(prevMessages, llm.Message{Role: llm.RoleUser, Content: refinedFeedback.Feedback})
 			refineCount++
 		}
 	}
 
-	// if refined successfully, store the tasks and initiate memory
-	if refined {
-		a.logger.Infof("Storing tasks...")
-		a.logger.Infof("Initiating memory...")
-		a.logger.Infof("Plan: \n%v\n", GetReadablePlan(plan))
-		for _, task := range plan.Tasks {
-			task, err := GetTaskByName(task.Name, task.Parameters)
-			if err != nil {
-				return err
-			}
-			a.Tasks = append(a.Tasks, *task)
+	if !refined {
+		return fmt.Errorf("max refine count reached, skipping memory initiation:[%v] %v", refinedFeedback.Type, refinedFeedback.Feedback)
+	}
+	if err := a.applyPlan(plan); err != nil {
+		return err
+	}
+	if a.Store != nil {
+		if err := a.saveStoredPlan(plan); err != nil {
+			return err
 		}
+		a.logger.Infof("Plan stored for reuse")
+	}
+	return nil
+}
+
+func (a *Agent) validatePlan(plan ExecutionPlan) []string {
+	return ValidatePlan(plan, a.Memory.Exchanges, TriggerMemoryKeys(a.Config.Triggers))
+}
 
-		for _, state := range plan.InitState {
-			a.Memory.Set(state.Key, state.Value)
+// applyPlan stores the tasks and initiates memory from an approved plan,
+// restoring the last memory snapshot if one was taken under the same plan
+func (a *Agent) applyPlan(plan ExecutionPlan) error {
+	a.logger.Infof("Storing tasks...")
+	a.logger.Infof("Initiating memory...")
+	a.logger.Infof("Plan: \n%v\n", GetReadablePlan(plan))
+	for _, taskFromAI := range plan.Tasks {
+		task, err := GetTaskByName(taskFromAI.Name, taskFromAI.Parameters)
+		if err != nil {
+			return err
 		}
+		task.Policy, err = newTaskPolicy(taskFromAI)
+		if err != nil {
+			return fmt.Errorf("task %v: %w", task.Name, err)
+		}
+		a.Tasks = append(a.Tasks, *task)
+	}
 
-	} else {
-		return fmt.Errorf("max refine count reached, skipping memory initiation:[%v] %v", refinedFeedback.Type, refinedFeedback.Feedback)
+	initMemory := a.Memory.WithWriter("initState", "")
+	for _, state := range plan.InitState {
+		initMemory.Set(state.Key, state.Value)
 	}
+	if a.Store != nil {
+		if err := a.restoreMemory(plan); err != nil {
+			return err
+		}
+	}
+	a.setExecutionPlan(&plan)
 	return nil
 }
 
-func (a *Agent) Execute(ctx context.Context) error {
-	// execute all tasks
+// GetExecutionPlan returns the approved plan, nil until planning succeeds
+func (a *Agent) GetExecutionPlan() *ExecutionPlan {
+	a.planMu.RLock()
+	defer a.planMu.RUnlock()
+	return a.ExecutionPlan
+}
+
+func (a *Agent) setExecutionPlan(plan *ExecutionPlan) {
+	a.planMu.Lock()
+	defer a.planMu.Unlock()
+	a.ExecutionPlan = plan
+}
+
+func (a *Agent) planningSettings() (mode types.PlanningMode, maxRefineCount int, approvalTimeout time.Duration, err error) {
+	mode, maxRefineCount = types.PlanningModeInteractive, 3
+	planningConfig := a.Config.Planning
+	if planningConfig == nil {
+		return mode, maxRefineCount, 0, nil
+	}
+	switch planningConfig.Mode {
+	case "":
+	case types.PlanningModeInteractive, types.PlanningModeAuto, types.PlanningModeApproval, types.PlanningModeFailFast:
+		mode = planningConfig.Mode
+	default:
+		return "", 0, 0, fmt.Errorf("unknown planning mode: %v", planningConfig.Mode)
+	}
+	if planningConfig.MaxRefineCount > 0 {
+		maxRefineCount = planningConfig.MaxRefineCount
+	}
+	if planningConfig.ApprovalTimeout != "" {
+		approvalTimeout, err = time.ParseDuration(planningConfig.ApprovalTimeout)
+		if err != nil {
+			return "", 0, 0, fmt.Errorf("invalid approval timeout '%v': %w", planningConfig.ApprovalTimeout, err)
+		}
+	}
+	return mode, maxRefineCount, approvalTimeout, nil
+}
+
+func (a *Agent) requestApproval(ctx context.Context, plan ExecutionPlan, timeout time.Duration) (PlanApproval, error) {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	a.logger.Infof("Waiting for plan approval...")
+	approval, err := a.Approver.RequestApproval(ctx, a.Id, plan)
+	if err != nil {
+		return PlanApproval{}, fmt.Errorf("fail to get plan approval: %w", err)
+	}
+	return approval, nil
+}
+
+// TryExecute runs the task pipeline unless a previous run is still in progress,
+// in which case it returns immediately with executed=false
+func (a *Agent) TryExecute(ctx context.Context) (result *RunResult, executed bool, err error) {
+	if !a.execMu.TryLock() {
+		return nil, false, nil
+	}
+	defer a.execMu.Unlock()
+	result, err = a.Execute(ctx)
+	return result, true, err
+}
+
+// Execute runs the task pipeline; the error is set if a task stopped the run
+func (a *Agent) Execute(ctx context.Context) (*RunResult, error) {
+	return a.execute(ctx, newRunId())
+}
+
+// run ids identify the writes of a run in the memory history
+func newRunId() string {
+	return time.Now().UTC().Format("20060102T150405.000")
+}
+
+func (a *Agent) execute(ctx context.Context, runId string) (*RunResult, error) {
+	result := &RunResult{RunId: runId, StartedAt: time.Now()}
+	a.Journal.StartRun(runId)
+	defer a.Journal.EndRun(runId)
+	if a.Config.Mode == types.AgentModeTools {
+		a.logger.Debugf("run %v started (tools mode)", runId)
+		err := a.executeTools(ctx, runId, result)
+		if err != nil {
+			a.logger.Errorf("Tools run aborted: %v", err)
+			result.Aborted = true
+			err = fmt.Errorf("run %v aborted: %w", runId, err)
+		}
+		result.Duration = time.Since(result.StartedAt)
+		a.logger.Infof("final memory state: %v", a.Memory.Snapshot())
+		return result, err
+	}
+	var runErr error
+
+	// execute all tasks, until one fails under the `abort` or `retry` policy
+	a.logger.Debugf("run %v started", runId)
 	for _, task := range a.Tasks {
+		if runErr != nil {
+			result.Tasks = append(result.Tasks, TaskResult{Name: task.Name, Status: types.TaskStatusNotRun})
+			continue
+		}
 		a.logger.Infof("Executing task: %v", task.Name)
-		err := task.Executable.Execute(ctx, a.Memory)
-		if err != nil {
-			a.logger.Errorf("Error executing task %v: %v", task.Name, err)
+		taskResult, err := a.runTask(ctx, task, a.Memory.WithWriter(task.Name, runId))
+		result.Tasks = append(result.Tasks, taskResult)
+		switch {
+		case err == nil:
+			a.logger.Infof("Task %v executed successfully", task.Name)
+		case task.Policy.OnError == types.TaskErrorSkip:
+			a.logger.Warnf("Error executing task %v, skipped: %v", task.Name, err)
+		default:
+			a.logger.Errorf("Error executing task %v, run aborted: %v", task.Name, err)
+			result.Aborted = true
+			runErr = fmt.Errorf("run %v aborted at task %v: %w", runId, task.Name, err)
 		}
-		a.logger.Infof("Task %v executed successfully", task.Name)
 	}
+	result.Duration = time.Since(result.StartedAt)
 
 	// last memory update log
-	a.logger.Infof("final memory state: %v", a.Memory.Data)
-	return nil
+	a.logger.Infof("final memory state: %v", a.Memory.Snapshot())
+	return result, runErr
 }
@@ -0,0 +1,251 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"lfg/pkg/llm"
+	"reflect"
+	"strings"
+)
+
+const (
+	OUTPUT_TEXT   = "text" // free text, trimmed
+	OUTPUT_ENUM   = "enum" // one of the allowed values
+	OUTPUT_NUMBER = "number"
+	OUTPUT_BOOL   = "bool"
+	OUTPUT_OBJECT = "object" // json object matching a schema
+
+	MAX_AI_ASKS = 3 // asks per askAI run, the model is re-asked while its answer is invalid
+)
+
+// AnswerType is embedded by askAI, the answer is enforced through structured output
+type AnswerType struct {
+	OutputType   string `json:"outputType,omitempty" jsonschema_description:"the type of the answer: 'text' (default, free text), 'enum' (one of outputValues; use it for answers compared with ifValue), 'number', 'bool' ('true' | 'false') or 'object' (json object matching outputSchema)"`
+	OutputValues string `json:"outputValues,omitempty" jsonschema_description:"enum only; the allowed answers separated by comma ex. 'yes,no,idk'"`
+	OutputSchema string `json:"outputSchema,omitempty" jsonschema_description:"object only; the json schema of the answer ex. '{\"type\":\"object\",\"properties\":{\"trend\":{\"type\":\"string\",\"enum\":[\"up\",\"down\"]},\"confidence\":{\"type\":\"number\"}},\"required\":[\"trend\",\"confidence\"]}'"`
+}
+
+func (t *AnswerType) Validate() error {
+	switch t.OutputType {
+	case "", OUTPUT_TEXT, OUTPUT_NUMBER, OUTPUT_BOOL:
+	case OUTPUT_ENUM:
+		if len(t.values()) == 0 {
+			return fmt.Errorf("outputType 'enum' requires outputValues")
+		}
+	case OUTPUT_OBJECT:
+		if _, err := t.objectSchema(); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unknown outputType '%v', must be one of [%v, %v, %v, %v, %v]", t.OutputType, OUTPUT_TEXT, OUTPUT_ENUM, OUTPUT_NUMBER, OUTPUT_BOOL, OUTPUT_OBJECT)
+	}
+	if t.OutputValues != "" && t.OutputType != OUTPUT_ENUM {
+		return fmt.Errorf("outputValues is only valid with outputType 'enum'")
+	}
+	if t.OutputSchema != "" && t.OutputType != OUTPUT_OBJECT {
+		return fmt.Errorf("outputSchema is only valid with outputType 'object'")
+	}
+	return nil
+}
+
+// OutputMemoryType is the type of the memory value the answer is stored as
+func (t *AnswerType) OutputMemoryType() MemoryType {
+	switch t.OutputType {
+	case OUTPUT_NUMBER:
+		return MemoryTypeNumber
+	case OUTPUT_BOOL:
+		return MemoryTypeBool
+	case OUTPUT_OBJECT:
+		return MemoryTypeObject
+	default:
+		return MemoryTypeStr
+	}
+}
+
+func (t *AnswerType) values() []string {
+	values := []string{}
+	for _, value := range strings.Split(t.OutputValues, ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+func (t *AnswerType) objectSchema() (map[string]any, error) {
+	if t.OutputSchema == "" {
+		return nil, fmt.Errorf("outputType 'object' requires outputSchema")
+	}
+	var schema map[string]any
+	if err := json.Unmarshal([]byte(t.OutputSchema), &schema); err != nil {
+		return nil, fmt.Errorf("outputSchema is not a json schema: %w", err)
+	}
+	if schema["type"] != "object" {
+		return nil, fmt.Errorf("outputSchema must be of type 'object'")
+	}
+	return schema, nil
+}
+
+// the answer is wrapped in an object, as structured outputs must be objects
+func (t *AnswerType) responseFormat() *llm.ResponseFormat {
+	var schema map[string]any
+	switch t.OutputType {
+	case OUTPUT_ENUM:
+		schema = map[string]any{"type": "string", "enum": t.values()}
+	case OUTPUT_NUMBER:
+		schema = map[string]any{"type": "number"}
+	case OUTPUT_BOOL:
+		schema = map[string]any{"type": "boolean"}
+	case OUTPUT_OBJECT:
+		schema, _ = t.objectSchema()
+	default:
+		return nil
+	}
+	return &llm.ResponseFormat{
+		Name:        "Answer",
+		Description: "The answer to the user instruction",
+		Schema: map[string]any{
+			"type":                 "object",
+			"properties":           map[string]any{"answer": schema},
+			"required":             []string{"answer"},
+			"additionalProperties": false,
+		},
+	}
+}
+
+// parseAnswer returns the memory value of the response content, or why it is invalid
+func (t *AnswerType) parseAnswer(content string) (Value, error) {
+	if t.responseFormat() == nil {
+		return Value{Type: MemoryTypeStr, Str: strings.TrimSpace(content)}, nil
+	}
+	var res struct {
+		Answer any `json:"answer"`
+	}
+	if err := json.Unmarshal([]byte(content), &res); err != nil {
+		return Value{}, fmt.Errorf("the response is not a json object with an 'answer': %w", err)
+	}
+	switch t.OutputType {
+	case OUTPUT_ENUM:
+		answer, ok := res.Answer.(string)
+		if !ok {
+			return Value{}, fmt.Errorf("the answer must be a string")
+		}
+		// @dev: tolerate case & spacing, the allowed value is stored as is
+		for _, value := range t.values() {
+			if strings.EqualFold(strings.TrimSpace(answer), value) {
+				return Value{Type: MemoryTypeStr, Str: value}, nil
+			}
+		}
+		return Value{}, fmt.Errorf("the answer '%v' must be one of [%v]", answer, strings.Join(t.values(), ", "))
+	case OUTPUT_NUMBER:
+		answer, ok := res.Answer.(float64)
+		if !ok {
+			return Value{}, fmt.Errorf("the answer must be a number")
+		}
+		return Value{Type: MemoryTypeNumber, Number: answer}, nil
+	case OUTPUT_BOOL:
+		answer, ok := res.Answer.(bool)
+		if !ok {
+			return Value{}, fmt.Errorf("the answer must be a boolean")
+		}
+		return Value{Type: MemoryTypeBool, Bool: answer}, nil
+	default:
+		schema, _ := t.objectSchema()
+		if err := validateJson(schema, res.Answer, "answer"); err != nil {
+			return Value{}, err
+		}
+		return Value{Type: MemoryTypeObject, Object: res.Answer.(map[string]any)}, nil
+	}
+}
+
+// ask asks the model until its answer is valid, at most MAX_AI_ASKS times
+func (t *AnswerType) ask(ctx context.Context, client llm.Client, prompt string) (Value, error) {
+	messages := []llm.Message{{Role: llm.RoleUser, Content: prompt}}
+	var err error
+	for ask := 1; ask <= MAX_AI_ASKS; ask++ {
+		res, completeErr := client.Complete(ctx, llm.Request{Messages: messages, ResponseFormat: t.responseFormat()})
+		if completeErr != nil {
+			return Value{}, completeErr
+		}
+		var value Value
+		value, err = t.parseAnswer(res.Message.Content)
+		if err == nil {
+			return value, nil
+		}
+		messages = append(messages,
+			res.Message,
+			llm.Message{Role: llm.RoleUser, Content: fmt.Sprintf("Your answer is invalid: %v. Answer again following the format strictly.", err)},
+		)
+	}
+	return Value{}, fmt.Errorf("no valid answer after %v asks: %w", MAX_AI_ASKS, err)
+}
+
+// validateJson checks the value against the subset of json schema used by outputSchema:
+// type, enum, properties, required & items
+func validateJson(schema map[string]any, value any, path string) error {
+	if enum, ok := schema["enum"].([]any); ok {
+		found := false
+		for _, allowed := range enum {
+			if reflect.DeepEqual(allowed, value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("'%v' must be one of %v, got %v", path, enum, value)
+		}
+	}
+	switch schema["type"] {
+	case "object":
+		object, ok := value.(map[string]any)
+		if !ok {
+			return fmt.Errorf("'%v' must be an object", path)
+		}
+		required, _ := schema["required"].([]any)
+		for _, key := range required {
+			if _, exists := object[fmt.Sprint(key)]; !exists {
+				return fmt.Errorf("'%v' is missing '%v'", path, key)
+			}
+		}
+		properties, _ := schema["properties"].(map[string]any)
+		for key, property := range properties {
+			propertySchema, ok := property.(map[string]any)
+			if _, exists := object[key]; !exists || !ok {
+				continue
+			}
+			if err := validateJson(propertySchema, object[key], path+"."+key); err != nil {
+				return err
+			}
+		}
+	case "array":
+		array, ok := value.([]any)
+		if !ok {
+			return fmt.Errorf("'%v' must be an array", path)
+		}
+		if items, ok := schema["items"].(map[string]any); ok {
+			for i, item := range array {
+				if err := validateJson(items, item, fmt.Sprintf("%v[%d]", path, i)); err != nil {
+					return err
+				}
+			}
+		}
+	case "string":
+		if _, ok := value.(string); !ok {
+			return fmt.Errorf("'%v' must be a string", path)
+		}
+	case "number":
+		if _, ok := value.(float64); !ok {
+			return fmt.Errorf("'%v' must be a number", path)
+		}
+	case "integer":
+		if number, ok := value.(float64); !ok || number != float64(int64(number)) {
+			return fmt.Errorf("'%v' must be an integer", path)
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return fmt.Errorf("'%v' must be a boolean", path)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package ai
+
+import (
+	"context"
+	"lfg/pkg/llm"
+	"reflect"
+	"testing"
+)
+
+const trendSchema = `{"type": "object", "properties": {"trend": {"type": "string", "enum": ["up", "down"]}, "confidence": {"type": "number"}, "levels": {"type": "array", "items": {"type": "integer"}}}, "required": ["trend", "confidence"]}`
+
+func TestAnswerTypeValidate(t *testing.T) {
+	tests := map[string]struct {
+		answerType AnswerType
+		valid      bool
+	}{
+		"default text":           {AnswerType{}, true},
+		"enum":                   {AnswerType{OutputType: OUTPUT_ENUM, OutputValues: "yes, no"}, true},
+		"enum without values":    {AnswerType{OutputType: OUTPUT_ENUM, OutputValues: " , "}, false},
+		"object":                 {AnswerType{OutputType: OUTPUT_OBJECT, OutputSchema: trendSchema}, true},
+		"object without schema":  {AnswerType{OutputType: OUTPUT_OBJECT}, false},
+		"object schema not json": {AnswerType{OutputType: OUTPUT_OBJECT, OutputSchema: "{trend}"}, false},
+		"object schema of array": {AnswerType{OutputType: OUTPUT_OBJECT, OutputSchema: `{"type": "array"}`}, false},
+		"values without enum":    {AnswerType{OutputType: OUTPUT_NUMBER, OutputValues: "1,2"}, false},
+		"schema without object":  {AnswerType{OutputSchema: trendSchema}, false},
+		"unknown type":           {AnswerType{OutputType: "date"}, false},
+	}
+	for name, tt := range tests {
+		if err := tt.answerType.Validate(); (err == nil) != tt.valid {
+			t.Errorf("%v: Validate() = %v, want valid %v", name, err, tt.valid)
+		}
+	}
+}
+
+func TestParseAnswer(t *testing.T) {
+	enum := AnswerType{OutputType: OUTPUT_ENUM, OutputValues: "yes,no"}
+	number := AnswerType{OutputType: OUTPUT_NUMBER}
+	boolean := AnswerType{OutputType: OUTPUT_BOOL}
+	object := AnswerType{OutputType: OUTPUT_OBJECT, OutputSchema: trendSchema}
+
+	tests := []struct {
+		name       string
+		answerType AnswerType
+		content    string
+		want       Value // zero if invalid
+	}{
+		{"text is trimmed", AnswerType{}, "  buy \n", Value{Type: MemoryTypeStr, Str: "buy"}},
+		{"enum value", enum, `{"answer": "no"}`, Value{Type: MemoryTypeStr, Str: "no"}},
+		{"enum tolerates case & spacing", enum, `{"answer": " YES "}`, Value{Type: MemoryTypeStr, Str: "yes"}},
+		{"enum unknown value", enum, `{"answer": "maybe"}`, Value{}},
+		{"enum not a string", enum, `{"answer": true}`, Value{}},
+		{"not json", enum, "yes", Value{}},
+		{"number", number, `{"answer": 42.5}`, Value{Type: MemoryTypeNumber, Number: 42.5}},
+		{"number as string", number, `{"answer": "42.5"}`, Value{}},
+		{"bool", boolean, `{"answer": false}`, Value{Type: MemoryTypeBool, Bool: false}},
+		{"bool as string", boolean, `{"answer": "false"}`, Value{}},
+		{
+			"object", object, `{"answer": {"trend": "up", "confidence": 0.8, "levels": [100, 110]}}`,
+			Value{Type: MemoryTypeObject, Object: map[string]any{"trend": "up", "confidence": 0.8, "levels": []any{100.0, 110.0}}},
+		},
+		{"object missing a required property", object, `{"answer": {"trend": "up"}}`, Value{}},
+		{"object property not in enum", object, `{"answer": {"trend": "sideways", "confidence": 0.8}}`, Value{}},
+		{"object item not an integer", object, `{"answer": {"trend": "up", "confidence": 0.8, "levels": [100.5]}}`, Value{}},
+		{"object not an object", object, `{"answer": "up"}`, Value{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			value, err := tt.answerType.parseAnswer(tt.content)
+			if tt.want.Type == "" {
+				if err == nil {
+					t.Fatalf("parseAnswer(%q) = %+v, want error", tt.content, value)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(value, tt.want) {
+				t.Fatalf("parseAnswer(%q) = %+v, want %+v", tt.content, value, tt.want)
+			}
+		})
+	}
+}
+
+func TestAsk(t *testing.T) {
+	answerType := AnswerType{OutputType: OUTPUT_ENUM, OutputValues: "yes,no"}
+
+	// the model is re-asked with the reason its answer is invalid
+	client := llm.NewScriptedClientFromResponses(map[string][]string{
+		"Answer": {`{"answer": "maybe"}`, `{"answer": "yes"}`},
+	})
+	value, err := answerType.ask(context.Background(), client, "is it a dip?")
+	if err != nil || value.Str != "yes" {
+		t.Fatalf("ask() = %+v, %v; want yes", value, err)
+	}
+
+	client = llm.NewScriptedClientFromResponses(map[string][]string{
+		"Answer": {`{"answer": "maybe"}`},
+	})
+	if _, err := answerType.ask(context.Background(), client, "is it a dip?"); err == nil {
+		t.Fatalf("ask() without a valid answer: want error")
+	}
+}
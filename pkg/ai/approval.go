@@ -0,0 +1,38 @@
+package ai
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+type PlanApproval struct {
+	Approved bool   `json:"approved"`
+	Comment  string `json:"comment"` // reason for rejection, fed back to the planner
+}
+
+// PlanApprover is the channel through which a refined plan is approved
+// before the agent starts running it (planning mode `approval`)
+type PlanApprover interface {
+	RequestApproval(ctx context.Context, agentId string, plan ExecutionPlan) (PlanApproval, error)
+}
+
+// @dev: agents plan concurrently; only one of them may own the terminal at a time
+var stdinMu sync.Mutex
+
+// read a planning comment from stdin; an empty line means no comment
+func readStdinComment(agentId string) (string, error) {
+	stdinMu.Lock()
+	defer stdinMu.Unlock()
+
+	reader := bufio.NewReader(os.Stdin)
+	fmt.Printf("[%s] Enter your comment (leave blank for no comment):\n", agentId)
+	comment, err := reader.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(comment), nil
+}
@@ -0,0 +1,58 @@
+package ai
+
+import (
+	"lfg/pkg/exchange"
+	"lfg/pkg/journal"
+	"lfg/pkg/llm"
+	"lfg/pkg/types"
+)
+
+// EnableJournal records the model calls, memory writes and orders of the agent in the journal;
+// it must be called before planning
+func (a *Agent) EnableJournal(j *journal.Journal) {
+	recorder := j.NewRecorder(a.Id)
+	a.Journal = recorder
+
+	a.Planner = journal.NewLLMClient(a.Planner, recorder, llm.GetPricing(a.Config.Planner, a.Planner.Model()))
+	a.Memory.LLM = journal.NewLLMClient(a.Memory.LLM, recorder, llm.GetPricing(a.Config.Runtime, a.Memory.LLM.Model()))
+
+	// @dev: exchanges may be shared with other agents, wrap them in a new map
+	exchanges := make(map[string]*exchange.Exchange, len(a.Memory.Exchanges))
+	for exchangeId, exchg := range a.Memory.Exchanges {
+		var journaled exchange.Exchange = journal.NewExchange(*exchg, recorder)
+		exchanges[exchangeId] = &journaled
+	}
+	a.Memory.Exchanges = exchanges
+
+	a.Memory.OnChange(func(change MemoryChange) {
+		recorder.Record(journal.Entry{
+			Time:  change.Time,
+			RunId: change.RunId,
+			Task:  change.Task,
+			Kind:  types.JournalMemory,
+			Key:   change.Key,
+			Value: change.New.String(),
+		})
+	})
+}
+
+// withJournalScope returns the model client recording under the run & task, if journaled
+func withJournalScope(client llm.Client, runId string, task string) llm.Client {
+	if journaled, ok := client.(*journal.LLMClient); ok {
+		return journaled.WithScope(runId, task)
+	}
+	return client
+}
+
+// withJournalScopes returns the exchanges recording their orders under the run & task, if journaled
+func withJournalScopes(exchanges map[string]*exchange.Exchange, runId string, task string) map[string]*exchange.Exchange {
+	scoped := make(map[string]*exchange.Exchange, len(exchanges))
+	for exchangeId, exchg := range exchanges {
+		if journaled, ok := (*exchg).(*journal.Exchange); ok {
+			var scopedExchange exchange.Exchange = journaled.WithScope(runId, task)
+			exchg = &scopedExchange
+		}
+		scoped[exchangeId] = exchg
+	}
+	return scoped
+}
@@ -4,127 +4,376 @@ import (
 	"encoding/json"
 	"fmt"
 	"lfg/pkg/exchange"
+	"lfg/pkg/llm"
+	"lfg/pkg/order"
 	"lfg/pkg/types"
+	"math"
+	"sort"
 	"strconv"
+	"strings"
+	"sync"
+	"time"
 )
 
+const MEMORY_HISTORY_SIZE = 100 // changes kept per key
+
+// AgentMemory is safe for concurrent use; copies made by WithWriter share the same data
 type AgentMemory struct {
 	Exchanges map[string]*exchange.Exchange
-	Data      map[string]any
+	LLM       llm.Client // runtime model used by AI tasks
+
+	store  *memoryStore
+	writer memoryWriter
+}
+
+type memoryStore struct {
+	mu      sync.RWMutex
+	data    map[string]Value
+	history map[string][]MemoryChange // key -> changes, oldest first
+
+	onChange func(MemoryChange) // optional; called after each write
+}
+
+// who writes to memory, recorded in the change history
+type memoryWriter struct {
+	task  string
+	runId string
+}
+
+// MemoryChange is an entry of the per-key change history
+type MemoryChange struct {
+	Key   string    `json:"key"`
+	Old   *Value    `json:"old"` // nil if the key was not set
+	New   Value     `json:"new"`
+	Task  string    `json:"task"`  // writing task, or `initState` | `snapshot` | `trigger`
+	RunId string    `json:"runId"` // empty outside of a run
+	Time  time.Time `json:"time"`
+}
+
+func NewAgentMemory(exchanges map[string]*exchange.Exchange, llmClient llm.Client) *AgentMemory {
+	return &AgentMemory{
+		Exchanges: exchanges,
+		LLM:       llmClient,
+		store: &memoryStore{
+			data:    make(map[string]Value),
+			history: make(map[string][]MemoryChange),
+		},
+	}
+}
+
+// WithWriter returns a view of the memory whose writes, model calls & orders are recorded as made by the task in the run
+func (m *AgentMemory) WithWriter(task string, runId string) *AgentMemory {
+	return &AgentMemory{
+		Exchanges: withJournalScopes(m.Exchanges, runId, task),
+		LLM:       withJournalScope(m.LLM, runId, task),
+		store:     m.store,
+		writer:    memoryWriter{task: task, runId: runId},
+	}
+}
+
+// Snapshot returns a copy of all memory values
+func (m *AgentMemory) Snapshot() map[string]Value {
+	m.store.mu.RLock()
+	defer m.store.mu.RUnlock()
+	data := make(map[string]Value, len(m.store.data))
+	for key, value := range m.store.data {
+		data[key] = value
+	}
+	return data
+}
+
+// History returns the recorded changes of a key (all keys if empty), most recent first
+func (m *AgentMemory) History(key string, limit int) []MemoryChange {
+	m.store.mu.RLock()
+	defer m.store.mu.RUnlock()
+	changes := []MemoryChange{}
+	if key != "" {
+		changes = append(changes, m.store.history[key]...)
+	} else {
+		for _, keyChanges := range m.store.history {
+			changes = append(changes, keyChanges...)
+		}
+	}
+	sort.SliceStable(changes, func(i, j int) bool {
+		return changes[i].Time.After(changes[j].Time)
+	})
+	if limit > 0 && len(changes) > limit {
+		changes = changes[:limit]
+	}
+	return changes
+}
+
+// OnChange sets a function called after each write, outside of the memory lock
+func (m *AgentMemory) OnChange(fn func(MemoryChange)) {
+	m.store.mu.Lock()
+	defer m.store.mu.Unlock()
+	m.store.onChange = fn
+}
+
+func (m *AgentMemory) set(key string, value Value) {
+	m.store.mu.Lock()
+	change := MemoryChange{
+		Key:   key,
+		New:   value,
+		Task:  m.writer.task,
+		RunId: m.writer.runId,
+		Time:  time.Now(),
+	}
+	if old, exists := m.store.data[key]; exists {
+		change.Old = &old
+	}
+	m.store.data[key] = value
+	history := append(m.store.history[key], change)
+	if len(history) > MEMORY_HISTORY_SIZE {
+		history = history[len(history)-MEMORY_HISTORY_SIZE:]
+	}
+	m.store.history[key] = history
+	onChange := m.store.onChange
+	m.store.mu.Unlock()
+
+	if onChange != nil {
+		onChange(change)
+	}
 }
 
 type MemoryType string
 
 const (
-	MemoryTypeStr      = MemoryType("string")
-	MemoryTypeFloat64  = MemoryType("float64")
-	MemoryTypeInt64    = MemoryType("int64")
-	MemoryTypeInterval = MemoryType("interval")
+	MemoryTypeStr       = MemoryType("string")
+	MemoryTypeNumber    = MemoryType("number")
+	MemoryTypeBool      = MemoryType("bool")
+	MemoryTypeSeries    = MemoryType("series")    // []float64, latest first (as the indicators output them)
+	MemoryTypeKlines    = MemoryType("klines")    // []types.KLineEvent, oldest first
+	MemoryTypePositions = MemoryType("positions") // []types.Position
+	MemoryTypeOrders    = MemoryType("orders")    // []order.Order
+	MemoryTypeObject    = MemoryType("object")    // json object
 )
 
+// Value is a typed memory value; only the field matching Type is set
+type Value struct {
+	Type      MemoryType         `json:"type"`
+	Str       string             `json:"str,omitempty"`
+	Number    float64            `json:"number,omitempty"`
+	Bool      bool               `json:"bool,omitempty"`
+	Series    []float64          `json:"series,omitempty"`
+	Klines    []types.KLineEvent `json:"klines,omitempty"`
+	Positions []types.Position   `json:"positions,omitempty"`
+	Orders    []order.Order      `json:"orders,omitempty"`
+	Object    map[string]any     `json:"object,omitempty"`
+}
+
+// String renders the value as text, e.g. for AI prompts; non-scalar values are json encoded
+func (v Value) String() string {
+	switch v.Type {
+	case MemoryTypeStr:
+		return v.Str
+	case MemoryTypeNumber:
+		return strconv.FormatFloat(v.Number, 'f', -1, 64)
+	case MemoryTypeBool:
+		return strconv.FormatBool(v.Bool)
+	}
+	var data any
+	switch v.Type {
+	case MemoryTypeSeries:
+		data = v.Series
+	case MemoryTypeKlines:
+		data = v.Klines
+	case MemoryTypePositions:
+		data = v.Positions
+	case MemoryTypeOrders:
+		data = v.Orders
+	case MemoryTypeObject:
+		data = v.Object
+	}
+	jsonData, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Sprintf("%v", data)
+	}
+	return string(jsonData)
+}
+
 // MARK: setters
 
-// store memory value
+// store memory value, inferring its type from the go type
 func (m *AgentMemory) Set(key string, value any) {
-	m.Data[key] = value
+	switch v := value.(type) {
+	case Value:
+		m.set(key, v)
+	case string:
+		m.SetAsStr(key, v)
+	case float64:
+		m.SetAsFloat64(key, v)
+	case int:
+		m.SetAsFloat64(key, float64(v))
+	case int64:
+		m.SetAsFloat64(key, float64(v))
+	case bool:
+		m.SetAsBool(key, v)
+	case []float64:
+		m.SetAsSeries(key, v)
+	case []types.KLineEvent:
+		m.SetAsKlines(key, v)
+	case []types.Position:
+		m.SetAsPositions(key, v)
+	case []order.Order:
+		m.SetAsOrders(key, v)
+	case map[string]any:
+		m.set(key, Value{Type: MemoryTypeObject, Object: v})
+	default:
+		if err := m.SetAsObject(key, v); err != nil {
+			m.SetAsStr(key, v)
+		}
+	}
 }
 
-// store memory value
+// store memory value as a string
 func (m *AgentMemory) SetAsStr(key string, value any) {
-	m.Data[key] = fmt.Sprintf("%v", value)
+	m.set(key, Value{Type: MemoryTypeStr, Str: fmt.Sprintf("%v", value)})
 }
 
-// SetKlines stores a slice of KLineEvents as JSON in memory
-func (m *AgentMemory) SetAsKlines(key string, klines []types.KLineEvent) error {
-	jsonData, err := json.Marshal(klines)
-	if err != nil {
-		return fmt.Errorf("failed to marshal klines to JSON: %w", err)
-	}
-	m.Data[key] = string(jsonData)
-	return nil
+func (m *AgentMemory) SetAsFloat64(key string, value float64) {
+	m.set(key, Value{Type: MemoryTypeNumber, Number: value})
 }
 
-// MARK: getters
+func (m *AgentMemory) SetAsBool(key string, value bool) {
+	m.set(key, Value{Type: MemoryTypeBool, Bool: value})
+}
 
-// retrieve memory value
-func (m *AgentMemory) Get(key string) any {
-	return m.Data[key]
+func (m *AgentMemory) SetAsSeries(key string, values []float64) {
+	m.set(key, Value{Type: MemoryTypeSeries, Series: values})
 }
 
-// retrieve memory value as a string
-func (m *AgentMemory) GetAsStr(key string) (string, error) {
-	value, ok := m.Data[key]
-	if !ok {
-		return "", fmt.Errorf("key '%v' not found in agent memory", key)
-	}
+func (m *AgentMemory) SetAsKlines(key string, klines []types.KLineEvent) {
+	m.set(key, Value{Type: MemoryTypeKlines, Klines: klines})
+}
 
-	casted, ok := value.(string)
-	if !ok {
-		return "", fmt.Errorf("value of key '%v' not stored as string", key)
-	}
+func (m *AgentMemory) SetAsPositions(key string, positions []types.Position) {
+	m.set(key, Value{Type: MemoryTypePositions, Positions: positions})
+}
 
-	return casted, nil
+func (m *AgentMemory) SetAsOrders(key string, orders []order.Order) {
+	m.set(key, Value{Type: MemoryTypeOrders, Orders: orders})
 }
 
-func (m *AgentMemory) GetAsKlines(key string) ([]types.KLineEvent, error) {
-	klinesStr, err := m.GetAsStr(key)
+// store a struct or map as a json object
+func (m *AgentMemory) SetAsObject(key string, value any) error {
+	jsonData, err := json.Marshal(value)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get klines string: %w", err)
+		return fmt.Errorf("failed to marshal object to JSON: %w", err)
+	}
+	var object map[string]any
+	if err := json.Unmarshal(jsonData, &object); err != nil {
+		return fmt.Errorf("value of key '%v' is not an object: %w", key, err)
 	}
+	m.set(key, Value{Type: MemoryTypeObject, Object: object})
+	return nil
+}
+
+// MARK: getters
+
+// ╔═════ conversion rules ═════╗
+// - any type reads as a string (scalars as text, others as json)
+// - strings are parsed when read as number, bool, series, klines or object
+// - a number reads as a single value series; a series reads as a number through its latest value
+// ╚════════════════════════════╝
 
-	// Add validation for empty string
-	if klinesStr == "" {
-		return nil, fmt.Errorf("empty klines string for key %s", key)
+// check if a value stored as `from` can be read as `to` by the getters
+func CanReadAs(from MemoryType, to MemoryType) bool {
+	switch {
+	case from == to, to == MemoryTypeStr:
+		return true
+	case from == MemoryTypeStr:
+		return to != MemoryTypePositions && to != MemoryTypeOrders
+	case from == MemoryTypeNumber:
+		return to == MemoryTypeSeries
+	case from == MemoryTypeSeries:
+		return to == MemoryTypeNumber
+	default:
+		return false
 	}
+}
 
-	var klines []types.KLineEvent
-	if err := json.Unmarshal([]byte(klinesStr), &klines); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal klines (raw: %s): %w", klinesStr, err)
+// retrieve memory value
+func (m *AgentMemory) Get(key string) (Value, error) {
+	m.store.mu.RLock()
+	value, ok := m.store.data[key]
+	m.store.mu.RUnlock()
+	if !ok {
+		return Value{}, fmt.Errorf("key '%v' not found in agent memory", key)
 	}
+	return value, nil
+}
 
-	return klines, nil
+// retrieve memory value as a string
+func (m *AgentMemory) GetAsStr(key string) (string, error) {
+	value, err := m.Get(key)
+	if err != nil {
+		return "", err
+	}
+	return value.String(), nil
 }
 
 // retrieve memory value as a float64
 func (m *AgentMemory) GetAsFloat64(key string) (float64, error) {
-	strValue, err := m.GetAsStr(key)
+	value, err := m.Get(key)
 	if err != nil {
 		return 0, err
 	}
-
-	casted, err := strconv.ParseFloat(strValue, 64)
-	if err != nil {
-		return 0, err
+	switch value.Type {
+	case MemoryTypeNumber:
+		return value.Number, nil
+	case MemoryTypeStr:
+		casted, err := strconv.ParseFloat(strings.TrimSpace(value.Str), 64)
+		if err != nil {
+			return 0, fmt.Errorf("value of key '%v' is not a number: %w", key, err)
+		}
+		return casted, nil
+	case MemoryTypeSeries:
+		if len(value.Series) == 0 {
+			return 0, fmt.Errorf("series of key '%v' is empty", key)
+		}
+		return value.Series[0], nil
+	default:
+		return 0, fmt.Errorf("value of key '%v' stored as %v cannot be read as number", key, value.Type)
 	}
-	return casted, nil
 }
 
-// retrieve memory value as an int64
+// retrieve memory value as an int
 func (m *AgentMemory) GetAsInt(key string) (int, error) {
-	strValue, err := m.GetAsStr(key)
+	casted, err := m.GetAsFloat64(key)
 	if err != nil {
 		return 0, err
 	}
-
-	casted, err := strconv.Atoi(strValue)
-	if err != nil {
-		return 0, err
+	if casted != math.Trunc(casted) {
+		return 0, fmt.Errorf("value of key '%v' is not an integer: %v", key, casted)
 	}
-	return casted, nil
+	return int(casted), nil
 }
 
 // retrieve memory value as an int64
 func (m *AgentMemory) GetAsInt64(key string) (int64, error) {
-	strValue, err := m.GetAsStr(key)
-	if err != nil {
-		return 0, err
-	}
+	casted, err := m.GetAsInt(key)
+	return int64(casted), err
+}
 
-	casted, err := strconv.Atoi(strValue)
+// retrieve memory value as a bool
+func (m *AgentMemory) GetAsBool(key string) (bool, error) {
+	value, err := m.Get(key)
 	if err != nil {
-		return 0, err
+		return false, err
+	}
+	switch value.Type {
+	case MemoryTypeBool:
+		return value.Bool, nil
+	case MemoryTypeStr:
+		casted, err := strconv.ParseBool(strings.TrimSpace(value.Str))
+		if err != nil {
+			return false, fmt.Errorf("value of key '%v' is not a bool: %w", key, err)
+		}
+		return casted, nil
+	default:
+		return false, fmt.Errorf("value of key '%v' stored as %v cannot be read as bool", key, value.Type)
 	}
-	return int64(casted), nil
 }
 
 // retrieve memory value as an interval
@@ -137,3 +386,89 @@ func (m *AgentMemory) GetAsInterval(key string) (types.Interval, error) {
 	casted := types.Interval(strValue)
 	return casted, nil
 }
+
+// retrieve memory value as a series of float64, latest first
+func (m *AgentMemory) GetAsSeries(key string) ([]float64, error) {
+	value, err := m.Get(key)
+	if err != nil {
+		return nil, err
+	}
+	switch value.Type {
+	case MemoryTypeSeries:
+		return value.Series, nil
+	case MemoryTypeNumber:
+		return []float64{value.Number}, nil
+	case MemoryTypeStr:
+		var series []float64
+		if err := json.Unmarshal([]byte(value.Str), &series); err != nil {
+			return nil, fmt.Errorf("value of key '%v' is not a series: %w", key, err)
+		}
+		return series, nil
+	default:
+		return nil, fmt.Errorf("value of key '%v' stored as %v cannot be read as series", key, value.Type)
+	}
+}
+
+func (m *AgentMemory) GetAsKlines(key string) ([]types.KLineEvent, error) {
+	value, err := m.Get(key)
+	if err != nil {
+		return nil, err
+	}
+	switch value.Type {
+	case MemoryTypeKlines:
+		if len(value.Klines) == 0 {
+			return nil, fmt.Errorf("empty klines for key %s", key)
+		}
+		return value.Klines, nil
+	case MemoryTypeStr:
+		var klines []types.KLineEvent
+		if err := json.Unmarshal([]byte(value.Str), &klines); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal klines (raw: %s): %w", value.Str, err)
+		}
+		return klines, nil
+	default:
+		return nil, fmt.Errorf("value of key '%v' stored as %v cannot be read as klines", key, value.Type)
+	}
+}
+
+func (m *AgentMemory) GetAsPositions(key string) ([]types.Position, error) {
+	value, err := m.Get(key)
+	if err != nil {
+		return nil, err
+	}
+	if value.Type != MemoryTypePositions {
+		return nil, fmt.Errorf("value of key '%v' stored as %v cannot be read as positions", key, value.Type)
+	}
+	return value.Positions, nil
+}
+
+func (m *AgentMemory) GetAsOrders(key string) ([]order.Order, error) {
+	value, err := m.Get(key)
+	if err != nil {
+		return nil, err
+	}
+	if value.Type != MemoryTypeOrders {
+		return nil, fmt.Errorf("value of key '%v' stored as %v cannot be read as orders", key, value.Type)
+	}
+	return value.Orders, nil
+}
+
+// retrieve memory value as a json object
+func (m *AgentMemory) GetAsObject(key string) (map[string]any, error) {
+	value, err := m.Get(key)
+	if err != nil {
+		return nil, err
+	}
+	switch value.Type {
+	case MemoryTypeObject:
+		return value.Object, nil
+	case MemoryTypeStr:
+		var object map[string]any
+		if err := json.Unmarshal([]byte(value.Str), &object); err != nil {
+			return nil, fmt.Errorf("value of key '%v' is not a json object: %w", key, err)
+		}
+		return object, nil
+	default:
+		return nil, fmt.Errorf("value of key '%v' stored as %v cannot be read as object", key, value.Type)
+	}
+}
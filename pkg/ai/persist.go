@@ -0,0 +1,162 @@
+package ai
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"lfg/pkg/storage"
+	"sort"
+	"strings"
+	"time"
+)
+
+// StoredPlan is an approved execution plan persisted for reuse across restarts
+type StoredPlan struct {
+	AgentId    string        `json:"agentId"`
+	Plan       ExecutionPlan `json:"plan"`
+	Model      string        `json:"model"`
+	PromptHash string        `json:"promptHash"` // the plan is reused only while this matches
+	CreatedAt  time.Time     `json:"createdAt"`
+}
+
+// StoredMemory is a snapshot of the agent memory, restored on startup while the plan is unchanged
+type StoredMemory struct {
+	AgentId  string           `json:"agentId"`
+	PlanHash string           `json:"planHash"` // memory keys only make sense for the plan that wrote them
+	Data     map[string]Value `json:"data"`
+	SavedAt  time.Time        `json:"savedAt"`
+}
+
+func planKey(agentId string) string {
+	return fmt.Sprintf("plans/%s.json", agentId)
+}
+
+func memoryKey(agentId string) string {
+	return fmt.Sprintf("memory/%s.json", agentId)
+}
+
+// promptHash covers every input the planner sees besides the task list,
+// so editing the prompt, exchanges, schedule or triggers invalidates the stored plan
+func (a *Agent) promptHash() string {
+	exchangeIds := []string{}
+	for exchangeId := range a.Memory.Exchanges {
+		exchangeIds = append(exchangeIds, exchangeId)
+	}
+	sort.Strings(exchangeIds)
+
+	h := sha256.New()
+	h.Write([]byte(a.Prompt))
+	h.Write([]byte{0})
+	h.Write([]byte(getRunContextDescription(a.Config)))
+	h.Write([]byte{0})
+	h.Write([]byte(strings.Join(exchangeIds, ",")))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// loadStoredPlan returns the persisted plan if it was made for the current prompt, nil otherwise
+func (a *Agent) loadStoredPlan() (*StoredPlan, error) {
+	data, err := a.Store.Get(planKey(a.Id))
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("fail to load stored plan: %w", err)
+	}
+	var stored StoredPlan
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return nil, fmt.Errorf("fail to decode stored plan: %w", err)
+	}
+	if stored.PromptHash != a.promptHash() {
+		a.logger.Infof("stored plan from %v is outdated, prompt changed", stored.CreatedAt.Format(time.RFC3339))
+		return nil, nil
+	}
+	return &stored, nil
+}
+
+func (a *Agent) saveStoredPlan(plan ExecutionPlan) error {
+	data, err := json.MarshalIndent(StoredPlan{
+		AgentId:    a.Id,
+		Plan:       plan,
+		Model:      a.Planner.Model(),
+		PromptHash: a.promptHash(),
+		CreatedAt:  time.Now(),
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := a.Store.Put(planKey(a.Id), data); err != nil {
+		return fmt.Errorf("fail to store plan: %w", err)
+	}
+	return nil
+}
+
+func getPlanHash(plan ExecutionPlan) (string, error) {
+	jsonPlan, err := json.Marshal(plan)
+	if err != nil {
+		return "", err
+	}
+	hash := sha256.Sum256(jsonPlan)
+	return hex.EncodeToString(hash[:]), nil
+}
+
+// SaveMemory snapshots the agent memory to the store between runs; it returns saved=false
+// without waiting if a run is in progress, as its memory may be half-updated
+func (a *Agent) SaveMemory() (saved bool, err error) {
+	plan := a.GetExecutionPlan()
+	if a.Store == nil || plan == nil {
+		return false, nil
+	}
+	if !a.execMu.TryLock() {
+		return false, nil
+	}
+	defer a.execMu.Unlock()
+	planHash, err := getPlanHash(*plan)
+	if err != nil {
+		return false, err
+	}
+
+	data, err := json.Marshal(StoredMemory{
+		AgentId:  a.Id,
+		PlanHash: planHash,
+		Data:     a.Memory.Snapshot(),
+		SavedAt:  time.Now(),
+	})
+	if err != nil {
+		return false, err
+	}
+	if err := a.Store.Put(memoryKey(a.Id), data); err != nil {
+		return false, fmt.Errorf("fail to store memory snapshot: %w", err)
+	}
+	return true, nil
+}
+
+// restoreMemory overwrites the initial state with the last snapshot taken under the same plan
+func (a *Agent) restoreMemory(plan ExecutionPlan) error {
+	data, err := a.Store.Get(memoryKey(a.Id))
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("fail to load memory snapshot: %w", err)
+	}
+	var stored StoredMemory
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return fmt.Errorf("fail to decode memory snapshot: %w", err)
+	}
+	planHash, err := getPlanHash(plan)
+	if err != nil {
+		return err
+	}
+	if stored.PlanHash != planHash {
+		a.logger.Infof("memory snapshot from %v belongs to another plan, starting from init state", stored.SavedAt.Format(time.RFC3339))
+		return nil
+	}
+	restoredMemory := a.Memory.WithWriter("snapshot", "")
+	for key, value := range stored.Data {
+		restoredMemory.Set(key, value)
+	}
+	a.logger.Infof("memory restored from snapshot at %v: %v keys", stored.SavedAt.Format(time.RFC3339), len(stored.Data))
+	return nil
+}
@@ -0,0 +1,52 @@
+package ai
+
+import (
+	"encoding/json"
+	"lfg/pkg/storage"
+	"testing"
+)
+
+type mapStore map[string][]byte
+
+func (s mapStore) Get(key string) ([]byte, error) {
+	data, exists := s[key]
+	if !exists {
+		return nil, storage.ErrNotFound
+	}
+	return data, nil
+}
+
+func (s mapStore) Put(key string, data []byte) error {
+	s[key] = data
+	return nil
+}
+
+func TestSaveMemoryBetweenRuns(t *testing.T) {
+	store := mapStore{}
+	agent := &Agent{Id: "test", Memory: NewAgentMemory(nil, nil), Store: store}
+	agent.setExecutionPlan(&ExecutionPlan{Reasoning: "hold", Tasks: []TaskFromAI{}, InitState: []Memory{}})
+	agent.Memory.SetAsFloat64("position", 1)
+
+	// a run in progress may have written only part of its memory
+	agent.execMu.Lock()
+	saved, err := agent.SaveMemory()
+	agent.execMu.Unlock()
+	if err != nil || saved {
+		t.Fatalf("SaveMemory() during a run = %v, %v; want skipped", saved, err)
+	}
+	if _, exists := store[memoryKey("test")]; exists {
+		t.Fatalf("memory stored during a run")
+	}
+
+	saved, err = agent.SaveMemory()
+	if err != nil || !saved {
+		t.Fatalf("SaveMemory() between runs = %v, %v; want saved", saved, err)
+	}
+	var stored StoredMemory
+	if err := json.Unmarshal(store[memoryKey("test")], &stored); err != nil {
+		t.Fatalf("fail to unmarshal snapshot: %v", err)
+	}
+	if stored.AgentId != "test" || stored.Data["position"].Number != 1 {
+		t.Fatalf("snapshot = %+v", stored)
+	}
+}
@@ -4,101 +4,83 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"os"
-
-	"github.com/openai/openai-go"
-	"github.com/openai/openai-go/option"
+	"lfg/pkg/llm"
 )
 
-// OpenAIClient holds the OpenAI client instance
-var OpenAIClient *openai.Client
-
-// InitOpenAIClient initializes the OpenAI client with configuration
-func InitOpenAIClient() error {
-	openaiBaseUrl := os.Getenv("OPENAI_BASE_URL")
-	if openaiBaseUrl == "" {
-		openaiBaseUrl = "https://openrouter.ai/api/v1/"
+// describe the available tasks for the planner
+func getTasksDescription(tasks []BaseTask) string {
+	tasksDescription := ""
+	for _, task := range tasks {
+		tasksDescription += fmt.Sprintf("- %s\n\tDescription: %s\n", task.Name, task.Description)
+		if task.PlacesOrders {
+			tasksDescription += "\tPlaces orders: OnError 'retry' is not allowed\n"
+		}
+		tasksDescription += "\tParameters:\n"
+		for _, param := range task.Parameters {
+			info := param.Type
+			if !param.Required {
+				info += ", optional"
+			}
+			if param.MemoryType != "" && isOutputParam(param.Name) {
+				info += ", writes memory " + string(param.MemoryType)
+			} else if param.MemoryType != "" {
+				info += ", reads memory " + string(param.MemoryType)
+			}
+			tasksDescription += fmt.Sprintf("\t\t- %s [%s] (%s)\n", param.Name, info, param.Description)
+		}
 	}
+	return tasksDescription
+}
 
-	openaiApiKey := os.Getenv("OPENAI_API_KEY")
-	if openaiApiKey == "" {
-		return fmt.Errorf("OPENAI_API_KEY is not set")
+// render previous planning rounds for the planner
+func getMessagesDescription(messages []llm.Message) string {
+	messagesDescription := ""
+	for _, message := range messages {
+		messagesDescription += fmt.Sprintf("[%s]: %s\n", message.Role, message.Content)
 	}
-
-	OpenAIClient = openai.NewClient(
-		option.WithBaseURL(openaiBaseUrl),
-		option.WithAPIKey(openaiApiKey),
-	)
-
-	return nil
+	return messagesDescription
 }
 
 // get system prompt for generating execution plan
-func getSystemPrompt(query string, prevMessages []openai.ChatCompletionMessageParamUnion, tasks []BaseTask, availableExchangesId []string) (string, error) {
-	tasksDescription := ""
-	for _, task := range tasks {
-		tasksDescription += fmt.Sprintf("- %s\n\tDescription: %s\n\tParameters:\n", task.Name, task.Description)
-		for key, value := range task.Parameters {
-			tasksDescription += fmt.Sprintf("\t\t- %s (%s)\n", key, value)
-		}
-	}
+func getSystemPrompt(query string, prevMessages []llm.Message, tasks []BaseTask, availableExchangesId []string, runContext string) (string, error) {
+	tasksDescription := getTasksDescription(tasks)
 
-	systemPrompt := fmt.Sprintf(SystemPrompt, tasksDescription, prevMessages, query, availableExchangesId)
+	systemPrompt := fmt.Sprintf(SystemPrompt, tasksDescription, getMessagesDescription(prevMessages), query, availableExchangesId, runContext)
 	return systemPrompt, nil
 }
 
 // get system prompt for refining execution plan
-func getRefinerPrompt(question string, executionPlan ExecutionPlan, tasks []BaseTask, availableExchangesId []string, userComment string) (string, error) {
-	tasksDescription := ""
-	for _, task := range tasks {
-		tasksDescription += fmt.Sprintf("- %s\n\tDescription: %s\n\tParameters:\n", task.Name, task.Description)
-		for key, value := range task.Parameters {
-			tasksDescription += fmt.Sprintf("\t\t- %s (%s)\n", key, value)
-		}
-	}
+func getRefinerPrompt(question string, executionPlan ExecutionPlan, tasks []BaseTask, availableExchangesId []string, runContext string, userComment string) (string, error) {
+	tasksDescription := getTasksDescription(tasks)
 
 	executionPlanJson, err := json.MarshalIndent(executionPlan, "", "  ")
 	if err != nil {
 		return "", err
 	}
 
-	refinerPrompt := fmt.Sprintf(RefinerPrompt, tasksDescription, executionPlanJson, question, availableExchangesId, userComment)
+	refinerPrompt := fmt.Sprintf(RefinerPrompt, tasksDescription, executionPlanJson, question, availableExchangesId, runContext, userComment)
 	return refinerPrompt, nil
 }
 
 // generate execution plan
-func GenerateExecutionPlan(ctx context.Context, client *openai.Client, availableExchangesId []string, question string, prevMessages []openai.ChatCompletionMessageParamUnion) (ExecutionPlan, error) {
+func GenerateExecutionPlan(ctx context.Context, client llm.Client, availableExchangesId []string, runContext string, question string, prevMessages []llm.Message) (ExecutionPlan, error) {
 	fmt.Println("Generating execution plan...")
 	fmt.Println(question)
 	tasks := GetAllTaskInterfaces()
-	systemPrompt, err := getSystemPrompt(question, prevMessages, tasks, availableExchangesId)
+	systemPrompt, err := getSystemPrompt(question, prevMessages, tasks, availableExchangesId, runContext)
 	if err != nil {
 		return ExecutionPlan{}, err
 	}
-	messages := []openai.ChatCompletionMessageParamUnion{
-		openai.SystemMessage(systemPrompt),
-	}
-	chatCompletion, err := client.Chat.Completions.New(
-		ctx,
-		openai.ChatCompletionNewParams{
-			Messages: openai.F(messages),
-			ResponseFormat: openai.F[openai.ChatCompletionNewParamsResponseFormatUnion](
-				openai.ResponseFormatJSONSchemaParam{
-					Type:       openai.F(openai.ResponseFormatJSONSchemaTypeJSONSchema),
-					JSONSchema: openai.F(planSchemaParam),
-				},
-			),
-			Model:       openai.F("openai/gpt-4o-mini"),
-			Modalities:  openai.F([]openai.ChatCompletionModality{openai.ChatCompletionModality(openai.ChatCompletionNewParamsResponseFormatTypeJSONSchema)}),
-			Temperature: openai.F(0.23),
-		},
-	)
+	res, err := client.Complete(ctx, llm.Request{
+		Messages:       []llm.Message{{Role: llm.RoleSystem, Content: systemPrompt}},
+		ResponseFormat: &planResponseFormat,
+	})
 	if err != nil {
 		return ExecutionPlan{}, err
 	}
 
 	var executionPlan ExecutionPlan
-	err = json.Unmarshal([]byte(chatCompletion.Choices[0].Message.Content), &executionPlan)
+	err = json.Unmarshal([]byte(res.Message.Content), &executionPlan)
 	if err != nil {
 		return ExecutionPlan{}, err
 	}
@@ -106,98 +88,50 @@ func GenerateExecutionPlan(ctx context.Context, client *openai.Client, available
 }
 
 // refine execution plan
-func RefineExecutionPlan(ctx context.Context, client *openai.Client, availableExchangesId []string, question string, executionPlan ExecutionPlan, userComment string) (Feedback, error) {
+func RefineExecutionPlan(ctx context.Context, client llm.Client, availableExchangesId []string, runContext string, question string, executionPlan ExecutionPlan, userComment string) (Feedback, error) {
 	fmt.Println("Refining execution plan...")
 	tasks := GetAllTaskInterfaces()
-	refinerPrompt, err := getRefinerPrompt(question, executionPlan, tasks, availableExchangesId, userComment)
+	refinerPrompt, err := getRefinerPrompt(question, executionPlan, tasks, availableExchangesId, runContext, userComment)
 	if err != nil {
 		return Feedback{}, err
 	}
-	messages := []openai.ChatCompletionMessageParamUnion{
-		openai.SystemMessage(refinerPrompt),
-	}
-	chatCompletion, err := client.Chat.Completions.New(
-		ctx,
-		openai.ChatCompletionNewParams{
-			Messages: openai.F(messages),
-			ResponseFormat: openai.F[openai.ChatCompletionNewParamsResponseFormatUnion](
-				openai.ResponseFormatJSONSchemaParam{
-					Type:       openai.F(openai.ResponseFormatJSONSchemaTypeJSONSchema),
-					JSONSchema: openai.F(feedbackSchemaParam),
-				},
-			),
-			Model:       openai.F("openai/gpt-4o-mini"),
-			Modalities:  openai.F([]openai.ChatCompletionModality{openai.ChatCompletionModality(openai.ChatCompletionNewParamsResponseFormatTypeJSONSchema)}),
-			Temperature: openai.F(0.23),
-		},
-	)
+	res, err := client.Complete(ctx, llm.Request{
+		Messages:       []llm.Message{{Role: llm.RoleSystem, Content: refinerPrompt}},
+		ResponseFormat: &feedbackResponseFormat,
+	})
 	if err != nil {
 		return Feedback{}, err
 	}
 
 	var feedback Feedback
-	err = json.Unmarshal([]byte(chatCompletion.Choices[0].Message.Content), &feedback)
+	err = json.Unmarshal([]byte(res.Message.Content), &feedback)
 	if err != nil {
 		return Feedback{}, err
 	}
 	return feedback, nil
 }
 
-func GetCompletion(ctx context.Context, client *openai.Client, prompt string) (string, error) {
-
-	chatCompletion, err := client.Chat.Completions.New(
-		ctx,
-		openai.ChatCompletionNewParams{
-			Messages: openai.F([]openai.ChatCompletionMessageParamUnion{
-				openai.UserMessage(prompt),
-			}),
-			Model:       openai.F("openai/gpt-4o-mini"),
-			Temperature: openai.F(0.23),
-		},
-	)
-	if err != nil {
-		return "", err
-	}
-	if len(chatCompletion.Choices) == 0 {
-		return "", fmt.Errorf("no completion found")
-	}
-	return chatCompletion.Choices[0].Message.Content, nil
+func GetCompletion(ctx context.Context, client llm.Client, prompt string) (string, error) {
+	return llm.Text(ctx, client, prompt)
 }
 
-func GetStructuredCompletion(ctx context.Context, client *openai.Client, prompt string) (map[string]string, error) {
+func GetStructuredCompletion(ctx context.Context, client llm.Client, prompt string) (map[string]string, error) {
 
 	var schema, _ = GenerateSchema[map[string]string]()
-	chatCompletion, err := client.Chat.Completions.New(
-		ctx,
-		openai.ChatCompletionNewParams{
-			Messages: openai.F([]openai.ChatCompletionMessageParamUnion{
-				openai.UserMessage(prompt),
-			}),
-			ResponseFormat: openai.F[openai.ChatCompletionNewParamsResponseFormatUnion](
-				openai.ResponseFormatJSONSchemaParam{
-					Type: openai.F(openai.ResponseFormatJSONSchemaTypeJSONSchema),
-					JSONSchema: openai.F(
-						openai.ResponseFormatJSONSchemaJSONSchemaParam{
-							Name:        openai.F("JSON Response"),
-							Description: openai.F("The JSON response from the AI"),
-							Schema:      openai.F(schema),
-						},
-					),
-				},
-			),
-			Model:       openai.F("openai/gpt-4o-mini"),
-			Temperature: openai.F(0.23),
+	res, err := client.Complete(ctx, llm.Request{
+		Messages: []llm.Message{{Role: llm.RoleUser, Content: prompt}},
+		ResponseFormat: &llm.ResponseFormat{
+			Name:        "JSON Response",
+			Description: "The JSON response from the AI",
+			Schema:      schema,
 		},
-	)
+	})
 	if err != nil {
 		return map[string]string{}, err
 	}
-	if len(chatCompletion.Choices) == 0 {
-		return map[string]string{}, fmt.Errorf("no completion found")
-	}
 	var jsonRes map[string]string
 
-	err = json.Unmarshal([]byte(chatCompletion.Choices[0].Message.Content), &jsonRes)
+	err = json.Unmarshal([]byte(res.Message.Content), &jsonRes)
 	if err != nil {
 		return map[string]string{}, err
 	}
@@ -208,6 +142,9 @@ func GetReadablePlan(plan ExecutionPlan) string {
 	str := fmt.Sprintf("Reasoning: %s\n\nTasks:", plan.Reasoning)
 	for _, task := range plan.Tasks {
 		str += fmt.Sprintf("\n\t- %s", task.Name)
+		if task.OnError != "" || task.Timeout != "" {
+			str += fmt.Sprintf(" (onError: %s, retries: %d, timeout: %s)", task.OnError, task.Retries, task.Timeout)
+		}
 		for key, value := range task.Parameters {
 			str += fmt.Sprintf("\n\t\t- %s: %s", key, value)
 		}
@@ -0,0 +1,180 @@
+package ai
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/invopop/jsonschema"
+)
+
+// TaskParameter describes a task parameter; it is derived from the `json` and
+// `jsonschema_description` (and optional `memory`) tags of the task struct
+type TaskParameter struct {
+	Name        string
+	Type        string // json type: string | integer | number | boolean
+	Description string
+	Required    bool       // false if the json tag has `omitempty`
+	MemoryType  MemoryType // type of the memory value the key parameter reads or writes, from the `memory` tag
+}
+
+var (
+	taskRegistry = make(map[string]*AgentTask)
+	taskNames    = []string{} // registration order
+)
+
+// Validatable is implemented by tasks whose parameters depend on each other
+type Validatable interface {
+	Validate() error
+}
+
+// OutputTyped is implemented by tasks whose output type depends on their parameters;
+// it overrides the `memory` tag of the output parameters
+type OutputTyped interface {
+	OutputMemoryType() MemoryType
+}
+
+// RegisterTask registers a task under a unique name; `executable` must be a pointer to a struct.
+// Intended to be called from init().
+func RegisterTask(name string, description string, executable Executable) {
+	registerTask(name, description, executable, false)
+}
+
+// RegisterOrderTask registers a task sending orders to the exchange, see RegisterTask
+func RegisterOrderTask(name string, description string, executable Executable) {
+	registerTask(name, description, executable, true)
+}
+
+func registerTask(name string, description string, executable Executable, placesOrders bool) {
+	if _, exists := taskRegistry[name]; exists {
+		panic(fmt.Sprintf("task %v registered twice", name))
+	}
+	t := reflect.TypeOf(executable)
+	if t.Kind() != reflect.Ptr || t.Elem().Kind() != reflect.Struct {
+		panic(fmt.Sprintf("task %v executable must be a pointer to a struct", name))
+	}
+	taskRegistry[name] = &AgentTask{
+		BaseTask: BaseTask{
+			Name:         name,
+			Description:  description,
+			Parameters:   getTaskParameters(t.Elem()),
+			PlacesOrders: placesOrders,
+		},
+		Executable: executable,
+	}
+	taskNames = append(taskNames, name)
+}
+
+func getTaskParameters(t reflect.Type) []TaskParameter {
+	params := []TaskParameter{}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Anonymous && field.Type.Kind() == reflect.Struct && field.Tag.Get("json") == "" {
+			params = append(params, getTaskParameters(field.Type)...) // embedded params, e.g. PositionSizing
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+		tags := strings.Split(field.Tag.Get("json"), ",")
+		if tags[0] == "" || tags[0] == "-" {
+			continue
+		}
+		params = append(params, TaskParameter{
+			Name:        tags[0],
+			Type:        getJsonType(field.Type.Kind()),
+			Description: field.Tag.Get("jsonschema_description"),
+			Required:    !strings.Contains(field.Tag.Get("json"), "omitempty"),
+			MemoryType:  MemoryType(field.Tag.Get("memory")),
+		})
+	}
+	return params
+}
+
+// `outputKey` & `<name>OutputKey` params are memory keys written by the task
+func isOutputParam(name string) bool {
+	return name == "outputKey" || strings.HasSuffix(name, "OutputKey")
+}
+
+func getJsonType(kind reflect.Kind) string {
+	switch kind {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "integer"
+	case reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.Bool:
+		return "boolean"
+	default:
+		return "string"
+	}
+}
+
+// Schema returns the JSON schema of the task parameters
+func (t *AgentTask) Schema() *jsonschema.Schema {
+	reflector := jsonschema.Reflector{
+		AllowAdditionalProperties: false,
+		DoNotReference:            true,
+	}
+	return reflector.ReflectFromType(reflect.TypeOf(t.Executable).Elem())
+}
+
+// newExecutable creates a fresh executable of the task from plan parameters; the planner
+// emits every value as a string, so values are converted to the parameter type first
+func (t *AgentTask) newExecutable(params map[string]string) (Executable, error) {
+	typed := make(map[string]any)
+	for key, value := range params {
+		param, exists := t.getParameter(key)
+		if !exists {
+			return nil, fmt.Errorf("unknown parameter '%v' for task %v", key, t.Name)
+		}
+		var err error
+		switch param.Type {
+		case "integer":
+			typed[key], err = strconv.ParseInt(value, 10, 64)
+		case "number":
+			typed[key], err = strconv.ParseFloat(value, 64)
+		case "boolean":
+			typed[key], err = strconv.ParseBool(value)
+		default:
+			typed[key] = value
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid %v value '%v' for parameter '%v' of task %v", param.Type, value, key, t.Name)
+		}
+	}
+	for _, param := range t.Parameters {
+		if _, exists := params[param.Name]; param.Required && !exists {
+			return nil, fmt.Errorf("missing parameter '%v' for task %v", param.Name, t.Name)
+		}
+	}
+
+	paramsJSON, err := json.Marshal(typed)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal params: %w", err)
+	}
+	executable := reflect.New(reflect.TypeOf(t.Executable).Elem()).Interface().(Executable)
+	decoder := json.NewDecoder(bytes.NewReader(paramsJSON))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(executable); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal params: %w", err)
+	}
+	if v, ok := executable.(Validatable); ok {
+		if err := v.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid parameters for task %v: %w", t.Name, err)
+		}
+	}
+	return executable, nil
+}
+
+func (t *AgentTask) getParameter(name string) (TaskParameter, bool) {
+	for _, param := range t.Parameters {
+		if param.Name == name {
+			return param, true
+		}
+	}
+	return TaskParameter{}, false
+}
@@ -0,0 +1,138 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"lfg/pkg/types"
+	"time"
+)
+
+const (
+	DEFAULT_TASK_RETRIES = 3
+	DEFAULT_TASK_TIMEOUT = 2 * time.Minute
+	MAX_TASK_RETRIES     = 10
+	RETRY_BASE_BACKOFF   = 1 * time.Second // doubled after each retry
+)
+
+// TaskPolicy is how the agent runs a task of the plan
+type TaskPolicy struct {
+	OnError types.TaskErrorPolicy
+	Retries int           // retry only
+	Timeout time.Duration // of one attempt
+}
+
+func newTaskPolicy(task TaskFromAI) (TaskPolicy, error) {
+	policy := TaskPolicy{OnError: task.OnError, Timeout: DEFAULT_TASK_TIMEOUT}
+	switch task.OnError {
+	case "":
+		policy.OnError = types.TaskErrorAbort
+	case types.TaskErrorAbort, types.TaskErrorSkip:
+	case types.TaskErrorRetry:
+		// @dev: a failed attempt (e.g. a request timeout) may still have placed its order,
+		// and the timeout does not interrupt the exchange call
+		if registered, exists := taskRegistry[task.Name]; exists && registered.PlacesOrders {
+			return TaskPolicy{}, fmt.Errorf("OnError '%v' is not allowed on a task placing orders, use '%v' or '%v'", types.TaskErrorRetry, types.TaskErrorAbort, types.TaskErrorSkip)
+		}
+		policy.Retries = DEFAULT_TASK_RETRIES
+		if task.Retries > 0 {
+			policy.Retries = task.Retries
+		}
+	default:
+		return TaskPolicy{}, fmt.Errorf("unknown OnError '%v', must be one of [%v, %v, %v]", task.OnError, types.TaskErrorAbort, types.TaskErrorSkip, types.TaskErrorRetry)
+	}
+	if task.Retries != 0 && policy.OnError != types.TaskErrorRetry {
+		return TaskPolicy{}, fmt.Errorf("Retries is only valid with OnError '%v', got OnError '%v'", types.TaskErrorRetry, policy.OnError)
+	}
+	if task.Retries < 0 || task.Retries > MAX_TASK_RETRIES {
+		return TaskPolicy{}, fmt.Errorf("Retries must be between 0 and %v, got %v", MAX_TASK_RETRIES, task.Retries)
+	}
+	if task.Timeout != "" {
+		timeout, err := time.ParseDuration(task.Timeout)
+		if err != nil || timeout <= 0 {
+			return TaskPolicy{}, fmt.Errorf("invalid Timeout '%v'", task.Timeout)
+		}
+		policy.Timeout = timeout
+	}
+	return policy, nil
+}
+
+type RunResult struct {
+	RunId     string
+	StartedAt time.Time
+	Duration  time.Duration
+	Aborted   bool // a task failed under the `abort` or `retry` policy
+	Tasks     []TaskResult
+}
+
+type TaskResult struct {
+	Name     string
+	Status   types.TaskStatus
+	Attempts int
+	Duration time.Duration
+	Error    string `json:",omitempty"`
+}
+
+// runTask executes the task under its policy; the returned error is the last attempt error
+func (a *Agent) runTask(ctx context.Context, task AgentTask, memory *AgentMemory) (result TaskResult, err error) {
+	result.Name = task.Name
+	start := time.Now()
+	defer func() {
+		result.Duration = time.Since(start)
+	}()
+
+	backoff := RETRY_BASE_BACKOFF
+	for {
+		result.Attempts++
+		err = executeWithTimeout(ctx, task, memory)
+		if err == nil {
+			result.Status = types.TaskStatusSucceeded
+			return result, nil
+		}
+		if result.Attempts > task.Policy.Retries || ctx.Err() != nil {
+			result.Status = types.TaskStatusFailed
+			result.Error = err.Error()
+			return result, err
+		}
+		a.logger.Warnf("task %v failed (attempt %v/%v), retrying in %v: %v", task.Name, result.Attempts, task.Policy.Retries+1, backoff, err)
+		select {
+		case <-ctx.Done():
+			result.Status = types.TaskStatusFailed
+			result.Error = err.Error()
+			return result, err
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// @dev: the timeout cancels the task context; calls that do not take a context
+// (e.g. exchange REST calls) are not interrupted and finish first
+func executeWithTimeout(ctx context.Context, task AgentTask, memory *AgentMemory) error {
+	if task.Policy.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, task.Policy.Timeout)
+		defer cancel()
+	}
+	err := task.Executable.Execute(ctx, memory)
+	if err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("timed out after %v: %w", task.Policy.Timeout, err)
+	}
+	return err
+}
+
+// Summary is a one-line description of the run for logs
+func (r *RunResult) Summary() string {
+	counts := make(map[types.TaskStatus]int)
+	for _, task := range r.Tasks {
+		counts[task.Status]++
+	}
+	summary := fmt.Sprintf("run %v finished in %v: %v/%v tasks succeeded", r.RunId, r.Duration.Round(time.Millisecond), counts[types.TaskStatusSucceeded], len(r.Tasks))
+	if failed := counts[types.TaskStatusFailed]; failed > 0 {
+		summary += fmt.Sprintf(", %v failed", failed)
+	}
+	if r.Aborted {
+		summary += fmt.Sprintf(", %v not run (aborted)", counts[types.TaskStatusNotRun])
+	}
+	return summary
+}
@@ -0,0 +1,135 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"lfg/pkg/types"
+	"strings"
+	"testing"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// stubTask fails its first `failures` executions; with `block` set, it waits for the context instead
+type stubTask struct {
+	failures int
+	block    bool
+	calls    int
+}
+
+func (s *stubTask) Execute(ctx context.Context, memory *AgentMemory) error {
+	s.calls++
+	if s.block {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	if s.calls <= s.failures {
+		return errors.New("exchange unavailable")
+	}
+	return nil
+}
+
+func TestNewTaskPolicy(t *testing.T) {
+	policy, err := newTaskPolicy(TaskFromAI{Name: "getKlines"})
+	if err != nil {
+		t.Fatalf("default policy: unexpected error: %v", err)
+	}
+	if policy != (TaskPolicy{OnError: types.TaskErrorAbort, Timeout: DEFAULT_TASK_TIMEOUT}) {
+		t.Fatalf("default policy = %+v", policy)
+	}
+
+	policy, err = newTaskPolicy(TaskFromAI{Name: "getKlines", OnError: types.TaskErrorRetry})
+	if err != nil || policy.Retries != DEFAULT_TASK_RETRIES {
+		t.Fatalf("retry policy = %+v, %v; want %v retries", policy, err, DEFAULT_TASK_RETRIES)
+	}
+
+	policy, err = newTaskPolicy(TaskFromAI{Name: "getKlines", OnError: types.TaskErrorRetry, Retries: 5, Timeout: "30s"})
+	if err != nil || policy.Retries != 5 || policy.Timeout != 30*time.Second {
+		t.Fatalf("custom retry policy = %+v, %v; want 5 retries of 30s", policy, err)
+	}
+
+	for _, task := range []TaskFromAI{
+		{Name: "getKlines", OnError: "ignore"},
+		{Name: "getKlines", OnError: types.TaskErrorRetry, Retries: MAX_TASK_RETRIES + 1},
+		{Name: "getKlines", OnError: types.TaskErrorRetry, Retries: -1},
+		{Name: "getKlines", Retries: 2},
+		{Name: "getKlines", OnError: types.TaskErrorSkip, Retries: 2},
+		{Name: "getKlines", Timeout: "soon"},
+		{Name: "getKlines", Timeout: "-1s"},
+	} {
+		if _, err := newTaskPolicy(task); err == nil {
+			t.Errorf("%+v: want error", task)
+		}
+	}
+
+	// a retried order may have been placed by the failed attempt
+	for _, name := range []string{"openMarketLongPositionIf", "closePositionIf", "openLimitLadderIf"} {
+		if _, err := newTaskPolicy(TaskFromAI{Name: name, OnError: types.TaskErrorRetry}); err == nil {
+			t.Errorf("retry on %v: want error", name)
+		}
+	}
+	if _, err := newTaskPolicy(TaskFromAI{Name: "openMarketLongPositionIf", OnError: types.TaskErrorSkip}); err != nil {
+		t.Errorf("skip on a task placing orders: unexpected error: %v", err)
+	}
+}
+
+func TestRunTask(t *testing.T) {
+	agent := &Agent{logger: log.WithField("agent", "test")}
+	memory := NewAgentMemory(nil, nil)
+
+	t.Run("retried until it succeeds", func(t *testing.T) {
+		stub := &stubTask{failures: 1}
+		task := AgentTask{BaseTask: BaseTask{Name: "stub"}, Executable: stub, Policy: TaskPolicy{OnError: types.TaskErrorRetry, Retries: 2}}
+		result, err := agent.runTask(context.Background(), task, memory)
+		if err != nil || result.Status != types.TaskStatusSucceeded || result.Attempts != 2 {
+			t.Fatalf("result = %+v, %v; want succeeded after 2 attempts", result, err)
+		}
+	})
+
+	t.Run("failed without retries", func(t *testing.T) {
+		stub := &stubTask{failures: 1}
+		task := AgentTask{BaseTask: BaseTask{Name: "stub"}, Executable: stub, Policy: TaskPolicy{OnError: types.TaskErrorSkip}}
+		result, err := agent.runTask(context.Background(), task, memory)
+		if err == nil || result.Status != types.TaskStatusFailed || result.Attempts != 1 || result.Error != err.Error() {
+			t.Fatalf("result = %+v, %v; want failed after 1 attempt", result, err)
+		}
+	})
+
+	t.Run("attempt timed out", func(t *testing.T) {
+		stub := &stubTask{block: true}
+		task := AgentTask{BaseTask: BaseTask{Name: "stub"}, Executable: stub, Policy: TaskPolicy{OnError: types.TaskErrorAbort, Timeout: 10 * time.Millisecond}}
+		_, err := agent.runTask(context.Background(), task, memory)
+		if err == nil || !strings.Contains(err.Error(), "timed out") || !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("error = %v, want timeout", err)
+		}
+	})
+
+	t.Run("no retry once the run is canceled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		stub := &stubTask{failures: 5}
+		task := AgentTask{BaseTask: BaseTask{Name: "stub"}, Executable: stub, Policy: TaskPolicy{OnError: types.TaskErrorRetry, Retries: 5}}
+		result, err := agent.runTask(ctx, task, memory)
+		if err == nil || result.Attempts != 1 {
+			t.Fatalf("result = %+v, %v; want failed after 1 attempt", result, err)
+		}
+	})
+}
+
+func TestRunResultSummary(t *testing.T) {
+	result := RunResult{
+		RunId:    "run-1",
+		Duration: 1500 * time.Millisecond,
+		Aborted:  true,
+		Tasks: []TaskResult{
+			{Name: "getKlines", Status: types.TaskStatusSucceeded},
+			{Name: "askAI", Status: types.TaskStatusFailed},
+			{Name: "openMarketLongPositionIf", Status: types.TaskStatusNotRun},
+		},
+	}
+	want := "run run-1 finished in 1.5s: 1/3 tasks succeeded, 1 failed, 1 not run (aborted)"
+	if got := result.Summary(); got != want {
+		t.Fatalf("Summary() = %q, want %q", got, want)
+	}
+}
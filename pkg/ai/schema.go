@@ -1,8 +1,10 @@
 package ai
 
 import (
+	"lfg/pkg/llm"
+	"lfg/pkg/types"
+
 	"github.com/invopop/jsonschema"
-	"github.com/openai/openai-go"
 )
 
 type ExecutionPlan struct {
@@ -22,8 +24,11 @@ type Memory struct {
 }
 
 type TaskFromAI struct {
-	Name       string            `json:"Name" jsonschema_description:"The exact function name of the tool to be executed"`
-	Parameters map[string]string `json:"Parameters" jsonschema_description:"The parameters of the tool"`
+	Name       string                `json:"Name" jsonschema_description:"The exact function name of the tool to be executed"`
+	Parameters map[string]string     `json:"Parameters" jsonschema_description:"The parameters of the tool"`
+	OnError    types.TaskErrorPolicy `json:"OnError,omitempty" jsonschema:"enum=abort,enum=skip,enum=retry" jsonschema_description:"What to do if the tool fails: 'abort' (default) stops the run, 'skip' continues with the next tool (e.g. optional data), 'retry' retries the tool Retries times with backoff then stops the run (e.g. flaky exchange data), not allowed on tools placing orders"`
+	Retries    int                   `json:"Retries,omitempty" jsonschema_description:"retry only; the max number of retries, default 3"`
+	Timeout    string                `json:"Timeout,omitempty" jsonschema_description:"The max duration of one attempt of the tool e.g. '30s', default 2m"`
 }
 
 func GenerateSchema[T any]() (interface{}, error) {
@@ -39,14 +44,14 @@ func GenerateSchema[T any]() (interface{}, error) {
 var ExecutionPlanSchema, _ = GenerateSchema[ExecutionPlan]()
 var FeedbackSchema, _ = GenerateSchema[Feedback]()
 
-var planSchemaParam = openai.ResponseFormatJSONSchemaJSONSchemaParam{
-	Name:        openai.F("ExecutionPlan"),
-	Description: openai.F("The execution plan for the trading strategy to the user query"),
-	Schema:      openai.F(ExecutionPlanSchema),
+var planResponseFormat = llm.ResponseFormat{
+	Name:        "ExecutionPlan",
+	Description: "The execution plan for the trading strategy to the user query",
+	Schema:      ExecutionPlanSchema,
 }
 
-var feedbackSchemaParam = openai.ResponseFormatJSONSchemaJSONSchemaParam{
-	Name:        openai.F("Feedback"),
-	Description: openai.F("The feedback of the execution plan"),
-	Schema:      openai.F(FeedbackSchema),
+var feedbackResponseFormat = llm.ResponseFormat{
+	Name:        "Feedback",
+	Description: "The feedback of the execution plan",
+	Schema:      FeedbackSchema,
 }
@@ -0,0 +1,152 @@
+package ai
+
+import (
+	"fmt"
+	"lfg/pkg/exchange"
+	"lfg/pkg/market"
+	"lfg/pkg/types"
+	"math"
+)
+
+const (
+	SIZING_USD         = "usd"        // position value in USD
+	SIZING_BALANCE_PCT = "balancePct" // position value as a percentage of the account balance
+	SIZING_RISK        = "risk"       // percentage of the account balance lost if the stop is hit
+)
+
+// PositionSizing is embedded by the tasks opening positions
+type PositionSizing struct {
+	Sizing        string  `json:"sizing,omitempty" jsonschema_description:"how the position size is computed: 'usd' (default) from amountUsdKey | 'balancePct' from balancePctKey | 'risk' from riskPctKey and the stop distance of stopPriceKey or atrKey"`
+	AmountUsdKey  string  `json:"amountUsdKey,omitempty" jsonschema_description:"the key of the amount usd position size in the memory" memory:"number"`
+	BalancePctKey string  `json:"balancePctKey,omitempty" jsonschema_description:"the key of the position value as a percentage of the account balance in the memory (e.g. 10 for 10%)" memory:"number"`
+	RiskPctKey    string  `json:"riskPctKey,omitempty" jsonschema_description:"the key of the percentage of the account balance lost if the stop is hit in the memory (e.g. 1 for 1%)" memory:"number"`
+	StopPriceKey  string  `json:"stopPriceKey,omitempty" jsonschema_description:"the key of the stop price value in the memory, below the entry price for a long and above for a short" memory:"number"`
+	AtrKey        string  `json:"atrKey,omitempty" jsonschema_description:"the key of the ATR value in the memory, the stop distance is atrMultiple ATRs; used if stopPriceKey is not set" memory:"number"`
+	AtrMultiple   float64 `json:"atrMultiple,omitempty" jsonschema_description:"the number of ATRs of the stop distance, default 1"`
+	Leverage      int     `json:"leverage,omitempty" jsonschema_description:"the leverage of the order, default 5; must not exceed the max leverage of the market"`
+}
+
+func (s *PositionSizing) Validate() error {
+	switch s.Sizing {
+	case "", SIZING_USD:
+		if s.AmountUsdKey == "" {
+			return fmt.Errorf("sizing '%v' requires amountUsdKey", SIZING_USD)
+		}
+	case SIZING_BALANCE_PCT:
+		if s.BalancePctKey == "" {
+			return fmt.Errorf("sizing '%v' requires balancePctKey", SIZING_BALANCE_PCT)
+		}
+	case SIZING_RISK:
+		if s.RiskPctKey == "" || (s.StopPriceKey == "" && s.AtrKey == "") {
+			return fmt.Errorf("sizing '%v' requires riskPctKey and either stopPriceKey or atrKey", SIZING_RISK)
+		}
+	default:
+		return fmt.Errorf("unknown sizing '%v', must be one of [%v, %v, %v]", s.Sizing, SIZING_USD, SIZING_BALANCE_PCT, SIZING_RISK)
+	}
+	if s.AtrMultiple < 0 {
+		return fmt.Errorf("atrMultiple must not be negative")
+	}
+	if s.Leverage < 0 {
+		return fmt.Errorf("leverage must not be negative")
+	}
+	return nil
+}
+
+// getOrderSize returns the quantity (rounded down to the lot step size) and the leverage
+// of an order opening a position at `price`
+func (s *PositionSizing) getOrderSize(memory *AgentMemory, exchg exchange.Exchange, symbol string, side types.OrderSide, price float64, orderType types.OrderType) (float64, int, error) {
+	if price <= 0 {
+		return 0, 0, fmt.Errorf("invalid entry price %v", price)
+	}
+	mkt := exchg.GetMarket(symbol)
+	lev, err := getLeverage(mkt, s.Leverage)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	var qty float64
+	switch s.Sizing {
+	case "", SIZING_USD:
+		amountUsd, err := memory.GetAsFloat64(s.AmountUsdKey)
+		if err != nil {
+			return 0, 0, err
+		}
+		qty = amountUsd / price
+	case SIZING_BALANCE_PCT:
+		balancePct, err := memory.GetAsFloat64(s.BalancePctKey)
+		if err != nil {
+			return 0, 0, err
+		}
+		balance, err := exchg.GetAccountBalance()
+		if err != nil {
+			return 0, 0, fmt.Errorf("fail to get account balance: %w", err)
+		}
+		qty = balance * balancePct / 100 / price
+	case SIZING_RISK:
+		riskPct, err := memory.GetAsFloat64(s.RiskPctKey)
+		if err != nil {
+			return 0, 0, err
+		}
+		stopDistance, err := s.getStopDistance(memory, side, price)
+		if err != nil {
+			return 0, 0, err
+		}
+		balance, err := exchg.GetAccountBalance()
+		if err != nil {
+			return 0, 0, fmt.Errorf("fail to get account balance: %w", err)
+		}
+		qty = balance * riskPct / 100 / stopDistance
+	default:
+		return 0, 0, fmt.Errorf("unknown sizing '%v'", s.Sizing)
+	}
+
+	qty = roundQty(mkt, qty, orderType)
+	if qty <= 0 {
+		return 0, 0, fmt.Errorf("position size is below the lot step size of %v", symbol)
+	}
+	return qty, lev, nil
+}
+
+func (s *PositionSizing) getStopDistance(memory *AgentMemory, side types.OrderSide, price float64) (float64, error) {
+	if s.StopPriceKey != "" {
+		stopPrice, err := memory.GetAsFloat64(s.StopPriceKey)
+		if err != nil {
+			return 0, err
+		}
+		if (side == types.OrderSideBuy && stopPrice >= price) || (side == types.OrderSideSell && stopPrice <= price) {
+			return 0, fmt.Errorf("stop price %v is on the wrong side of the entry price %v", stopPrice, price)
+		}
+		return math.Abs(price - stopPrice), nil
+	}
+	atr, err := memory.GetAsFloat64(s.AtrKey)
+	if err != nil {
+		return 0, err
+	}
+	atrMultiple := s.AtrMultiple
+	if atrMultiple == 0 {
+		atrMultiple = 1
+	}
+	if atr*atrMultiple <= 0 {
+		return 0, fmt.Errorf("invalid stop distance of %v ATR (ATR: %v)", atrMultiple, atr)
+	}
+	return atr * atrMultiple, nil
+}
+
+// getLeverage defaults the leverage and checks it against the market max leverage, if known
+func getLeverage(mkt *market.Market, lev int) (int, error) {
+	if lev == 0 {
+		lev = DEFAULT_LEVERAGE
+	}
+	if err := mkt.CheckLeverage(lev); err != nil {
+		return 0, err
+	}
+	return lev, nil
+}
+
+// roundQty rounds the quantity down to the lot step size of the order type, if known
+func roundQty(mkt *market.Market, qty float64, orderType types.OrderType) float64 {
+	if mkt == nil {
+		return qty
+	}
+	return mkt.RoundQty(qty, orderType)
+}
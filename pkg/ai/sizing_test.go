@@ -0,0 +1,117 @@
+package ai
+
+import (
+	"lfg/pkg/exchange"
+	"lfg/pkg/market"
+	"lfg/pkg/types"
+	"math"
+	"testing"
+)
+
+// sizingExchange answers the market & balance lookups of the position sizing
+type sizingExchange struct {
+	exchange.Exchange
+	market  *market.Market
+	balance float64
+}
+
+func (e *sizingExchange) GetMarket(symbol string) *market.Market { return e.market }
+func (e *sizingExchange) GetAccountBalance() (float64, error)    { return e.balance, nil }
+
+func TestPositionSizingValidate(t *testing.T) {
+	valid := []PositionSizing{
+		{AmountUsdKey: "amount"},
+		{Sizing: SIZING_BALANCE_PCT, BalancePctKey: "pct"},
+		{Sizing: SIZING_RISK, RiskPctKey: "risk", StopPriceKey: "stop"},
+		{Sizing: SIZING_RISK, RiskPctKey: "risk", AtrKey: "atr", AtrMultiple: 2, Leverage: 10},
+	}
+	for _, sizing := range valid {
+		if err := sizing.Validate(); err != nil {
+			t.Errorf("%+v: unexpected error: %v", sizing, err)
+		}
+	}
+
+	invalid := []PositionSizing{
+		{},
+		{Sizing: SIZING_BALANCE_PCT, AmountUsdKey: "amount"},
+		{Sizing: SIZING_RISK, RiskPctKey: "risk"},
+		{Sizing: SIZING_RISK, StopPriceKey: "stop"},
+		{Sizing: "kelly", AmountUsdKey: "amount"},
+		{AmountUsdKey: "amount", AtrMultiple: -1},
+		{AmountUsdKey: "amount", Leverage: -1},
+	}
+	for _, sizing := range invalid {
+		if err := sizing.Validate(); err == nil {
+			t.Errorf("%+v: want error", sizing)
+		}
+	}
+}
+
+func TestGetOrderSize(t *testing.T) {
+	exchg := &sizingExchange{
+		market:  &market.Market{Symbol: "BTCUSDT", LotStepSize: 0.001, MarketLotStepSize: 0.01, MaxLeverage: 20},
+		balance: 2000,
+	}
+	memory := NewAgentMemory(nil, nil)
+	memory.SetAsFloat64("amount", 1000)
+	memory.SetAsFloat64("pct", 10)
+	memory.SetAsFloat64("risk", 1)
+	memory.SetAsFloat64("stop", 95)
+	memory.SetAsFloat64("atr", 2.5)
+
+	tests := []struct {
+		name      string
+		sizing    PositionSizing
+		side      types.OrderSide
+		price     float64
+		orderType types.OrderType
+		wantQty   float64
+		wantLev   int
+	}{
+		{"usd", PositionSizing{AmountUsdKey: "amount"}, types.OrderSideBuy, 100, types.OrderLimit, 10, DEFAULT_LEVERAGE},
+		{"balance pct", PositionSizing{Sizing: SIZING_BALANCE_PCT, BalancePctKey: "pct", Leverage: 3}, types.OrderSideBuy, 100, types.OrderLimit, 2, 3},
+		{"risk to the stop price", PositionSizing{Sizing: SIZING_RISK, RiskPctKey: "risk", StopPriceKey: "stop"}, types.OrderSideBuy, 100, types.OrderLimit, 4, DEFAULT_LEVERAGE},
+		{"risk to 2 ATR", PositionSizing{Sizing: SIZING_RISK, RiskPctKey: "risk", AtrKey: "atr", AtrMultiple: 2}, types.OrderSideSell, 100, types.OrderLimit, 4, DEFAULT_LEVERAGE},
+		{"rounded down to the lot step", PositionSizing{Sizing: SIZING_BALANCE_PCT, BalancePctKey: "pct"}, types.OrderSideBuy, 300, types.OrderLimit, 0.666, DEFAULT_LEVERAGE},
+		{"rounded down to the market lot step", PositionSizing{Sizing: SIZING_BALANCE_PCT, BalancePctKey: "pct"}, types.OrderSideBuy, 300, types.OrderMarket, 0.66, DEFAULT_LEVERAGE},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			qty, lev, err := tt.sizing.getOrderSize(memory, exchg, "BTC_USD", tt.side, tt.price, tt.orderType)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if math.Abs(qty-tt.wantQty) > 1e-9 || lev != tt.wantLev {
+				t.Fatalf("getOrderSize = (%v, %v), want (%v, %v)", qty, lev, tt.wantQty, tt.wantLev)
+			}
+		})
+	}
+}
+
+func TestGetOrderSizeErrors(t *testing.T) {
+	exchg := &sizingExchange{
+		market:  &market.Market{Symbol: "BTCUSDT", LotStepSize: 0.001, MaxLeverage: 20},
+		balance: 2000,
+	}
+	memory := NewAgentMemory(nil, nil)
+	memory.SetAsFloat64("amount", 1000)
+	memory.SetAsFloat64("dust", 0.01)
+	memory.SetAsFloat64("risk", 1)
+	memory.SetAsFloat64("stop", 105)
+
+	if _, _, err := (&PositionSizing{AmountUsdKey: "amount"}).getOrderSize(memory, exchg, "BTC_USD", types.OrderSideBuy, 0, types.OrderLimit); err == nil {
+		t.Errorf("no entry price: want error")
+	}
+	if _, _, err := (&PositionSizing{AmountUsdKey: "missing"}).getOrderSize(memory, exchg, "BTC_USD", types.OrderSideBuy, 100, types.OrderLimit); err == nil {
+		t.Errorf("amount not in memory: want error")
+	}
+	if _, _, err := (&PositionSizing{AmountUsdKey: "dust"}).getOrderSize(memory, exchg, "BTC_USD", types.OrderSideBuy, 100, types.OrderLimit); err == nil {
+		t.Errorf("size below the lot step: want error")
+	}
+	if _, _, err := (&PositionSizing{Sizing: SIZING_RISK, RiskPctKey: "risk", StopPriceKey: "stop"}).getOrderSize(memory, exchg, "BTC_USD", types.OrderSideBuy, 100, types.OrderLimit); err == nil {
+		t.Errorf("long with the stop above the entry: want error")
+	}
+	if _, _, err := (&PositionSizing{AmountUsdKey: "amount", Leverage: 25}).getOrderSize(memory, exchg, "BTC_USD", types.OrderSideBuy, 100, types.OrderLimit); err == nil {
+		t.Errorf("leverage above the market max: want error")
+	}
+}
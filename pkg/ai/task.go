@@ -2,17 +2,22 @@ package ai
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
+	"lfg/pkg/exchange"
 	"lfg/pkg/indicator"
+	"lfg/pkg/order"
 	"lfg/pkg/types"
+	"math"
 	"strings"
 )
 
+const DEFAULT_LEVERAGE = 5 // leverage of agent orders if the task does not set one
+
 type BaseTask struct {
-	Name        string
-	Description string
-	Parameters  map[string]string
+	Name         string
+	Description  string
+	Parameters   []TaskParameter
+	PlacesOrders bool // a failed attempt may still have placed its order, so the task is never retried
 }
 
 type Executable interface {
@@ -21,7 +26,8 @@ type Executable interface {
 
 type AgentTask struct {
 	BaseTask
-	Executable Executable
+	Executable Executable // prototype; GetTaskByName returns a fresh instance
+	Policy     TaskPolicy // set from the plan; zero in the registry
 }
 
 func GetAllTaskInterfaces() []BaseTask {
@@ -34,30 +40,24 @@ func GetAllTaskInterfaces() []BaseTask {
 }
 
 func GetTaskByName(name string, params map[string]string) (*AgentTask, error) {
-	for _, task := range GetAllTasks() {
-		if task.Name == name {
-			res := task
-			// Convert params map to JSON
-			paramsJSON, err := json.Marshal(params)
-			if err != nil {
-				return nil, fmt.Errorf("failed to marshal params: %w", err)
-			}
-			if err := json.Unmarshal(paramsJSON, &res.Executable); err != nil {
-				return nil, fmt.Errorf("failed to unmarshal params: %w", err)
-			}
-			return &res, nil
-		}
+	task, exists := taskRegistry[name]
+	if !exists {
+		return nil, fmt.Errorf("unknown task: %v", name)
+	}
+	executable, err := task.newExecutable(params)
+	if err != nil {
+		return nil, err
 	}
-	return nil, nil
+	return &AgentTask{BaseTask: task.BaseTask, Executable: executable}, nil
 }
 
 // MARK: GetKlineTask
 type GetKlineTask struct {
-	ExchangeIdKey string `json:"exchangeIdKey"`
-	IntervalKey   string `json:"intervalKey"`
-	SymbolKey     string `json:"symbolKey"`
-	WindowKey     string `json:"windowKey"`
-	OutputKey     string `json:"outputKey"`
+	ExchangeIdKey string `json:"exchangeIdKey" jsonschema_description:"the key of the exchange id value in the memory that is set by the agent" memory:"string"`
+	IntervalKey   string `json:"intervalKey" jsonschema_description:"the key of the interval value in the memory ex. 'interval1' : '15m'" memory:"string"`
+	SymbolKey     string `json:"symbolKey" jsonschema_description:"the key of the symbol value in the memory (format 'TICKER_USD' not 'TICKER_USDT')" memory:"string"`
+	WindowKey     string `json:"windowKey" jsonschema_description:"the key of the window value in the memory ex. 'window1' : '20'" memory:"number"`
+	OutputKey     string `json:"outputKey" jsonschema_description:"the key of the output value in the memory ex. 'kline1'" memory:"klines"`
 }
 
 func (t *GetKlineTask) Execute(ctx context.Context, memory *AgentMemory) error {
@@ -90,10 +90,22 @@ func (t *GetKlineTask) Execute(ctx context.Context, memory *AgentMemory) error {
 	return nil
 }
 
+// check the indicator window against the number of klines it needs
+func validateWindow(window int, klines []types.KLineEvent, requiredKlines int) error {
+	if window <= 0 {
+		return fmt.Errorf("window must be positive, got %v", window)
+	}
+	if len(klines) < requiredKlines {
+		return fmt.Errorf("insufficient klines for window %v: have %v/%v", window, len(klines), requiredKlines)
+	}
+	return nil
+}
+
 // MARK: GetMovingAverageTask
 type GetMovingAverageTask struct {
-	KlineKey  string `json:"klineKey"`
-	OutputKey string `json:"outputKey"`
+	KlineKey  string `json:"klineKey" jsonschema_description:"the key of the kline value in the memory" memory:"klines"`
+	Window    int    `json:"window" jsonschema_description:"the moving average period ex. 9; the kline must have at least window candles"`
+	OutputKey string `json:"outputKey" jsonschema_description:"the key of the output value in the memory" memory:"series"`
 }
 
 func (t *GetMovingAverageTask) Execute(ctx context.Context, memory *AgentMemory) error {
@@ -102,17 +114,22 @@ func (t *GetMovingAverageTask) Execute(ctx context.Context, memory *AgentMemory)
 	if err != nil {
 		return err
 	}
+	if err := validateWindow(t.Window, klines, t.Window); err != nil {
+		return err
+	}
 
-	maValues := indicator.CalculateMovingAverage(klines, int(len(klines)/2))
+	maValues := indicator.CalculateMovingAverage(klines, t.Window)
 	// write result to memory
-	memory.SetAsStr(t.OutputKey, maValues)
+	memory.SetAsSeries(t.OutputKey, maValues)
 	return nil
 }
 
 // MARK: GetBollingerBandTask
 type GetBollingerBandTask struct {
-	KlineKey  string `json:"klineKey"`
-	OutputKey string `json:"outputKey"`
+	KlineKey  string  `json:"klineKey" jsonschema_description:"the key of the kline value in the memory" memory:"klines"`
+	Window    int     `json:"window" jsonschema_description:"the bollinger band period ex. 20; the kline must have at least window candles"`
+	Deviation float64 `json:"deviation" jsonschema_description:"the number of standard deviations of the upper and lower bands ex. 2"`
+	OutputKey string  `json:"outputKey" jsonschema_description:"the key of the output value in the memory with UpperBand, MiddleBand and LowerBand" memory:"object"`
 }
 
 func (t *GetBollingerBandTask) Execute(ctx context.Context, memory *AgentMemory) error {
@@ -121,20 +138,122 @@ func (t *GetBollingerBandTask) Execute(ctx context.Context, memory *AgentMemory)
 	if err != nil {
 		return err
 	}
+	if err := validateWindow(t.Window, klines, t.Window); err != nil {
+		return err
+	}
+	if t.Deviation <= 0 {
+		return fmt.Errorf("deviation must be positive, got %v", t.Deviation)
+	}
+
+	bollingerBand, err := indicator.CalculateBollingerBand(klines, t.Window, t.Deviation)
+	if err != nil {
+		return err
+	}
+	return memory.SetAsObject(t.OutputKey, bollingerBand)
+}
+
+// MARK: GetATRTask
+type GetATRTask struct {
+	KlineKey  string `json:"klineKey" jsonschema_description:"the key of the kline value in the memory" memory:"klines"`
+	Window    int    `json:"window" jsonschema_description:"the ATR period ex. 14; the kline must have at least 2*window+1 candles"`
+	OutputKey string `json:"outputKey" jsonschema_description:"the key of the output value in the memory" memory:"number"`
+}
+
+func (t *GetATRTask) Execute(ctx context.Context, memory *AgentMemory) error {
+	klines, err := memory.GetAsKlines(t.KlineKey)
+	if err != nil {
+		return err
+	}
+	if err := validateWindow(t.Window, klines, 2*t.Window+1); err != nil {
+		return err
+	}
+
+	atr, err := indicator.CalculateATR(klines, t.Window)
+	if err != nil {
+		return err
+	}
+	memory.SetAsFloat64(t.OutputKey, atr)
+	return nil
+}
+
+// MARK: GetATRIndexTask
+type GetATRIndexTask struct {
+	KlineKey  string `json:"klineKey" jsonschema_description:"the key of the kline value in the memory" memory:"klines"`
+	Window    int    `json:"window" jsonschema_description:"the ATR period ex. 14; the kline must have at least 2*window+1 candles"`
+	OutputKey string `json:"outputKey" jsonschema_description:"the key of the output value in the memory" memory:"number"`
+}
+
+func (t *GetATRIndexTask) Execute(ctx context.Context, memory *AgentMemory) error {
+	klines, err := memory.GetAsKlines(t.KlineKey)
+	if err != nil {
+		return err
+	}
+	if err := validateWindow(t.Window, klines, 2*t.Window+1); err != nil {
+		return err
+	}
 
-	bollingerBand, err := indicator.CalculateBollingerBand(klines, int(len(klines)/2), 2)
+	atrIndex, err := indicator.CalculateATRIndex(klines, t.Window)
 	if err != nil {
 		return err
 	}
-	memory.SetAsStr(t.OutputKey, bollingerBand)
+	memory.SetAsFloat64(t.OutputKey, atrIndex)
+	return nil
+}
+
+// MARK: GetRVITask
+type GetRVITask struct {
+	KlineKey  string `json:"klineKey" jsonschema_description:"the key of the kline value in the memory" memory:"klines"`
+	Window    int    `json:"window" jsonschema_description:"the RVI period ex. 14; the kline must have at least window+1 candles"`
+	OutputKey string `json:"outputKey" jsonschema_description:"the key of the output value in the memory" memory:"number"`
+}
+
+func (t *GetRVITask) Execute(ctx context.Context, memory *AgentMemory) error {
+	klines, err := memory.GetAsKlines(t.KlineKey)
+	if err != nil {
+		return err
+	}
+	if err := validateWindow(t.Window, klines, t.Window+1); err != nil {
+		return err
+	}
+
+	rvi, err := indicator.CalculateRVI(klines, t.Window)
+	if err != nil {
+		return err
+	}
+	memory.SetAsFloat64(t.OutputKey, rvi)
+	return nil
+}
+
+// MARK: GetVolatilityTask
+type GetVolatilityTask struct {
+	KlineKey  string `json:"klineKey" jsonschema_description:"the key of the kline value in the memory" memory:"klines"`
+	Window    int    `json:"window" jsonschema_description:"the volatility period ex. 20; the kline must have at least window+1 candles"`
+	OutputKey string `json:"outputKey" jsonschema_description:"the key of the output value in the memory" memory:"number"`
+}
+
+func (t *GetVolatilityTask) Execute(ctx context.Context, memory *AgentMemory) error {
+	klines, err := memory.GetAsKlines(t.KlineKey)
+	if err != nil {
+		return err
+	}
+	if err := validateWindow(t.Window, klines, t.Window+1); err != nil {
+		return err
+	}
+
+	volatility, err := indicator.CalculateVolatility(klines, t.Window)
+	if err != nil {
+		return err
+	}
+	memory.SetAsFloat64(t.OutputKey, volatility)
 	return nil
 }
 
 // MARK: AskAITask
 type AskAITask struct {
-	Prompt    string `json:"prompt"`
-	DataKeys  string `json:"dataKeys"`
-	OutputKey string `json:"outputKey"`
+	Prompt    string `json:"prompt" jsonschema_description:"the prompt to be asked to the AI in the memory. be specific and clear. u MUST CLEARLY outline the output format ex. ONLY OUTPUT 'yes' | 'no' | 'idk'"`
+	DataKeys  string `json:"dataKeys" jsonschema_description:"the keys of the data values in the memory separated by comma ex. 'data1,data2,data3'"`
+	OutputKey string `json:"outputKey" jsonschema_description:"the key of the output value in the memory; a string for 'text' & 'enum', otherwise of the outputType" memory:"string"`
+	AnswerType
 }
 
 func (t *AskAITask) Execute(ctx context.Context, memory *AgentMemory) error {
@@ -150,24 +269,21 @@ func (t *AskAITask) Execute(ctx context.Context, memory *AgentMemory) error {
 	}
 	prompt += "\n\nUSER INSTRUCTION: " + t.Prompt
 
-	prompt += "\nIMPORTANT: YOUR OUTPUT WILL BE USED TO SET AS A STR IN THE MEMORY AND USED FURTHER. FOLLOW FORMAT IN THE INSTRUCTION STRICTLY"
+	prompt += "\nIMPORTANT: YOUR OUTPUT WILL BE USED TO SET IN THE MEMORY AND USED FURTHER. FOLLOW FORMAT IN THE INSTRUCTION STRICTLY"
 
-	aiResponse, err := GetCompletion(ctx, OpenAIClient, prompt)
+	answer, err := t.ask(ctx, memory.LLM, prompt)
 	if err != nil {
 		return err
 	}
-
-	fmt.Println("aiResponse: ", aiResponse)
-
-	memory.SetAsStr(t.OutputKey, aiResponse)
+	memory.Set(t.OutputKey, answer)
 	return nil
 }
 
 // MARK: AISetMemoryTask
 type AISetMemoryTask struct {
-	Prompt   string `json:"prompt"`
-	DataKeys string `json:"dataKeys"`
-	Output   string `json:"output"`
+	Prompt   string `json:"prompt" jsonschema_description:"the prompt to be asked to the AI in the memory. be specific and clear. u MUST CLEARLY outline the output format ex. {'name': '...', 'desc': '...'}"`
+	DataKeys string `json:"dataKeys" jsonschema_description:"the keys of the data values in the memory separated by comma ex. 'data1,data2,data3'"`
+	Output   string `json:"output" jsonschema_description:"output value to be set in the memory as map[string]string"`
 }
 
 func (t *AISetMemoryTask) Execute(ctx context.Context, memory *AgentMemory) error {
@@ -184,12 +300,10 @@ func (t *AISetMemoryTask) Execute(ctx context.Context, memory *AgentMemory) erro
 	prompt += "\n\nUSER INSTRUCTION: " + t.Prompt
 	prompt += "\nIMPORTANT: YOUR OUTPUT WILL BE USED TO SET AS A JSON IN THE MEMORY AND USED FURTHER. FOLLOW FORMAT IN THE INSTRUCTION STRICTLY"
 
-	aiResponse, err := GetStructuredCompletion(ctx, OpenAIClient, prompt)
+	aiResponse, err := GetStructuredCompletion(ctx, memory.LLM, prompt)
 	if err != nil {
 		return err
 	}
-	fmt.Println("aiResponse: ", aiResponse)
-
 	for key, value := range aiResponse {
 		memory.SetAsStr(key, value)
 	}
@@ -198,11 +312,11 @@ func (t *AISetMemoryTask) Execute(ctx context.Context, memory *AgentMemory) erro
 
 // MARK: openMarketLongPositionTask
 type OpenMarketLongPositionIfTask struct {
-	IfKey         string `json:"ifKey"`
-	IfValue       string `json:"ifValue"`
-	AmountUsdKey  string `json:"amountUsdKey"`
-	SymbolKey     string `json:"symbolKey"`
-	ExchangeIdKey string `json:"exchangeIdKey"`
+	IfKey         string `json:"ifKey" jsonschema_description:"the key of the if value in the memory" memory:"string"`
+	IfValue       string `json:"ifValue" jsonschema_description:"the value of the if value in the memory"`
+	SymbolKey     string `json:"symbolKey" jsonschema_description:"the key of the symbol value in the memory (format 'TICKER_USD' not 'TICKER_USDT')" memory:"string"`
+	ExchangeIdKey string `json:"exchangeIdKey" jsonschema_description:"the key of the exchange id value in the memory that is set by the agent" memory:"string"`
+	PositionSizing
 }
 
 func (t *OpenMarketLongPositionIfTask) Execute(ctx context.Context, memory *AgentMemory) error {
@@ -214,10 +328,6 @@ func (t *OpenMarketLongPositionIfTask) Execute(ctx context.Context, memory *Agen
 		return nil
 	}
 
-	amountUsd, err := memory.GetAsFloat64(t.AmountUsdKey)
-	if err != nil {
-		return err
-	}
 	symbol, err := memory.GetAsStr(t.SymbolKey)
 	if err != nil {
 		return err
@@ -226,18 +336,19 @@ func (t *OpenMarketLongPositionIfTask) Execute(ctx context.Context, memory *Agen
 	if err != nil {
 		return err
 	}
+	exchg := *memory.Exchanges[exchangeId]
 
-	klines, err := (*memory.Exchanges[exchangeId]).GetKLines(symbol, types.Interval1m, 1)
+	klines, err := exchg.GetKLines(symbol, types.Interval1m, 1)
 	if err != nil {
 		return err
 	}
 	price := klines[len(klines)-1].Kline.C
 
-	amount := amountUsd / price
-
-	// TODO: make leverage dynamic
-	lev := 5
-	err = (*memory.Exchanges[exchangeId]).OpenMarketOrder(symbol, types.OrderSideBuy, amount, lev, false)
+	qty, lev, err := t.getOrderSize(memory, exchg, symbol, types.OrderSideBuy, price, types.OrderMarket)
+	if err != nil {
+		return err
+	}
+	err = exchg.OpenMarketOrder(symbol, types.OrderSideBuy, qty, lev, false)
 	if err != nil {
 		return err
 	}
@@ -247,11 +358,11 @@ func (t *OpenMarketLongPositionIfTask) Execute(ctx context.Context, memory *Agen
 
 // MARK: openMarketShortPositionTask
 type OpenMarketShortPositionIfTask struct {
-	IfKey         string `json:"ifKey"`
-	IfValue       string `json:"ifValue"`
-	AmountUsdKey  string `json:"amountUsdKey"`
-	SymbolKey     string `json:"symbolKey"`
-	ExchangeIdKey string `json:"exchangeIdKey"`
+	IfKey         string `json:"ifKey" jsonschema_description:"the key of the if value in the memory" memory:"string"`
+	IfValue       string `json:"ifValue" jsonschema_description:"the value of the if value in the memory"`
+	SymbolKey     string `json:"symbolKey" jsonschema_description:"the key of the symbol value in the memory (format 'TICKER_USD' not 'TICKER_USDT')" memory:"string"`
+	ExchangeIdKey string `json:"exchangeIdKey" jsonschema_description:"the key of the exchange id value in the memory that is set by the agent" memory:"string"`
+	PositionSizing
 }
 
 func (t *OpenMarketShortPositionIfTask) Execute(ctx context.Context, memory *AgentMemory) error {
@@ -263,10 +374,6 @@ func (t *OpenMarketShortPositionIfTask) Execute(ctx context.Context, memory *Age
 		return nil
 	}
 
-	amountUsd, err := memory.GetAsFloat64(t.AmountUsdKey)
-	if err != nil {
-		return err
-	}
 	symbol, err := memory.GetAsStr(t.SymbolKey)
 	if err != nil {
 		return err
@@ -275,18 +382,19 @@ func (t *OpenMarketShortPositionIfTask) Execute(ctx context.Context, memory *Age
 	if err != nil {
 		return err
 	}
+	exchg := *memory.Exchanges[exchangeId]
 
-	klines, err := (*memory.Exchanges[exchangeId]).GetKLines(symbol, types.Interval1m, 1)
+	klines, err := exchg.GetKLines(symbol, types.Interval1m, 1)
 	if err != nil {
 		return err
 	}
 	price := klines[len(klines)-1].Kline.C
 
-	amount := amountUsd / price
-
-	// TODO: make leverage dynamic
-	lev := 5
-	err = (*memory.Exchanges[exchangeId]).OpenMarketOrder(symbol, types.OrderSideSell, amount, lev, false)
+	qty, lev, err := t.getOrderSize(memory, exchg, symbol, types.OrderSideSell, price, types.OrderMarket)
+	if err != nil {
+		return err
+	}
+	err = exchg.OpenMarketOrder(symbol, types.OrderSideSell, qty, lev, false)
 	if err != nil {
 		return err
 	}
@@ -296,12 +404,13 @@ func (t *OpenMarketShortPositionIfTask) Execute(ctx context.Context, memory *Age
 
 // MARK: openLimitLongPositionTask
 type OpenLimitLongPositionIfTask struct {
-	IfKey         string `json:"ifKey"`
-	IfValue       string `json:"ifValue"`
-	PriceKey      string `json:"priceKey"`
-	AmountUsdKey  string `json:"amountUsdKey"`
-	SymbolKey     string `json:"symbolKey"`
-	ExchangeIdKey string `json:"exchangeIdKey"`
+	IfKey            string `json:"ifKey" jsonschema_description:"the key of the if value in the memory" memory:"string"`
+	IfValue          string `json:"ifValue" jsonschema_description:"the value of the if value in the memory"`
+	PriceKey         string `json:"priceKey" jsonschema_description:"the key of the price value in the memory" memory:"number"`
+	SymbolKey        string `json:"symbolKey" jsonschema_description:"the key of the symbol value in the memory (format 'TICKER_USD' not 'TICKER_USDT')" memory:"string"`
+	ExchangeIdKey    string `json:"exchangeIdKey" jsonschema_description:"the key of the exchange id value in the memory that is set by the agent" memory:"string"`
+	OrderIdOutputKey string `json:"orderIdOutputKey,omitempty" jsonschema_description:"the key of the placed order id output in the memory, to cancel it on a later run" memory:"string"`
+	PositionSizing
 }
 
 func (t *OpenLimitLongPositionIfTask) Execute(ctx context.Context, memory *AgentMemory) error {
@@ -313,10 +422,56 @@ func (t *OpenLimitLongPositionIfTask) Execute(ctx context.Context, memory *Agent
 		return nil
 	}
 
-	amountUsd, err := memory.GetAsFloat64(t.AmountUsdKey)
+	symbol, err := memory.GetAsStr(t.SymbolKey)
 	if err != nil {
 		return err
 	}
+	exchangeId, err := memory.GetAsStr(t.ExchangeIdKey)
+	if err != nil {
+		return err
+	}
+	exchg := *memory.Exchanges[exchangeId]
+
+	price, err := memory.GetAsFloat64(t.PriceKey)
+	if err != nil {
+		return err
+	}
+
+	qty, lev, err := t.getOrderSize(memory, exchg, symbol, types.OrderSideBuy, price, types.OrderLimit)
+	if err != nil {
+		return err
+	}
+	oId, err := exchg.OpenLimitOrder(symbol, types.OrderSideBuy, price, qty, lev, false, types.OrderTIFGTC, "")
+	if err != nil {
+		return err
+	}
+	if t.OrderIdOutputKey != "" {
+		memory.SetAsStr(t.OrderIdOutputKey, oId)
+	}
+
+	return nil
+}
+
+// MARK: openShortPositionTask
+type OpenLimitShortPositionIfTask struct {
+	IfKey            string `json:"ifKey" jsonschema_description:"the key of the if value in the memory" memory:"string"`
+	IfValue          string `json:"ifValue" jsonschema_description:"the value of the if value in the memory"`
+	PriceKey         string `json:"priceKey" jsonschema_description:"the key of the price value in the memory" memory:"number"`
+	SymbolKey        string `json:"symbolKey" jsonschema_description:"the key of the symbol value in the memory (format 'TICKER_USD' not 'TICKER_USDT')" memory:"string"`
+	ExchangeIdKey    string `json:"exchangeIdKey" jsonschema_description:"the key of the exchange id value in the memory that is set by the agent" memory:"string"`
+	OrderIdOutputKey string `json:"orderIdOutputKey,omitempty" jsonschema_description:"the key of the placed order id output in the memory, to cancel it on a later run" memory:"string"`
+	PositionSizing
+}
+
+func (t *OpenLimitShortPositionIfTask) Execute(ctx context.Context, memory *AgentMemory) error {
+	ifValue, err := memory.GetAsStr(t.IfKey)
+	if err != nil {
+		return err
+	}
+	if ifValue != t.IfValue {
+		return nil
+	}
+
 	symbol, err := memory.GetAsStr(t.SymbolKey)
 	if err != nil {
 		return err
@@ -325,35 +480,224 @@ func (t *OpenLimitLongPositionIfTask) Execute(ctx context.Context, memory *Agent
 	if err != nil {
 		return err
 	}
+	exchg := *memory.Exchanges[exchangeId]
 
 	price, err := memory.GetAsFloat64(t.PriceKey)
 	if err != nil {
 		return err
 	}
 
-	amount := amountUsd / price
+	qty, lev, err := t.getOrderSize(memory, exchg, symbol, types.OrderSideSell, price, types.OrderLimit)
+	if err != nil {
+		return err
+	}
+	oId, err := exchg.OpenLimitOrder(symbol, types.OrderSideSell, price, qty, lev, false, types.OrderTIFGTC, "")
+	if err != nil {
+		return err
+	}
+	if t.OrderIdOutputKey != "" {
+		memory.SetAsStr(t.OrderIdOutputKey, oId)
+	}
+
+	return nil
+}
+
+// MARK: signals
+const (
+	SIGNAL_CROSS_UP   = "cross_up"
+	SIGNAL_CROSS_DOWN = "cross_down"
+	SIGNAL_NONE       = "none"
+)
+
+// memory key remembering on which side a signal was on the previous run
+func getSignalStateKey(outputKey string) string {
+	return outputKey + "_prevState"
+}
+
+// detectCross compares the latest value against the reference and reports a cross only on
+// the run the side changes; the first run has no previous side and only records it.
+// Touching the reference keeps the previous side.
+func detectCross(memory *AgentMemory, stateKey string, value float64, reference float64) string {
+	prevState, _ := memory.GetAsStr(stateKey)
+	state := prevState
+	if value > reference {
+		state = "above"
+	} else if value < reference {
+		state = "below"
+	}
+	if state != prevState {
+		memory.SetAsStr(stateKey, state)
+	}
+
+	switch {
+	case prevState == "below" && state == "above":
+		return SIGNAL_CROSS_UP
+	case prevState == "above" && state == "below":
+		return SIGNAL_CROSS_DOWN
+	default:
+		return SIGNAL_NONE
+	}
+}
+
+// MARK: DetectCrossoverTask
+type DetectCrossoverTask struct {
+	FastKey   string `json:"fastKey" jsonschema_description:"the key of the fast series in the memory ex. the 9 period moving average" memory:"series"`
+	SlowKey   string `json:"slowKey" jsonschema_description:"the key of the slow series in the memory ex. the 26 period moving average" memory:"series"`
+	OutputKey string `json:"outputKey" jsonschema_description:"the key of the output value in the memory: 'cross_up' | 'cross_down' | 'none'" memory:"string"`
+}
+
+func (t *DetectCrossoverTask) Execute(ctx context.Context, memory *AgentMemory) error {
+	fast, err := memory.GetAsFloat64(t.FastKey)
+	if err != nil {
+		return err
+	}
+	slow, err := memory.GetAsFloat64(t.SlowKey)
+	if err != nil {
+		return err
+	}
+
+	memory.SetAsStr(t.OutputKey, detectCross(memory, getSignalStateKey(t.OutputKey), fast, slow))
+	return nil
+}
+
+// MARK: DetectLevelCrossTask
+type DetectLevelCrossTask struct {
+	ValueKey  string `json:"valueKey" jsonschema_description:"the key of the value or series in the memory, its latest value is compared" memory:"series"`
+	LevelKey  string `json:"levelKey" jsonschema_description:"the key of the level value in the memory ex. 'level1' : '70'" memory:"number"`
+	OutputKey string `json:"outputKey" jsonschema_description:"the key of the output value in the memory: 'cross_up' | 'cross_down' | 'none'" memory:"string"`
+}
 
-	// TODO: make leverage dynamic
-	lev := 5
-	_, err = (*memory.Exchanges[exchangeId]).OpenLimitOrder(symbol, types.OrderSideBuy, price, amount, lev, false, types.OrderTIFGTC, "")
+func (t *DetectLevelCrossTask) Execute(ctx context.Context, memory *AgentMemory) error {
+	value, err := memory.GetAsFloat64(t.ValueKey)
+	if err != nil {
+		return err
+	}
+	level, err := memory.GetAsFloat64(t.LevelKey)
 	if err != nil {
 		return err
 	}
 
+	memory.SetAsStr(t.OutputKey, detectCross(memory, getSignalStateKey(t.OutputKey), value, level))
 	return nil
 }
 
-// MARK: openShortPositionTask
-type OpenLimitShortPositionIfTask struct {
-	IfKey         string `json:"ifKey"`
-	IfValue       string `json:"ifValue"`
-	PriceKey      string `json:"priceKey"`
-	AmountUsdKey  string `json:"amountUsdKey"`
-	SymbolKey     string `json:"symbolKey"`
-	ExchangeIdKey string `json:"exchangeIdKey"`
+// MARK: GetPositionTask
+type GetPositionTask struct {
+	ExchangeIdKey       string `json:"exchangeIdKey" jsonschema_description:"the key of the exchange id value in the memory that is set by the agent" memory:"string"`
+	SymbolKey           string `json:"symbolKey" jsonschema_description:"the key of the symbol value in the memory (format 'TICKER_USD' not 'TICKER_USDT')" memory:"string"`
+	SideOutputKey       string `json:"sideOutputKey" jsonschema_description:"the key of the position side output in the memory: 'long' | 'short' | 'flat'" memory:"string"`
+	QtyOutputKey        string `json:"qtyOutputKey" jsonschema_description:"the key of the position size output in the memory, in base asset, 0 if flat" memory:"number"`
+	EntryPriceOutputKey string `json:"entryPriceOutputKey" jsonschema_description:"the key of the average entry price output in the memory, 0 if flat" memory:"number"`
 }
 
-func (t *OpenLimitShortPositionIfTask) Execute(ctx context.Context, memory *AgentMemory) error {
+func (t *GetPositionTask) Execute(ctx context.Context, memory *AgentMemory) error {
+	symbol, err := memory.GetAsStr(t.SymbolKey)
+	if err != nil {
+		return err
+	}
+	exchangeId, err := memory.GetAsStr(t.ExchangeIdKey)
+	if err != nil {
+		return err
+	}
+
+	netQty, entryPrice, err := getNetPosition(*memory.Exchanges[exchangeId], symbol)
+	if err != nil {
+		return err
+	}
+	side := "flat"
+	if netQty > 0 {
+		side = "long"
+	} else if netQty < 0 {
+		side = "short"
+	}
+
+	memory.SetAsStr(t.SideOutputKey, side)
+	memory.SetAsFloat64(t.QtyOutputKey, math.Abs(netQty))
+	memory.SetAsFloat64(t.EntryPriceOutputKey, entryPrice)
+	return nil
+}
+
+// getNetPosition nets the positions of the symbol (one-way mode has at most one);
+// qty is negative for a short and the entry price is weighted by size
+func getNetPosition(exchg exchange.Exchange, symbol string) (qty float64, entryPrice float64, err error) {
+	positions, err := exchg.GetActivePositionByMarket(symbol)
+	if err != nil {
+		return 0, 0, err
+	}
+	notional := 0.0
+	for _, position := range positions {
+		// @dev: bnf qty is signed while hpl qty is absolute
+		posQty := math.Abs(position.Qty)
+		notional += posQty * position.EntryPrice
+		if position.Side == types.OrderSideSell {
+			posQty = -posQty
+		}
+		qty += posQty
+	}
+	if qty != 0 {
+		entryPrice = notional / math.Abs(qty)
+	}
+	return qty, entryPrice, nil
+}
+
+// MARK: GetAccountBalanceTask
+type GetAccountBalanceTask struct {
+	ExchangeIdKey string `json:"exchangeIdKey" jsonschema_description:"the key of the exchange id value in the memory that is set by the agent" memory:"string"`
+	OutputKey     string `json:"outputKey" jsonschema_description:"the key of the account balance output in the memory, in USD" memory:"number"`
+}
+
+func (t *GetAccountBalanceTask) Execute(ctx context.Context, memory *AgentMemory) error {
+	exchangeId, err := memory.GetAsStr(t.ExchangeIdKey)
+	if err != nil {
+		return err
+	}
+
+	balance, err := (*memory.Exchanges[exchangeId]).GetAccountBalance()
+	if err != nil {
+		return err
+	}
+	memory.SetAsFloat64(t.OutputKey, balance)
+	return nil
+}
+
+// MARK: GetOpenOrdersTask
+type GetOpenOrdersTask struct {
+	ExchangeIdKey  string `json:"exchangeIdKey" jsonschema_description:"the key of the exchange id value in the memory that is set by the agent" memory:"string"`
+	SymbolKey      string `json:"symbolKey" jsonschema_description:"the key of the symbol value in the memory (format 'TICKER_USD' not 'TICKER_USDT')" memory:"string"`
+	OutputKey      string `json:"outputKey" jsonschema_description:"the key of the open orders output in the memory" memory:"orders"`
+	CountOutputKey string `json:"countOutputKey,omitempty" jsonschema_description:"the key of the number of open orders output in the memory" memory:"number"`
+}
+
+func (t *GetOpenOrdersTask) Execute(ctx context.Context, memory *AgentMemory) error {
+	symbol, err := memory.GetAsStr(t.SymbolKey)
+	if err != nil {
+		return err
+	}
+	exchangeId, err := memory.GetAsStr(t.ExchangeIdKey)
+	if err != nil {
+		return err
+	}
+
+	orders, err := (*memory.Exchanges[exchangeId]).GetPendingOrders(symbol)
+	if err != nil {
+		return err
+	}
+	memory.SetAsOrders(t.OutputKey, orders)
+	if t.CountOutputKey != "" {
+		memory.SetAsFloat64(t.CountOutputKey, float64(len(orders)))
+	}
+	return nil
+}
+
+// MARK: ClosePositionIfTask
+type ClosePositionIfTask struct {
+	IfKey         string `json:"ifKey" jsonschema_description:"the key of the if value in the memory" memory:"string"`
+	IfValue       string `json:"ifValue" jsonschema_description:"the value of the if value in the memory"`
+	SymbolKey     string `json:"symbolKey" jsonschema_description:"the key of the symbol value in the memory (format 'TICKER_USD' not 'TICKER_USDT')" memory:"string"`
+	ExchangeIdKey string `json:"exchangeIdKey" jsonschema_description:"the key of the exchange id value in the memory that is set by the agent" memory:"string"`
+}
+
+func (t *ClosePositionIfTask) Execute(ctx context.Context, memory *AgentMemory) error {
 	ifValue, err := memory.GetAsStr(t.IfKey)
 	if err != nil {
 		return err
@@ -362,10 +706,41 @@ func (t *OpenLimitShortPositionIfTask) Execute(ctx context.Context, memory *Agen
 		return nil
 	}
 
-	amountUsd, err := memory.GetAsFloat64(t.AmountUsdKey)
+	symbol, err := memory.GetAsStr(t.SymbolKey)
+	if err != nil {
+		return err
+	}
+	exchangeId, err := memory.GetAsStr(t.ExchangeIdKey)
+	if err != nil {
+		return err
+	}
+
+	return (*memory.Exchanges[exchangeId]).CloseActivePositionByMarket(symbol, DEFAULT_LEVERAGE)
+}
+
+// MARK: ReducePositionIfTask
+type ReducePositionIfTask struct {
+	IfKey            string  `json:"ifKey" jsonschema_description:"the key of the if value in the memory" memory:"string"`
+	IfValue          string  `json:"ifValue" jsonschema_description:"the value of the if value in the memory"`
+	Fraction         float64 `json:"fraction" jsonschema_description:"the fraction of the position to close, in (0, 1]; 1 closes the whole position"`
+	PriceKey         string  `json:"priceKey,omitempty" jsonschema_description:"the key of the limit price value in the memory (e.g. a take profit), a market order is used if omitted" memory:"number"`
+	SymbolKey        string  `json:"symbolKey" jsonschema_description:"the key of the symbol value in the memory (format 'TICKER_USD' not 'TICKER_USDT')" memory:"string"`
+	ExchangeIdKey    string  `json:"exchangeIdKey" jsonschema_description:"the key of the exchange id value in the memory that is set by the agent" memory:"string"`
+	OrderIdOutputKey string  `json:"orderIdOutputKey,omitempty" jsonschema_description:"the key of the placed limit order id output in the memory, to cancel it on a later run" memory:"string"`
+}
+
+func (t *ReducePositionIfTask) Execute(ctx context.Context, memory *AgentMemory) error {
+	ifValue, err := memory.GetAsStr(t.IfKey)
 	if err != nil {
 		return err
 	}
+	if ifValue != t.IfValue {
+		return nil
+	}
+	if t.Fraction <= 0 || t.Fraction > 1 {
+		return fmt.Errorf("fraction must be in (0, 1], got %v", t.Fraction)
+	}
+
 	symbol, err := memory.GetAsStr(t.SymbolKey)
 	if err != nil {
 		return err
@@ -374,145 +749,296 @@ func (t *OpenLimitShortPositionIfTask) Execute(ctx context.Context, memory *Agen
 	if err != nil {
 		return err
 	}
+	exchg := *memory.Exchanges[exchangeId]
+
+	netQty, _, err := getNetPosition(exchg, symbol)
+	if err != nil {
+		return err
+	}
+	if netQty == 0 {
+		return nil // flat, nothing to reduce
+	}
+	side := types.OrderSideSell
+	if netQty < 0 {
+		side = types.OrderSideBuy
+	}
+	qty := math.Abs(netQty) * t.Fraction
+	// @dev: a full close keeps the exact position size, partial closes are rounded to the lot step size
+	orderType := types.OrderMarket
+	if t.PriceKey != "" {
+		orderType = types.OrderLimit
+	}
+	if t.Fraction < 1 {
+		qty = roundQty(exchg.GetMarket(symbol), qty, orderType)
+		if qty <= 0 {
+			return fmt.Errorf("size to reduce is below the lot step size of %v", symbol)
+		}
+	}
 
+	if t.PriceKey == "" {
+		return exchg.OpenMarketOrder(symbol, side, qty, DEFAULT_LEVERAGE, true)
+	}
 	price, err := memory.GetAsFloat64(t.PriceKey)
 	if err != nil {
 		return err
 	}
+	oId, err := exchg.OpenLimitOrder(symbol, side, price, qty, DEFAULT_LEVERAGE, true, types.OrderTIFGTC, "")
+	if err != nil {
+		return err
+	}
+	if t.OrderIdOutputKey != "" {
+		memory.SetAsStr(t.OrderIdOutputKey, oId)
+	}
+	return nil
+}
+
+// MARK: OpenLimitLadderIfTask
+const MAX_LADDER_ORDERS = 20
 
-	amount := amountUsd / price
+type OpenLimitLadderIfTask struct {
+	IfKey         string `json:"ifKey" jsonschema_description:"the key of the if value in the memory" memory:"string"`
+	IfValue       string `json:"ifValue" jsonschema_description:"the value of the if value in the memory"`
+	Side          string `json:"side" jsonschema_description:"the side of the orders: 'buy' | 'sell'"`
+	StartPriceKey string `json:"startPriceKey" jsonschema_description:"the key of the price value of the first order in the memory" memory:"number"`
+	EndPriceKey   string `json:"endPriceKey" jsonschema_description:"the key of the price value of the last order in the memory" memory:"number"`
+	Count         int    `json:"count" jsonschema_description:"the number of orders, evenly spaced from the start price to the end price (max 20)"`
+	AmountUsdKey  string `json:"amountUsdKey" jsonschema_description:"the key of the total amount usd in the memory, split evenly across the orders" memory:"number"`
+	ReduceOnly    bool   `json:"reduceOnly,omitempty" jsonschema_description:"true if the orders may only reduce the current position (e.g. a take profit ladder)"`
+	SymbolKey     string `json:"symbolKey" jsonschema_description:"the key of the symbol value in the memory (format 'TICKER_USD' not 'TICKER_USDT')" memory:"string"`
+	ExchangeIdKey string `json:"exchangeIdKey" jsonschema_description:"the key of the exchange id value in the memory that is set by the agent" memory:"string"`
+	Leverage      int    `json:"leverage,omitempty" jsonschema_description:"the leverage of the orders, default 5; must not exceed the max leverage of the market"`
+	OutputKey     string `json:"outputKey,omitempty" jsonschema_description:"the key of the placed orders output in the memory, to cancel them on a later run" memory:"orders"`
+}
 
-	// TODO: make leverage dynamic
-	lev := 5
-	_, err = (*memory.Exchanges[exchangeId]).OpenLimitOrder(symbol, types.OrderSideSell, price, amount, lev, false, types.OrderTIFGTC, "")
+func (t *OpenLimitLadderIfTask) Execute(ctx context.Context, memory *AgentMemory) error {
+	ifValue, err := memory.GetAsStr(t.IfKey)
 	if err != nil {
 		return err
 	}
+	if ifValue != t.IfValue {
+		return nil
+	}
+	side := types.OrderSide(t.Side)
+	if side != types.OrderSideBuy && side != types.OrderSideSell {
+		return fmt.Errorf("invalid side '%v', must be 'buy' or 'sell'", t.Side)
+	}
+	if t.Count < 1 || t.Count > MAX_LADDER_ORDERS {
+		return fmt.Errorf("count must be between 1 and %v, got %v", MAX_LADDER_ORDERS, t.Count)
+	}
 
-	return nil
+	startPrice, err := memory.GetAsFloat64(t.StartPriceKey)
+	if err != nil {
+		return err
+	}
+	endPrice, err := memory.GetAsFloat64(t.EndPriceKey)
+	if err != nil {
+		return err
+	}
+	amountUsd, err := memory.GetAsFloat64(t.AmountUsdKey)
+	if err != nil {
+		return err
+	}
+	symbol, err := memory.GetAsStr(t.SymbolKey)
+	if err != nil {
+		return err
+	}
+	exchangeId, err := memory.GetAsStr(t.ExchangeIdKey)
+	if err != nil {
+		return err
+	}
+
+	exchg := *memory.Exchanges[exchangeId]
+	mkt := exchg.GetMarket(symbol)
+	lev, err := getLeverage(mkt, t.Leverage)
+	if err != nil {
+		return err
+	}
+
+	step := 0.0
+	if t.Count > 1 {
+		step = (endPrice - startPrice) / float64(t.Count-1)
+	}
+	inputs := make([]types.LimitOrderInput, 0, t.Count)
+	for i := 0; i < t.Count; i++ {
+		price := startPrice + step*float64(i)
+		if price <= 0 {
+			return fmt.Errorf("invalid price %v for order #%v of the ladder", price, i+1)
+		}
+		qty := roundQty(mkt, amountUsd/float64(t.Count)/price, types.OrderLimit)
+		if qty <= 0 {
+			return fmt.Errorf("size of order #%v of the ladder is below the lot step size of %v", i+1, symbol)
+		}
+		inputs = append(inputs, types.LimitOrderInput{
+			Side:       side,
+			Price:      price,
+			Qty:        qty,
+			Tif:        types.OrderTIFGTC,
+			ReduceOnly: t.ReduceOnly,
+		})
+	}
+
+	// @dev: the placed orders of a partially failed ladder are stored before failing, so they can be canceled
+	oIds, err := exchg.OpenBatchLimitOrders(symbol, inputs, lev)
+	if t.OutputKey == "" {
+		return err
+	}
+	orders := make([]order.Order, 0, len(oIds))
+	for i, oId := range oIds {
+		if oId == "" || i >= len(inputs) {
+			continue
+		}
+		orders = append(orders, order.Order{
+			Id:           oId,
+			Symbol:       symbol,
+			OrderType:    types.OrderLimit,
+			OrderSide:    side,
+			Price:        inputs[i].Price,
+			OriginalQty:  inputs[i].Qty,
+			RemainingQty: inputs[i].Qty,
+		})
+	}
+	if err == nil || len(orders) > 0 {
+		memory.SetAsOrders(t.OutputKey, orders)
+	}
+	return err
+}
+
+// MARK: CancelOrderIfTask
+type CancelOrderIfTask struct {
+	IfKey         string `json:"ifKey" jsonschema_description:"the key of the if value in the memory" memory:"string"`
+	IfValue       string `json:"ifValue" jsonschema_description:"the value of the if value in the memory"`
+	OrderIdKey    string `json:"orderIdKey" jsonschema_description:"the key of the order id value in the memory" memory:"string"`
+	SymbolKey     string `json:"symbolKey" jsonschema_description:"the key of the symbol value in the memory (format 'TICKER_USD' not 'TICKER_USDT')" memory:"string"`
+	ExchangeIdKey string `json:"exchangeIdKey" jsonschema_description:"the key of the exchange id value in the memory that is set by the agent" memory:"string"`
+}
+
+func (t *CancelOrderIfTask) Execute(ctx context.Context, memory *AgentMemory) error {
+	ifValue, err := memory.GetAsStr(t.IfKey)
+	if err != nil {
+		return err
+	}
+	if ifValue != t.IfValue {
+		return nil
+	}
+
+	orderId, err := memory.GetAsStr(t.OrderIdKey)
+	if err != nil {
+		return err
+	}
+	symbol, err := memory.GetAsStr(t.SymbolKey)
+	if err != nil {
+		return err
+	}
+	exchangeId, err := memory.GetAsStr(t.ExchangeIdKey)
+	if err != nil {
+		return err
+	}
+
+	return (*memory.Exchanges[exchangeId]).CancelOrder(symbol, orderId, "")
+}
+
+// MARK: CancelOrdersIfTask
+type CancelOrdersIfTask struct {
+	IfKey         string `json:"ifKey" jsonschema_description:"the key of the if value in the memory" memory:"string"`
+	IfValue       string `json:"ifValue" jsonschema_description:"the value of the if value in the memory"`
+	OrdersKey     string `json:"ordersKey" jsonschema_description:"the key of the orders value in the memory" memory:"orders"`
+	SymbolKey     string `json:"symbolKey" jsonschema_description:"the key of the symbol value in the memory (format 'TICKER_USD' not 'TICKER_USDT')" memory:"string"`
+	ExchangeIdKey string `json:"exchangeIdKey" jsonschema_description:"the key of the exchange id value in the memory that is set by the agent" memory:"string"`
+}
+
+func (t *CancelOrdersIfTask) Execute(ctx context.Context, memory *AgentMemory) error {
+	ifValue, err := memory.GetAsStr(t.IfKey)
+	if err != nil {
+		return err
+	}
+	if ifValue != t.IfValue {
+		return nil
+	}
+
+	orders, err := memory.GetAsOrders(t.OrdersKey)
+	if err != nil {
+		return err
+	}
+	if len(orders) == 0 {
+		return nil
+	}
+	symbol, err := memory.GetAsStr(t.SymbolKey)
+	if err != nil {
+		return err
+	}
+	exchangeId, err := memory.GetAsStr(t.ExchangeIdKey)
+	if err != nil {
+		return err
+	}
+
+	orderIds := make([]string, 0, len(orders))
+	for _, order := range orders {
+		orderIds = append(orderIds, order.Id)
+	}
+	return (*memory.Exchanges[exchangeId]).CancelBatchOrders(symbol, orderIds)
+}
+
+// MARK: CancelAllOrdersIfTask
+type CancelAllOrdersIfTask struct {
+	IfKey         string `json:"ifKey" jsonschema_description:"the key of the if value in the memory" memory:"string"`
+	IfValue       string `json:"ifValue" jsonschema_description:"the value of the if value in the memory"`
+	SymbolKey     string `json:"symbolKey" jsonschema_description:"the key of the symbol value in the memory (format 'TICKER_USD' not 'TICKER_USDT')" memory:"string"`
+	ExchangeIdKey string `json:"exchangeIdKey" jsonschema_description:"the key of the exchange id value in the memory that is set by the agent" memory:"string"`
+}
+
+func (t *CancelAllOrdersIfTask) Execute(ctx context.Context, memory *AgentMemory) error {
+	ifValue, err := memory.GetAsStr(t.IfKey)
+	if err != nil {
+		return err
+	}
+	if ifValue != t.IfValue {
+		return nil
+	}
+
+	symbol, err := memory.GetAsStr(t.SymbolKey)
+	if err != nil {
+		return err
+	}
+	exchangeId, err := memory.GetAsStr(t.ExchangeIdKey)
+	if err != nil {
+		return err
+	}
+
+	return (*memory.Exchanges[exchangeId]).CancelAllOrders(symbol)
 }
 
 // MARK: allTasks
 func GetAllTasks() []AgentTask {
-	return []AgentTask{
-		{
-			BaseTask: BaseTask{
-				Name:        "getKline",
-				Description: "Get the kline of the asset using symbol from symbolKey and store the kline in outputKey",
-				Parameters: map[string]string{
-					"exchangeIdKey": "the key of the exchange id value in the memory that is set by the agent",
-					"symbolKey":     "the key of the symbol value in the memory (format 'TICKER_USD' not 'TICKER_USDT')",
-					"intervalKey":   "the key of the interval value in the memory ex. 'interval1' : '15m'",
-					"windowKey":     "the key of the window value in the memory ex. 'window1' : '20'",
-					"outputKey":     "the key of the output value in the memory ex. 'kline1' : '10000'",
-				},
-			},
-			Executable: &GetKlineTask{},
-		},
-		{
-			BaseTask: BaseTask{
-				Name:        "getMovingAverage",
-				Description: "Get the moving average of the asset using kline from klineKey and store the moving average in outputKey. Note that the kline is a list of kline events and if the window is equal to the length of the kline, the moving average will only have last value",
-				Parameters: map[string]string{
-					"klineKey":  "the key of the kline value in the memory",
-					"outputKey": "the key of the output value in the memory as []float64",
-				},
-			},
-			Executable: &GetMovingAverageTask{},
-		},
-		{
-			BaseTask: BaseTask{
-				Name:        "getBollingerBand",
-				Description: "Get the bollinger band of the asset using kline from klineKey and store the bollinger band in outputKey",
-				Parameters: map[string]string{
-					"klineKey":  "the key of the kline value in the memory",
-					"outputKey": "the key of the output value in the memory as []float64",
-				},
-			},
-			Executable: &GetBollingerBandTask{},
-		},
-		{
-			BaseTask: BaseTask{
-				Name:        "openMarketLongPositionIf",
-				Description: "Open a market long position of the symbolKey using amountUsd from amountUsdKey IF the value of ifKey in the memory is equal to ifValue",
-				Parameters: map[string]string{
-					"ifKey":         "the key of the if value in the memory",
-					"ifValue":       "the value of the if value in the memory",
-					"amountUsdKey":  "the key of the amount usd position size in the memory",
-					"symbolKey":     "the key of the symbol value in the memory (format 'TICKER_USD' not 'TICKER_USDT')",
-					"exchangeIdKey": "the key of the exchange id value in the memory that is set by the agent",
-				},
-			},
-			Executable: &OpenMarketLongPositionIfTask{},
-		},
-		{
-			BaseTask: BaseTask{
-				Name:        "openMarketShortPositionIf",
-				Description: "Open a market short position of the symbolKey using amountUsd from amountUsdKey IF the value of ifKey in the memory is equal to ifValue",
-				Parameters: map[string]string{
-					"ifKey":         "the key of the if value in the memory",
-					"ifValue":       "the value of the if value in the memory",
-					"priceKey":      "the key of the price value in the memory",
-					"amountUsdKey":  "the key of the amount usd position size in the memory",
-					"symbolKey":     "the key of the symbol value in the memory (format 'TICKER_USD' not 'TICKER_USDT')",
-					"exchangeIdKey": "the key of the exchange id value in the memory that is set by the agent",
-				},
-			},
-			Executable: &OpenMarketShortPositionIfTask{},
-		},
-		{
-			BaseTask: BaseTask{
-				Name:        "openLimitLongPositionIf",
-				Description: "Open a limit long position of the symbolKey using amountUsd from amountUsdKey IF the value of ifKey in the memory is equal to ifValue",
-				Parameters: map[string]string{
-					"ifKey":         "the key of the if value in the memory",
-					"ifValue":       "the value of the if value in the memory",
-					"priceKey":      "the key of the price value in the memory",
-					"amountUsdKey":  "the key of the amount usd position size in the memory",
-					"symbolKey":     "the key of the symbol value in the memory (format 'TICKER_USD' not 'TICKER_USDT')",
-					"exchangeIdKey": "the key of the exchange id value in the memory that is set by the agent",
-				},
-			},
-			Executable: &OpenLimitLongPositionIfTask{},
-		},
-		{
-			BaseTask: BaseTask{
-				Name:        "openShortPositionIf",
-				Description: "Open a short position of the symbolKey using amountUsd from amountUsdKey IF the value of ifKey in the memory is equal to ifValue",
-				Parameters: map[string]string{
-					"ifKey":         "the key of the if value in the memory",
-					"ifValue":       "the value of the if value in the memory",
-					"priceKey":      "the key of the price value in the memory",
-					"amountUsdKey":  "the key of the amount usd position size in the memory",
-					"symbolKey":     "the key of the symbol value in the memory (format 'TICKER_USD' not 'TICKER_USDT')",
-					"exchangeIdKey": "the key of the exchange id value in the memory that is set by the agent",
-				},
-			},
-			Executable: &OpenLimitShortPositionIfTask{},
-		},
-		{
-			BaseTask: BaseTask{
-				Name:        "askAI",
-				Description: "Ask the AI to answer a question along with the available data in dataKeys and store the answer as string in outputKey",
-				Parameters: map[string]string{
-					"prompt":    "the prompt to be asked to the AI in the memory. be specific and clear. u MUST CLEARLY outline the output format ex. ONLY OUTPUT 'yes' | 'no' | 'idk'",
-					"dataKeys":  "the keys of the data values in the memory separated by comma ex. 'data1,data2,data3'",
-					"outputKey": "the key of the output value in the memory",
-				},
-			},
-			Executable: &AskAITask{},
-		},
-		{
-			BaseTask: BaseTask{
-				Name:        "aiSetMemory",
-				Description: "Ask the AI a query along with the available data in dataKeys and return json that will be set in memory (map[string]string)",
-				Parameters: map[string]string{
-					"prompt":   "the prompt to be asked to the AI in the memory. be specific and clear. u MUST CLEARLY outline the output format ex. {'name': '...', 'desc': '...'}",
-					"dataKeys": "the keys of the data values in the memory separated by comma ex. 'data1,data2,data3'",
-					"output":   "output value to be set in the memory as map[string]string",
-				},
-			},
-			Executable: &AISetMemoryTask{},
-		},
+	allTasks := []AgentTask{}
+	for _, name := range taskNames {
+		allTasks = append(allTasks, *taskRegistry[name])
 	}
+	return allTasks
+}
+
+func init() {
+	RegisterTask("getKline", "Get the kline of the asset using symbol from symbolKey and store the kline in outputKey", &GetKlineTask{})
+	RegisterTask("getMovingAverage", "Get the simple moving average over window candles using kline from klineKey and store the moving average series (latest first) in outputKey. Note that if the window is equal to the length of the kline, the moving average will only have last value", &GetMovingAverageTask{})
+	RegisterTask("getBollingerBand", "Get the bollinger band over window candles with deviation standard deviations using kline from klineKey and store the bollinger band in outputKey", &GetBollingerBandTask{})
+	RegisterTask("getATR", "Get the average true range (ATR) over window candles using kline from klineKey and store it in outputKey", &GetATRTask{})
+	RegisterTask("getATRIndex", "Get the average true range over window candles as a percentage of the average close price using kline from klineKey and store it in outputKey", &GetATRIndexTask{})
+	RegisterTask("getRVI", "Get the relative volatility index (RVI, 0-100) over window candles using kline from klineKey and store it in outputKey", &GetRVITask{})
+	RegisterTask("getVolatility", "Get the volatility (root mean square of close price changes) over window candles using kline from klineKey and store it in outputKey", &GetVolatilityTask{})
+	RegisterOrderTask("openMarketLongPositionIf", "Open a market long position of the symbolKey, sized by sizing (amountUsd from amountUsdKey by default), IF the value of ifKey in the memory is equal to ifValue", &OpenMarketLongPositionIfTask{})
+	RegisterOrderTask("openMarketShortPositionIf", "Open a market short position of the symbolKey, sized by sizing (amountUsd from amountUsdKey by default), IF the value of ifKey in the memory is equal to ifValue", &OpenMarketShortPositionIfTask{})
+	RegisterOrderTask("openLimitLongPositionIf", "Open a limit long position of the symbolKey at the price from priceKey, sized by sizing (amountUsd from amountUsdKey by default), IF the value of ifKey in the memory is equal to ifValue", &OpenLimitLongPositionIfTask{})
+	RegisterOrderTask("openShortPositionIf", "Open a limit short position of the symbolKey at the price from priceKey, sized by sizing (amountUsd from amountUsdKey by default), IF the value of ifKey in the memory is equal to ifValue", &OpenLimitShortPositionIfTask{})
+	RegisterTask("detectCrossover", "Compare the latest values of the fast series from fastKey and the slow series from slowKey and store 'cross_up' if fast crossed above slow since the previous run, 'cross_down' if it crossed below, otherwise 'none' in outputKey. A cross is reported exactly once; the first run only records the state", &DetectCrossoverTask{})
+	RegisterTask("detectLevelCross", "Compare the latest value from valueKey with the level from levelKey and store 'cross_up' if the value crossed above the level since the previous run, 'cross_down' if it crossed below, otherwise 'none' in outputKey. A cross is reported exactly once; the first run only records the state", &DetectLevelCrossTask{})
+	RegisterTask("getPosition", "Get the current position of the symbolKey and store its side ('long' | 'short' | 'flat') in sideOutputKey, its size in qtyOutputKey and its entry price in entryPriceOutputKey", &GetPositionTask{})
+	RegisterTask("getAccountBalance", "Get the account balance in USD and store it in outputKey", &GetAccountBalanceTask{})
+	RegisterTask("getOpenOrders", "Get the open (pending) orders of the symbolKey and store them in outputKey, and optionally their count in countOutputKey", &GetOpenOrdersTask{})
+	RegisterOrderTask("closePositionIf", "Close the whole position of the symbolKey with a market order IF the value of ifKey in the memory is equal to ifValue", &ClosePositionIfTask{})
+	RegisterOrderTask("reducePositionIf", "Close a fraction of the position of the symbolKey with a reduce-only order IF the value of ifKey in the memory is equal to ifValue; a limit order at the price from priceKey if set, otherwise a market order. Does nothing if flat", &ReducePositionIfTask{})
+	RegisterOrderTask("openLimitLadderIf", "Place count limit orders on side, evenly spaced from the price in startPriceKey to the price in endPriceKey, splitting amountUsd from amountUsdKey evenly, IF the value of ifKey in the memory is equal to ifValue", &OpenLimitLadderIfTask{})
+	RegisterTask("cancelOrderIf", "Cancel the order of the symbolKey with the id from orderIdKey IF the value of ifKey in the memory is equal to ifValue", &CancelOrderIfTask{})
+	RegisterTask("cancelOrdersIf", "Cancel the orders from ordersKey (e.g. the output of getOpenOrders or openLimitLadderIf) IF the value of ifKey in the memory is equal to ifValue", &CancelOrdersIfTask{})
+	RegisterTask("cancelAllOrdersIf", "Cancel all open orders of the symbolKey IF the value of ifKey in the memory is equal to ifValue", &CancelAllOrdersIfTask{})
+	RegisterTask("askAI", "Ask the AI to answer a question along with the available data in dataKeys and store the answer in outputKey; set outputType to get a validated enum, number, bool or json object instead of free text", &AskAITask{})
+	RegisterTask("aiSetMemory", "Ask the AI a query along with the available data in dataKeys and return json that will be set in memory (map[string]string)", &AISetMemoryTask{})
 }
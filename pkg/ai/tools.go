@@ -0,0 +1,221 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"lfg/pkg/llm"
+	"lfg/pkg/types"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	DEFAULT_MAX_TOOL_STEPS = 10
+	DEFAULT_MAX_TOOL_CALLS = 20   // per run, a single model call can request many tools
+	MAX_TOOL_RESULT_CHARS  = 4000 // memory values are truncated in tool results & prompt
+
+	TOOL_SET_MEMORY = "setMemory"
+	TOOL_GET_MEMORY = "getMemory"
+)
+
+type setMemoryArgs struct {
+	Key   string `json:"key" jsonschema_description:"the key of the value in the memory"`
+	Value string `json:"value" jsonschema_description:"the value to store"`
+}
+
+type getMemoryArgs struct {
+	Keys string `json:"keys" jsonschema_description:"the keys of the values in the memory separated by comma ex. 'data1,data2'"`
+}
+
+// getTools returns the registered tasks & the memory tools as tool definitions
+func getTools() []llm.Tool {
+	setMemorySchema, _ := GenerateSchema[setMemoryArgs]()
+	getMemorySchema, _ := GenerateSchema[getMemoryArgs]()
+	tools := []llm.Tool{
+		{Name: TOOL_SET_MEMORY, Description: "Store a value in the memory", Parameters: setMemorySchema},
+		{Name: TOOL_GET_MEMORY, Description: "Read values from the memory", Parameters: getMemorySchema},
+	}
+	for _, task := range GetAllTasks() {
+		tools = append(tools, llm.Tool{
+			Name:        task.Name,
+			Description: task.Description,
+			Parameters:  task.Schema(),
+		})
+	}
+	return tools
+}
+
+// setupToolsMode replaces planning in tools mode; the plan only identifies the memory
+// snapshots of the agent, so they are restored while its prompt and setup are unchanged
+func (a *Agent) setupToolsMode() error {
+	plan := ExecutionPlan{
+		Reasoning: fmt.Sprintf("tools mode (%v): the runtime model calls the tools on each run", a.promptHash()),
+		Tasks:     []TaskFromAI{},
+		InitState: []Memory{},
+	}
+	if a.Store != nil {
+		if err := a.restoreMemory(plan); err != nil {
+			return err
+		}
+	}
+	a.setExecutionPlan(&plan)
+	a.logger.Infof("Tools mode, skipping planning (max %v steps & %v tool calls per run)", a.maxToolSteps(), a.maxToolCalls())
+	return nil
+}
+
+func (a *Agent) maxToolSteps() int {
+	if a.Config.MaxSteps > 0 {
+		return a.Config.MaxSteps
+	}
+	return DEFAULT_MAX_TOOL_STEPS
+}
+
+func (a *Agent) maxToolCalls() int {
+	if a.Config.MaxToolCalls > 0 {
+		return a.Config.MaxToolCalls
+	}
+	return DEFAULT_MAX_TOOL_CALLS
+}
+
+// executeTools lets the runtime model call tools until it answers without a tool call
+func (a *Agent) executeTools(ctx context.Context, runId string, result *RunResult) error {
+	availableExchangesId := sortedKeys(a.Memory.Exchanges)
+	systemPrompt := fmt.Sprintf(ToolsSystemPrompt, a.Prompt, availableExchangesId, getRunContextDescription(a.Config), getMemoryDescription(a.Memory))
+	messages := []llm.Message{
+		{Role: llm.RoleSystem, Content: systemPrompt},
+		{Role: llm.RoleUser, Content: fmt.Sprintf("Run the strategy now (%v UTC)", time.Now().UTC().Format(time.RFC3339))},
+	}
+	tools := getTools()
+	toolCalls := 0
+	client := withJournalScope(a.Memory.LLM, runId, "tools")
+
+	for step := 1; step <= a.maxToolSteps(); step++ {
+		res, err := client.Complete(ctx, llm.Request{Messages: messages, Tools: tools})
+		if err != nil {
+			return fmt.Errorf("fail to get tool calls at step %v: %w", step, err)
+		}
+		messages = append(messages, res.Message)
+		if len(res.Message.ToolCalls) == 0 {
+			a.logger.Infof("Tools run finished in %v steps: %v", step, res.Message.Content)
+			return nil
+		}
+		// @dev: checked before any call of the step runs, so a step is never half executed
+		if toolCalls += len(res.Message.ToolCalls); toolCalls > a.maxToolCalls() {
+			return fmt.Errorf("max tool calls (%v) exceeded at step %v: %v tool calls requested", a.maxToolCalls(), step, len(res.Message.ToolCalls))
+		}
+		for _, toolCall := range res.Message.ToolCalls {
+			a.logger.Infof("Calling tool: %v %v", toolCall.Name, toolCall.Arguments)
+			taskResult, content := a.callTool(ctx, runId, toolCall)
+			result.Tasks = append(result.Tasks, taskResult)
+			messages = append(messages, llm.Message{Role: llm.RoleTool, Content: content, ToolCallId: toolCall.Id})
+		}
+	}
+	return fmt.Errorf("max steps (%v) reached before the run finished", a.maxToolSteps())
+}
+
+// callTool runs a tool call and returns its result for the model; tool errors are
+// returned to the model instead of stopping the run, so it can adapt
+func (a *Agent) callTool(ctx context.Context, runId string, toolCall llm.ToolCall) (TaskResult, string) {
+	start := time.Now()
+	memory := a.Memory.WithWriter(toolCall.Name, runId)
+	fail := func(err error) (TaskResult, string) {
+		a.logger.Warnf("Tool %v failed: %v", toolCall.Name, err)
+		return TaskResult{Name: toolCall.Name, Status: types.TaskStatusFailed, Attempts: 1, Duration: time.Since(start), Error: err.Error()}, "error: " + err.Error()
+	}
+
+	args, err := getToolArguments(toolCall.Arguments)
+	if err != nil {
+		return fail(err)
+	}
+
+	var content string
+	switch toolCall.Name {
+	case TOOL_SET_MEMORY:
+		// @dev: stored as string like the init state of a plan, tasks convert it on read
+		memory.SetAsStr(args["key"], args["value"])
+		content = "done"
+	case TOOL_GET_MEMORY:
+		content = getValuesDescription(memory, strings.Split(args["keys"], ","))
+	default:
+		task, err := GetTaskByName(toolCall.Name, args)
+		if err != nil {
+			return fail(err)
+		}
+		task.Policy = TaskPolicy{OnError: types.TaskErrorSkip, Timeout: DEFAULT_TASK_TIMEOUT}
+		if _, err := a.runTask(ctx, *task, memory); err != nil {
+			return fail(err)
+		}
+		outputKeys := []string{}
+		for _, param := range task.Parameters {
+			if outputKey, exists := args[param.Name]; exists && isOutputParam(param.Name) {
+				outputKeys = append(outputKeys, outputKey)
+			}
+		}
+		content = "done"
+		if len(outputKeys) > 0 {
+			content = getValuesDescription(memory, outputKeys)
+		}
+	}
+	return TaskResult{Name: toolCall.Name, Status: types.TaskStatusSucceeded, Attempts: 1, Duration: time.Since(start)}, content
+}
+
+// the model emits typed json arguments while tasks take the plan format (string values)
+func getToolArguments(arguments string) (map[string]string, error) {
+	raw := make(map[string]any)
+	if strings.TrimSpace(arguments) != "" {
+		if err := json.Unmarshal([]byte(arguments), &raw); err != nil {
+			return nil, fmt.Errorf("invalid tool arguments: %w", err)
+		}
+	}
+	args := make(map[string]string)
+	for key, value := range raw {
+		switch v := value.(type) {
+		case nil:
+		case string:
+			args[key] = v
+		case float64:
+			args[key] = strconv.FormatFloat(v, 'f', -1, 64)
+		case bool:
+			args[key] = strconv.FormatBool(v)
+		default:
+			data, err := json.Marshal(v)
+			if err != nil {
+				return nil, fmt.Errorf("invalid tool argument '%v': %w", key, err)
+			}
+			args[key] = string(data)
+		}
+	}
+	return args, nil
+}
+
+func getMemoryDescription(memory *AgentMemory) string {
+	snapshot := memory.Snapshot()
+	if len(snapshot) == 0 {
+		return "(empty)"
+	}
+	return getValuesDescription(memory, sortedKeys(snapshot))
+}
+
+func getValuesDescription(memory *AgentMemory, keys []string) string {
+	desc := ""
+	for _, key := range keys {
+		value, err := memory.Get(key)
+		if err != nil {
+			desc += fmt.Sprintf("- %s: (not set)\n", key)
+			continue
+		}
+		str := value.String()
+		if len(str) > MAX_TOOL_RESULT_CHARS {
+			// @dev: klines are oldest first, keep the latest ones
+			if value.Type == MemoryTypeKlines {
+				str = "(truncated)..." + str[len(str)-MAX_TOOL_RESULT_CHARS:]
+			} else {
+				str = str[:MAX_TOOL_RESULT_CHARS] + "...(truncated)"
+			}
+		}
+		desc += fmt.Sprintf("- %s (%s): %s\n", key, value.Type, str)
+	}
+	return desc
+}
@@ -0,0 +1,151 @@
+package ai
+
+import (
+	"context"
+	"lfg/config"
+	"lfg/pkg/journal"
+	"lfg/pkg/llm"
+	"lfg/pkg/types"
+	"strings"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// capturingClient keeps the requests sent to the scripted model
+type capturingClient struct {
+	*llm.ScriptedClient
+	requests []llm.Request
+}
+
+func (c *capturingClient) Complete(ctx context.Context, req llm.Request) (llm.Response, error) {
+	c.requests = append(c.requests, req)
+	return c.ScriptedClient.Complete(ctx, req)
+}
+
+func newToolsAgent(maxSteps int, toolResponses ...string) (*Agent, *capturingClient) {
+	client := &capturingClient{ScriptedClient: llm.NewScriptedClientFromResponses(map[string][]string{"tool": toolResponses})}
+	return &Agent{
+		Id:     "test",
+		Prompt: "buy the dip",
+		Config: &config.AgentConfig{Mode: types.AgentModeTools, MaxSteps: maxSteps},
+		Memory: NewAgentMemory(nil, client),
+		logger: log.WithField("agent", "test"),
+	}, client
+}
+
+func TestExecuteTools(t *testing.T) {
+	agent, client := newToolsAgent(0,
+		`[{"name": "setMemory", "arguments": {"key": "symbol", "value": "BTC_USD"}}, {"name": "getMemory", "arguments": {"keys": "symbol,side"}}]`,
+		`[{"name": "flyToTheMoon", "arguments": {}}]`,
+		"nothing to do",
+	)
+	result := &RunResult{}
+	if err := agent.executeTools(context.Background(), "run-1", result); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if symbol, _ := agent.Memory.GetAsStr("symbol"); symbol != "BTC_USD" {
+		t.Fatalf("symbol = %q, want BTC_USD", symbol)
+	}
+	wantStatuses := []types.TaskStatus{types.TaskStatusSucceeded, types.TaskStatusSucceeded, types.TaskStatusFailed}
+	if len(result.Tasks) != len(wantStatuses) {
+		t.Fatalf("tasks = %+v, want %v", result.Tasks, len(wantStatuses))
+	}
+	for i, status := range wantStatuses {
+		if result.Tasks[i].Status != status {
+			t.Fatalf("task %v (%v) status = %v, want %v", i, result.Tasks[i].Name, result.Tasks[i].Status, status)
+		}
+	}
+	if len(client.requests) != 3 {
+		t.Fatalf("model calls = %v, want 3", len(client.requests))
+	}
+
+	// the model reads the tool results, a failed tool is reported instead of stopping the run
+	messages := client.requests[2].Messages
+	toolResults := []string{}
+	for _, message := range messages {
+		if message.Role == llm.RoleTool {
+			toolResults = append(toolResults, message.Content)
+		}
+	}
+	if len(toolResults) != 3 {
+		t.Fatalf("tool results = %q, want 3", toolResults)
+	}
+	if toolResults[0] != "done" ||
+		!strings.Contains(toolResults[1], "symbol (string): BTC_USD") || !strings.Contains(toolResults[1], "side: (not set)") ||
+		!strings.HasPrefix(toolResults[2], "error: ") {
+		t.Fatalf("tool results = %q", toolResults)
+	}
+}
+
+func TestExecuteToolsMaxSteps(t *testing.T) {
+	agent, client := newToolsAgent(2, `[{"name": "getMemory", "arguments": {"keys": "symbol"}}]`)
+	err := agent.executeTools(context.Background(), "run-1", &RunResult{})
+	if err == nil || !strings.Contains(err.Error(), "max steps (2)") {
+		t.Fatalf("error = %v, want max steps", err)
+	}
+	if len(client.requests) != 2 {
+		t.Fatalf("model calls = %v, want 2", len(client.requests))
+	}
+}
+
+func TestExecuteToolsMaxToolCalls(t *testing.T) {
+	agent, _ := newToolsAgent(0,
+		`[{"name": "setMemory", "arguments": {"key": "a", "value": "1"}}, {"name": "setMemory", "arguments": {"key": "b", "value": "2"}}]`,
+		`[{"name": "setMemory", "arguments": {"key": "c", "value": "3"}}, {"name": "setMemory", "arguments": {"key": "d", "value": "4"}}]`,
+	)
+	agent.Config.MaxToolCalls = 3
+	result := &RunResult{}
+	err := agent.executeTools(context.Background(), "run-1", result)
+	if err == nil || !strings.Contains(err.Error(), "max tool calls (3)") {
+		t.Fatalf("error = %v, want max tool calls", err)
+	}
+	// the step exceeding the cap runs none of its calls
+	if len(result.Tasks) != 2 {
+		t.Fatalf("tool calls run = %v, want 2", len(result.Tasks))
+	}
+	if _, err := agent.Memory.GetAsStr("c"); err == nil {
+		t.Fatalf("tool call of the step exceeding the cap was run")
+	}
+}
+
+func TestExecuteToolsJournal(t *testing.T) {
+	agent, client := newToolsAgent(0, `[{"name": "setMemory", "arguments": {"key": "symbol", "value": "BTC_USD"}}]`, "done")
+	agent.Planner = client
+	j := journal.New(nil)
+	agent.EnableJournal(j)
+	if err := agent.executeTools(context.Background(), "run-1", &RunResult{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// the model calls & tool writes are recorded under the run, without a shared scope
+	llmEntries := j.Query(journal.Filter{RunId: "run-1", Task: "tools", Kind: types.JournalLLM})
+	memoryEntries := j.Query(journal.Filter{RunId: "run-1", Task: TOOL_SET_MEMORY, Kind: types.JournalMemory})
+	if len(llmEntries) != 2 || len(memoryEntries) != 1 {
+		t.Fatalf("journal = %+v", j.Query(journal.Filter{}))
+	}
+}
+
+func TestGetToolArguments(t *testing.T) {
+	args, err := getToolArguments(`{"symbol": "BTC_USD", "window": 20, "ratio": 0.5, "reduceOnly": true, "levels": [1, 2], "none": null}`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := map[string]string{"symbol": "BTC_USD", "window": "20", "ratio": "0.5", "reduceOnly": "true", "levels": "[1,2]"}
+	if len(args) != len(want) {
+		t.Fatalf("args = %v, want %v", args, want)
+	}
+	for key, value := range want {
+		if args[key] != value {
+			t.Fatalf("args[%v] = %q, want %q", key, args[key], value)
+		}
+	}
+
+	if args, err := getToolArguments(" "); err != nil || len(args) != 0 {
+		t.Fatalf("empty arguments = %v, %v", args, err)
+	}
+	if _, err := getToolArguments("{symbol: BTC_USD}"); err == nil {
+		t.Fatalf("invalid json: want error")
+	}
+}
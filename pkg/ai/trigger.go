@@ -0,0 +1,156 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"lfg/config"
+	"lfg/pkg/types"
+	"strings"
+	"time"
+)
+
+// well-known memory keys written right before a triggered run
+const (
+	MemoryKeyTriggerType       = "triggerType"       // klineClose | markPriceCross | orderFill | marketListing
+	MemoryKeyTriggerExchangeId = "triggerExchangeId" // exchange id the event comes from
+	MemoryKeyTriggerSymbol     = "triggerSymbol"     // universal symbol e.g. BTC_USD; empty for a market missing from the symbol map
+	MemoryKeyTriggerTime       = "triggerTime"       // event time in RFC3339
+	MemoryKeyTriggerPrice      = "triggerPrice"      // kline close / mark price / order avg fill price
+	MemoryKeyTriggerKline      = "triggerKline"      // klineClose only: the closed kline as klines
+	MemoryKeyTriggerOrderId    = "triggerOrderId"    // orderFill only
+	MemoryKeyTriggerOrderSide  = "triggerOrderSide"  // orderFill only: buy | sell
+	MemoryKeyTriggerOrderQty   = "triggerOrderQty"   // orderFill only: filled qty
+	MemoryKeyTriggerListing    = "triggerListing"    // marketListing only: listed | delisted
+	MemoryKeyTriggerLocSymbol  = "triggerLocSymbol"  // marketListing only: symbol of the market on the exchange
+)
+
+type TriggerEvent struct {
+	Type       types.TriggerType
+	ExchangeId string
+	Symbol     string
+	Time       time.Time
+
+	// exactly one of below is set depending on Type
+	KLine     *types.KLineEvent
+	MarkPrice *types.MarkPriceEvent
+	Order     *types.OrderEvent
+	Market    *types.MarketEvent
+}
+
+// TryExecuteOnTrigger injects the event into memory and runs the task pipeline,
+// unless a previous run is still in progress (executed=false)
+func (a *Agent) TryExecuteOnTrigger(ctx context.Context, evt TriggerEvent) (result *RunResult, executed bool, err error) {
+	if !a.execMu.TryLock() {
+		return nil, false, nil
+	}
+	defer a.execMu.Unlock()
+
+	runId := newRunId()
+	if err := a.Memory.WithWriter("trigger", runId).SetTriggerEvent(evt); err != nil {
+		return nil, true, err
+	}
+	a.logger.Infof("triggered by %v on %v %v", evt.Type, evt.ExchangeId, evt.Symbol)
+	result, err = a.execute(ctx, runId)
+	return result, true, err
+}
+
+func (m *AgentMemory) SetTriggerEvent(evt TriggerEvent) error {
+	m.SetAsStr(MemoryKeyTriggerType, evt.Type)
+	m.SetAsStr(MemoryKeyTriggerExchangeId, evt.ExchangeId)
+	m.SetAsStr(MemoryKeyTriggerSymbol, evt.Symbol)
+	m.SetAsStr(MemoryKeyTriggerTime, evt.Time.UTC().Format(time.RFC3339))
+
+	switch evt.Type {
+	case types.TriggerKLineClose:
+		if evt.KLine == nil {
+			return fmt.Errorf("missing kline in %v trigger event", evt.Type)
+		}
+		m.SetAsFloat64(MemoryKeyTriggerPrice, evt.KLine.Kline.C)
+		m.SetAsKlines(MemoryKeyTriggerKline, []types.KLineEvent{*evt.KLine})
+	case types.TriggerMarkPriceCross:
+		if evt.MarkPrice == nil {
+			return fmt.Errorf("missing mark price in %v trigger event", evt.Type)
+		}
+		m.SetAsFloat64(MemoryKeyTriggerPrice, evt.MarkPrice.Price)
+	case types.TriggerOrderFill:
+		if evt.Order == nil {
+			return fmt.Errorf("missing order in %v trigger event", evt.Type)
+		}
+		m.SetAsFloat64(MemoryKeyTriggerPrice, evt.Order.AvgPrice)
+		m.SetAsStr(MemoryKeyTriggerOrderId, evt.Order.OId)
+		m.SetAsStr(MemoryKeyTriggerOrderSide, evt.Order.Side)
+		m.SetAsFloat64(MemoryKeyTriggerOrderQty, evt.Order.FilledQty)
+	case types.TriggerMarketListing:
+		if evt.Market == nil {
+			return fmt.Errorf("missing market in %v trigger event", evt.Type)
+		}
+		m.SetAsStr(MemoryKeyTriggerListing, evt.Market.Type)
+		m.SetAsStr(MemoryKeyTriggerLocSymbol, evt.Market.LocSymbol)
+	default:
+		return fmt.Errorf("unknown trigger type: %v", evt.Type)
+	}
+	return nil
+}
+
+// TriggerMemoryKeys returns the memory keys that are available to the plan for the given triggers
+func TriggerMemoryKeys(triggers []*config.TriggerConfig) []string {
+	if len(triggers) == 0 {
+		return nil
+	}
+	keys := []string{MemoryKeyTriggerType, MemoryKeyTriggerExchangeId, MemoryKeyTriggerSymbol, MemoryKeyTriggerTime}
+	hasPrice, hasKLine, hasOrder, hasListing := false, false, false, false
+	for _, trigger := range triggers {
+		hasPrice = hasPrice || trigger.Type != types.TriggerMarketListing
+		hasKLine = hasKLine || trigger.Type == types.TriggerKLineClose
+		hasOrder = hasOrder || trigger.Type == types.TriggerOrderFill
+		hasListing = hasListing || trigger.Type == types.TriggerMarketListing
+	}
+	if hasPrice {
+		keys = append(keys, MemoryKeyTriggerPrice)
+	}
+	if hasKLine {
+		keys = append(keys, MemoryKeyTriggerKline)
+	}
+	if hasOrder {
+		keys = append(keys, MemoryKeyTriggerOrderId, MemoryKeyTriggerOrderSide, MemoryKeyTriggerOrderQty)
+	}
+	if hasListing {
+		keys = append(keys, MemoryKeyTriggerListing, MemoryKeyTriggerLocSymbol)
+	}
+	return keys
+}
+
+// describe how the plan is run so the planner can rely on trigger keys
+func getRunContextDescription(agentConfig *config.AgentConfig) string {
+	desc := ""
+	if agentConfig.Schedule != nil {
+		if agentConfig.Schedule.Cron != "" {
+			desc += fmt.Sprintf("- The plan runs on cron schedule '%s' (UTC)\n", agentConfig.Schedule.Cron)
+		} else {
+			desc += fmt.Sprintf("- The plan runs every %s\n", agentConfig.Schedule.Every)
+		}
+	}
+	for _, trigger := range agentConfig.Triggers {
+		switch trigger.Type {
+		case types.TriggerKLineClose:
+			desc += fmt.Sprintf("- The plan runs when a %s kline of %s closes on exchange %s\n", trigger.Interval, trigger.Symbol, trigger.Exchange)
+		case types.TriggerMarkPriceCross:
+			desc += fmt.Sprintf("- The plan runs when the mark price of %s on exchange %s crosses %v (direction: %s)\n", trigger.Symbol, trigger.Exchange, trigger.Level, trigger.Direction)
+		case types.TriggerOrderFill:
+			desc += fmt.Sprintf("- The plan runs when an order of %s is filled on exchange %s\n", trigger.Symbol, trigger.Exchange)
+		case types.TriggerMarketListing:
+			market := trigger.Symbol
+			if market == "" {
+				market = "any market"
+			}
+			desc += fmt.Sprintf("- The plan runs when %s is listed or delisted on exchange %s\n", market, trigger.Exchange)
+		}
+	}
+	if keys := TriggerMemoryKeys(agentConfig.Triggers); len(keys) > 0 {
+		desc += fmt.Sprintf("- Before each triggered run these keys are set in memory: %s\n", strings.Join(keys, ", "))
+	}
+	if desc == "" {
+		desc = "- The plan runs once\n"
+	}
+	return desc
+}
@@ -0,0 +1,142 @@
+package ai
+
+import (
+	"fmt"
+	"lfg/pkg/exchange"
+	"sort"
+	"strings"
+)
+
+// ValidatePlan statically checks an execution plan against the task registry and the agent
+// exchanges before it is sent to the refiner. An empty result means the plan is valid.
+//
+// @dev: `availableKeys` are memory keys set outside of the plan (e.g. trigger keys)
+func ValidatePlan(plan ExecutionPlan, exchanges map[string]*exchange.Exchange, availableKeys []string) []string {
+	violations := []string{}
+
+	initState := make(map[string]string)
+	produced := make(map[string]MemoryType) // key -> value type, empty if unknown
+	for _, key := range availableKeys {
+		produced[key] = ""
+	}
+	for _, state := range plan.InitState {
+		initState[state.Key] = state.Value
+		produced[state.Key] = MemoryTypeStr
+	}
+
+	// @dev: aiSetMemory writes keys chosen by the AI at runtime, so keys that
+	// are not resolvable after it can only be checked by the refiner
+	dynamicKeys := false
+
+	for i, taskFromAI := range plan.Tasks {
+		where := fmt.Sprintf("task #%d '%s'", i+1, taskFromAI.Name)
+
+		task, exists := taskRegistry[taskFromAI.Name]
+		if !exists {
+			violations = append(violations, fmt.Sprintf("%s: unknown task, must be one of [%s]", where, strings.Join(taskNames, ", ")))
+			continue
+		}
+
+		if _, err := newTaskPolicy(taskFromAI); err != nil {
+			violations = append(violations, fmt.Sprintf("%s: %v", where, err))
+		}
+
+		// params must match the task parameters and convert to their type
+		taskViolations := len(violations)
+		paramNames := []string{}
+		for _, param := range task.Parameters {
+			paramNames = append(paramNames, param.Name)
+			if _, exists := taskFromAI.Parameters[param.Name]; param.Required && !exists {
+				violations = append(violations, fmt.Sprintf("%s: missing parameter '%s'", where, param.Name))
+			}
+		}
+		for _, param := range sortedKeys(taskFromAI.Parameters) {
+			if _, exists := task.getParameter(param); !exists {
+				violations = append(violations, fmt.Sprintf("%s: unknown parameter '%s', must be one of [%s]", where, param, strings.Join(paramNames, ", ")))
+			}
+		}
+		executable, err := task.newExecutable(taskFromAI.Parameters)
+		if err != nil && len(violations) == taskViolations {
+			violations = append(violations, fmt.Sprintf("%s: %v", where, err))
+		}
+
+		// input keys must be produced by init state or an earlier task
+		for _, param := range sortedKeys(taskFromAI.Parameters) {
+			if isOutputParam(param) || !isKeyParam(param) {
+				continue
+			}
+			taskParam, _ := task.getParameter(param)
+			for _, key := range splitKeys(param, taskFromAI.Parameters[param]) {
+				memoryType, exists := produced[key]
+				if !exists && !dynamicKeys {
+					violations = append(violations, fmt.Sprintf("%s: '%s' reads memory key '%s' which is neither in InitState nor an output of an earlier task", where, param, key))
+				}
+				if exists && memoryType != "" && taskParam.MemoryType != "" && !CanReadAs(memoryType, taskParam.MemoryType) {
+					violations = append(violations, fmt.Sprintf("%s: '%s' reads memory key '%s' as %s but it holds %s", where, param, key, taskParam.MemoryType, memoryType))
+				}
+			}
+		}
+
+		// exchange id and symbol must be known, if they are set in init state
+		exchangeId, hasExchangeId := initState[taskFromAI.Parameters["exchangeIdKey"]]
+		if hasExchangeId {
+			if _, exists := exchanges[exchangeId]; !exists {
+				violations = append(violations, fmt.Sprintf("%s: exchange id '%s' (from '%s') is not available, must be one of [%s]", where, exchangeId, taskFromAI.Parameters["exchangeIdKey"], strings.Join(sortedKeys(exchanges), ", ")))
+				hasExchangeId = false
+			}
+		}
+		if symbol, exists := initState[taskFromAI.Parameters["symbolKey"]]; exists {
+			if !hasSymbol(exchanges, exchangeId, hasExchangeId, symbol) {
+				violations = append(violations, fmt.Sprintf("%s: symbol '%s' (from '%s') is not listed on the exchange, use the 'TICKER_USD' format", where, symbol, taskFromAI.Parameters["symbolKey"]))
+			}
+		}
+
+		for _, param := range task.Parameters {
+			if outputKey, exists := taskFromAI.Parameters[param.Name]; exists && isOutputParam(param.Name) {
+				produced[outputKey] = param.MemoryType
+				if typed, ok := executable.(OutputTyped); ok {
+					produced[outputKey] = typed.OutputMemoryType()
+				}
+			}
+		}
+		if task.Name == "aiSetMemory" {
+			dynamicKeys = true
+		}
+	}
+	return violations
+}
+
+// params ending with `Key` (or `Keys`) reference memory keys
+func isKeyParam(param string) bool {
+	return strings.HasSuffix(param, "Key") || strings.HasSuffix(param, "Keys")
+}
+
+func splitKeys(param string, value string) []string {
+	if !strings.HasSuffix(param, "Keys") {
+		return []string{value}
+	}
+	// @dev: not trimmed, the task reads the keys exactly as split
+	return strings.Split(value, ",")
+}
+
+// check the symbol on the task exchange, or on any exchange if it is not known statically
+func hasSymbol(exchanges map[string]*exchange.Exchange, exchangeId string, hasExchangeId bool, symbol string) bool {
+	if hasExchangeId {
+		return (*exchanges[exchangeId]).HasSymbol(symbol)
+	}
+	for _, exchg := range exchanges {
+		if (*exchg).HasSymbol(symbol) {
+			return true
+		}
+	}
+	return false
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
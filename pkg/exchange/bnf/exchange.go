@@ -3,6 +3,7 @@ package bnf
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"lfg/config"
 	"lfg/pkg/market"
@@ -10,10 +11,12 @@ import (
 	"lfg/pkg/stream"
 	"lfg/pkg/types"
 	"lfg/pkg/utils"
+	"math"
 	"os"
 	"path/filepath"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	log "github.com/sirupsen/logrus"
@@ -22,17 +25,32 @@ import (
 	"github.com/adshao/go-binance/v2/futures"
 )
 
+const (
+	MAX_BATCH_ORDERS     = 5                // orders per batch order request
+	MAX_BATCH_CANCELS    = 10               // order ids per batch cancel request
+	LISTEN_KEY_KEEPALIVE = 30 * time.Minute // listen keys expire after 60m without keepalive
+	INCOME_HISTORY_LIMIT = 1000             // incomes per income history request
+	MARK_PRICE_MAX_DELAY = 5000             // ms; mark prices older than this are not cached by order mgmt sessions
+
+	// ref: https://www.binance.com/en/fee/futureFee
+	BASE_MAKER_FEE_PCT = 0.0002    // 2 bps, used when the account commission rates are unavailable
+	BASE_TAKER_FEE_PCT = 0.0005    // 5 bps, used when the account commission rates are unavailable
+	FEE_REF_SYMBOL     = "BTCUSDT" // symbol the account commission rates are read from
+)
+
 type BnfExchange struct {
 	BnfConfig *bnfConfig
 
 	sClient *binance.Client
 	fClient *futures.Client
 
-	Markets      map[string]*market.Market
-	SymbolMapU2L map[string]string
-	SymbolMapL2U map[string]string
+	Markets *market.Registry
+	Symbols *market.SymbolMap
 
 	StopStreamC map[string]map[types.Stream]chan struct{}
+
+	AccountLeverage map[string]int // local symbol -> leverage set through UpdateAccountLeverage
+	leverageMu      sync.Mutex
 }
 
 func New(exchgConfig *config.ExchangeConfig) (*BnfExchange, error) {
@@ -45,8 +63,7 @@ func New(exchgConfig *config.ExchangeConfig) (*BnfExchange, error) {
 	}
 
 	// (2) load symbol
-	symbolMapU2L := utils.LoadExchangeSymbolMap(string(types.ExchangeBnf))
-	symbolMapL2U := utils.ReverseStrMap(symbolMapU2L)
+	symbols := market.NewSymbolMap(utils.LoadExchangeSymbolMap(string(types.ExchangeBnf)))
 
 	// (3) validate config
 	key := utils.LoadEnv(exchgConfig.EnvPrefix + "_API_KEY")
@@ -67,19 +84,28 @@ func New(exchgConfig *config.ExchangeConfig) (*BnfExchange, error) {
 	}
 
 	// (4) load markets
-	markets, err := loadMarkets(fClient)
+	markets, err := market.NewRegistry(types.ExchangeBnf, func() (map[string]*market.Market, error) {
+		markets, err := loadMarkets(fClient)
+		if err != nil {
+			return nil, err
+		}
+		// @dev: markets missing from the symbol map (e.g. new listings) are mapped before their listing is emitted
+		symbols.Extend(markets, deriveUniSymbol)
+		return markets, nil
+	})
 	if err != nil {
 		return nil, err
 	}
 
 	return &BnfExchange{
-		BnfConfig:    &bnfConfig,
-		sClient:      sClient,
-		fClient:      fClient,
-		SymbolMapU2L: symbolMapU2L,
-		SymbolMapL2U: symbolMapL2U,
-		Markets:      markets,
-		StopStreamC:  make(map[string]map[types.Stream]chan struct{}),
+		BnfConfig:   &bnfConfig,
+		sClient:     sClient,
+		fClient:     fClient,
+		Symbols:     symbols,
+		Markets:     markets,
+		StopStreamC: make(map[string]map[types.Stream]chan struct{}),
+
+		AccountLeverage: make(map[string]int),
 	}, nil
 }
 
@@ -92,11 +118,18 @@ func (e *BnfExchange) Name() types.ExchangeName {
 // ╚═════════════╝
 
 func (e *BnfExchange) GetMarket(symbol string) *market.Market {
-	symbol = e.ToLocSymbol(symbol)
-	if market, exists := e.Markets[symbol]; exists {
-		return market
-	}
-	return nil
+	return e.Markets.Get(e.ToLocSymbol(symbol))
+}
+
+func (e *BnfExchange) RefreshMarkets() error {
+	return e.Markets.Refresh()
+}
+
+func (e *BnfExchange) SubscribeMarketEvents(ctx context.Context, onEvent func(types.MarketEvent)) {
+	e.Markets.Subscribe(ctx, func(event types.MarketEvent) {
+		event.Symbol, _ = e.Symbols.ToUni(event.LocSymbol)
+		onEvent(event)
+	})
 }
 
 func (e *BnfExchange) getListenKey() (string, error) {
@@ -112,9 +145,10 @@ func (e *BnfExchange) getListenKey() (string, error) {
 // ╚═════════════╝
 
 func (e *BnfExchange) GetMarkPrice(symbol string) (float64, error) {
-	res, err := e.fClient.NewPremiumIndexService().Do(context.Background())
+	symbol = e.ToLocSymbol(symbol)
+	res, err := e.fClient.NewPremiumIndexService().Symbol(symbol).Do(context.Background())
 	if err != nil {
-		return 0, err
+		return 0, fmt.Errorf("fail to get mark price of %v: %w", symbol, err)
 	}
 	for _, price := range res {
 		if price.Symbol == symbol {
@@ -124,6 +158,16 @@ func (e *BnfExchange) GetMarkPrice(symbol string) (float64, error) {
 	return 0, fmt.Errorf("bad symbol: %s", symbol)
 }
 
+// marketOrderPrice returns the mark price the min notional of a market order is checked at,
+// or 0 when there is nothing to check: reduce-only orders & markets without a min notional
+func (e *BnfExchange) marketOrderPrice(symbol string, reduceOnly bool) (float64, error) {
+	m := e.GetMarket(symbol)
+	if reduceOnly || m == nil || m.MinNotional == 0 {
+		return 0, nil
+	}
+	return e.GetMarkPrice(symbol)
+}
+
 func (e *BnfExchange) GetKLines(symbol string, interval types.Interval, window int) ([]types.KLineEvent, error) {
 	symbol = e.ToLocSymbol(symbol)
 	res, err := e.fClient.NewKlinesService().
@@ -151,23 +195,97 @@ func (e *BnfExchange) GetKLines(symbol string, interval types.Interval, window i
 // ╚═════════════╝
 
 func (e *BnfExchange) CancelOrder(symbol string, orderId string, cloId string) error {
-	return fmt.Errorf("not implemented")
+	symbol = e.ToLocSymbol(symbol)
+	service := e.fClient.NewCancelOrderService().Symbol(symbol)
+	if cloId != "" {
+		service = service.OrigClientOrderID(cloId)
+	} else {
+		oId, err := strconv.ParseInt(orderId, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid order id %v: %w", orderId, err)
+		}
+		service = service.OrderID(oId)
+	}
+	if _, err := service.Do(context.Background()); err != nil {
+		return fmt.Errorf("fail to cancel order: %w", err)
+	}
+	return nil
 }
 
 func (e *BnfExchange) CancelBatchOrders(symbol string, orderIds []string) error {
-	return fmt.Errorf("not implemented")
+	if len(orderIds) == 0 {
+		return nil
+	}
+	symbol = e.ToLocSymbol(symbol)
+	oIds := make([]int64, 0, len(orderIds))
+	for _, orderId := range orderIds {
+		oId, err := strconv.ParseInt(orderId, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid order id %v: %w", orderId, err)
+		}
+		oIds = append(oIds, oId)
+	}
+
+	// @dev: BNF cancels at most MAX_BATCH_CANCELS orders per request
+	failed := 0
+	for i := 0; i < len(oIds); i += MAX_BATCH_CANCELS {
+		res, err := e.fClient.NewCancelMultipleOrdersService().
+			Symbol(symbol).
+			OrderIDList(oIds[i:min(i+MAX_BATCH_CANCELS, len(oIds))]).
+			Do(context.Background())
+		if err != nil {
+			return fmt.Errorf("fail to cancel orders: %w", err)
+		}
+		for _, canceled := range res {
+			// failed cancels are returned as an error object without order id
+			if canceled.OrderID == 0 {
+				failed++
+			}
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("fail to cancel %v of %v orders", failed, len(oIds))
+	}
+	return nil
 }
 
 func (e *BnfExchange) CancelAllOrders(symbol string) error {
-	return fmt.Errorf("not implemented")
+	symbol = e.ToLocSymbol(symbol)
+	if err := e.fClient.NewCancelAllOpenOrdersService().Symbol(symbol).Do(context.Background()); err != nil {
+		return fmt.Errorf("fail to cancel all orders: %w", err)
+	}
+	return nil
 }
 
 func (e *BnfExchange) GetPendingOrders(symbol string) ([]order.Order, error) {
-	return nil, fmt.Errorf("not implemented")
+	symbol = e.ToLocSymbol(symbol)
+	res, err := e.fClient.NewListOpenOrdersService().Symbol(symbol).Do(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("fail to get pending orders: %w", err)
+	}
+	return parsePendingOrders(res)
+}
+
+func (e *BnfExchange) GetAllPendingOrders() ([]order.Order, error) {
+	res, err := e.fClient.NewListOpenOrdersService().Do(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("fail to get pending orders: %w", err)
+	}
+	return parsePendingOrders(res)
 }
 
 func (e *BnfExchange) OpenMarketOrder(symbol string, orderSide types.OrderSide, qty float64, lev int, reduceOnly bool) error {
-	// TODO: use e.markets to filter invalid params
+	markPrice, err := e.marketOrderPrice(symbol, reduceOnly)
+	if err != nil {
+		return fmt.Errorf("fail to open market order %v %v: %w", orderSide, symbol, err)
+	}
+	_, qty, err = e.GetMarket(symbol).NormalizeOrder(types.OrderMarket, markPrice, qty, reduceOnly)
+	if err != nil {
+		return fmt.Errorf("fail to open market order %v %v: %w", orderSide, symbol, err)
+	}
+	if err := e.ensureLeverage(symbol, lev); err != nil {
+		return err
+	}
 	symbol = e.ToLocSymbol(symbol)
 	side, err := convertOrderSide(orderSide)
 	if err != nil {
@@ -177,7 +295,7 @@ func (e *BnfExchange) OpenMarketOrder(symbol string, orderSide types.OrderSide,
 		Symbol(symbol).
 		Type(futures.OrderTypeMarket).
 		Side(side).
-		Quantity(fmt.Sprintf("%f", qty)).
+		Quantity(utils.FloatToStr(qty)).
 		ReduceOnly(reduceOnly).
 		Do(context.Background())
 	if err != nil {
@@ -187,9 +305,15 @@ func (e *BnfExchange) OpenMarketOrder(symbol string, orderSide types.OrderSide,
 }
 
 func (e *BnfExchange) OpenLimitOrder(symbol string, orderSide types.OrderSide, price float64, qty float64, lev int, reduceOnly bool, orderTif types.OrderTIF, cloId string) (string, error) {
-	// TODO:
-	// - use e.markets to filter invalid params
-	// - enforce cloId usage
+	// TODO: enforce cloId usage
+	price, qty, err := e.GetMarket(symbol).NormalizeOrder(types.OrderLimit, price, qty, reduceOnly)
+	if err != nil {
+		return "", fmt.Errorf("fail to open limit order %v %v: %w", orderSide, symbol, err)
+	}
+	if err := e.ensureLeverage(symbol, lev); err != nil {
+		return "", err
+	}
+	symbol = e.ToLocSymbol(symbol)
 	side, err := convertOrderSide(orderSide)
 	if err != nil {
 		return "", err
@@ -218,16 +342,164 @@ func (e *BnfExchange) OpenLimitOrder(symbol string, orderSide types.OrderSide, p
 	return oId, nil
 }
 
+// orders failing the market filters or rejected in the batch are logged and skipped, like on HPL;
+// an error is returned only if no order was placed
+// OpenBatchLimitOrders returns one order id per input, "" for the orders that failed, whose errors are joined
 func (e *BnfExchange) OpenBatchLimitOrders(symbol string, inputs []types.LimitOrderInput, lev int) ([]string, error) {
-	return nil, fmt.Errorf("not implemented")
+	if len(inputs) == 0 {
+		return nil, fmt.Errorf("inputs length is 0")
+	}
+	if err := e.ensureLeverage(symbol, lev); err != nil {
+		return nil, err
+	}
+
+	// convert
+	mkt := e.GetMarket(symbol)
+	symbol = e.ToLocSymbol(symbol)
+	oIds := make([]string, len(inputs))
+	errs := []error{}
+	services := make([]*futures.CreateOrderService, 0, len(inputs))
+	serviceInputs := make([]int, 0, len(inputs)) // input index of each service
+	for i, input := range inputs {
+		price, qty, err := mkt.NormalizeOrder(types.OrderLimit, input.Price, input.Qty, input.ReduceOnly)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("order #%v: %w", i+1, err))
+			continue
+		}
+		side, err := convertOrderSide(input.Side)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("order #%v: %w", i+1, err))
+			continue
+		}
+		tif, err := convertOrderTIF(input.Tif)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("order #%v: %w", i+1, err))
+			continue
+		}
+		services = append(services, e.fClient.NewCreateOrderService().
+			Symbol(symbol).
+			Type(futures.OrderTypeLimit).
+			Side(side).
+			Price(utils.FloatToStr(price)).
+			Quantity(utils.FloatToStr(qty)).
+			ReduceOnly(input.ReduceOnly).
+			TimeInForce(tif))
+		serviceInputs = append(serviceInputs, i)
+	}
+
+	// @dev: BNF accepts at most MAX_BATCH_ORDERS orders per request
+	for start := 0; start < len(services); start += MAX_BATCH_ORDERS {
+		end := min(start+MAX_BATCH_ORDERS, len(services))
+		res, err := e.fClient.NewCreateBatchOrdersService().
+			OrderList(services[start:end]).
+			Do(context.Background())
+		if err != nil {
+			for _, i := range serviceInputs[start:end] {
+				errs = append(errs, fmt.Errorf("order #%v: %w", i+1, err))
+			}
+			continue
+		}
+		// @dev: errors are indexed like the request while orders only hold the placed ones, in order
+		placed := 0
+		for j, i := range serviceInputs[start:end] {
+			if j < len(res.Errors) && res.Errors[j] != nil {
+				errs = append(errs, fmt.Errorf("order #%v: %w", i+1, res.Errors[j]))
+				continue
+			}
+			if placed < len(res.Orders) {
+				oIds[i] = strconv.FormatInt(res.Orders[placed].OrderID, 10)
+				placed++
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return oIds, fmt.Errorf("fail to open %v of %v limit orders in batch: %w", len(errs), len(inputs), errors.Join(errs...))
+	}
+	return oIds, nil
+}
+
+// ensureLeverage updates the account leverage of the symbol if it differs from `lev`
+func (e *BnfExchange) ensureLeverage(symbol string, lev int) error {
+	e.leverageMu.Lock()
+	current := e.AccountLeverage[e.ToLocSymbol(symbol)]
+	e.leverageMu.Unlock()
+	if lev <= 0 || current == lev {
+		return nil
+	}
+	return e.UpdateAccountLeverage(symbol, lev)
+}
+
+func (e *BnfExchange) UpdateAccountLeverage(symbol string, lev int) error {
+	symbol = e.ToLocSymbol(symbol)
+	if _, err := e.fClient.NewChangeLeverageService().Symbol(symbol).Leverage(lev).Do(context.Background()); err != nil {
+		return fmt.Errorf("fail to update account leverage for %s to %v: %w", symbol, lev, err)
+	}
+	e.leverageMu.Lock()
+	defer e.leverageMu.Unlock()
+	e.AccountLeverage[symbol] = lev
+	return nil
 }
 
 // ╔═══════════════════╗
 //    OrderMgmtStream
 // ╚═══════════════════╝
 
+// ConnectOrderMgmtStream opens a WS API session to manage orders with low latency;
+// the order updates of the symbol come from the user data stream, closed with the session
 func (e *BnfExchange) ConnectOrderMgmtStream(ctx context.Context, symbol string, onConn func(stream.Stream), onEvent func(stream.Stream, types.OrderEvent), onClose func(stream.Stream)) (stream.Stream, error) {
-	return nil, fmt.Errorf("not implemented")
+	sessionCtx, cancel := context.WithCancel(ctx)
+
+	// connect bnfStream
+	bnfStream, err := NewStream(sessionCtx, types.StreamOrderMgmt, e, e.BnfConfig.WsApiUrl, onConn, func(s stream.Stream) {
+		cancel()
+		if onClose != nil {
+			onClose(s)
+		}
+	})
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	doneC, stopC, err := bnfStream.ConnectAndSubscribe(map[string]string{}, func(msg []byte) {
+		if !bnfStream.handleApiResponse(msg) {
+			log.Debugf("ignore ws api message: %v", string(msg))
+		}
+	})
+	if err != nil {
+		cancel()
+		log.Errorf("fail to connect and subscribe: %v", err)
+		return nil, err
+	}
+
+	// forward the order updates as events of the session
+	_, err = e.subscribeUserDataStream(sessionCtx, types.StreamOrder, symbol, nil, func(_ stream.Stream, evt types.OrderEvent) {
+		if onEvent != nil {
+			onEvent(bnfStream, evt)
+		}
+	}, nil)
+	if err != nil {
+		bnfStream.Close()
+		return nil, fmt.Errorf("fail to subscribe order updates: %w", err)
+	}
+
+	// cache the mark price to check the min notional of market orders without a REST call
+	_, err = e.SubscribeMarkPriceStream(sessionCtx, symbol, nil, func(_ stream.Stream, evt types.MarkPriceEvent) {
+		bnfStream.setMarkPrice(symbol, evt.Price)
+	}, nil, MARK_PRICE_MAX_DELAY)
+	if err != nil {
+		bnfStream.Close()
+		return nil, fmt.Errorf("fail to subscribe mark price: %w", err)
+	}
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			close(stopC)
+		case <-doneC:
+		}
+	}()
+
+	return bnfStream, nil
 }
 
 // ╔══════════════╗
@@ -403,6 +675,11 @@ func (e *BnfExchange) SubscribeBookDepthStream(ctx context.Context, symbol strin
 // ╚═══════════════╝
 
 func (e *BnfExchange) SubscribeOrderStream(ctx context.Context, symbol string, onConn func(stream.Stream), onEvent func(stream.Stream, types.OrderEvent), onClose func(stream.Stream)) (stream.Stream, error) {
+	return e.subscribeUserDataStream(ctx, types.StreamOrder, symbol, onConn, onEvent, onClose)
+}
+
+// subscribeUserDataStream delivers the order updates of the symbol from the user data stream
+func (e *BnfExchange) subscribeUserDataStream(ctx context.Context, streamName types.Stream, symbol string, onConn func(stream.Stream), onEvent func(stream.Stream, types.OrderEvent), onClose func(stream.Stream)) (stream.Stream, error) {
 	symbol = e.ToLocSymbol(symbol)
 	listenKey, err := e.getListenKey()
 	if err != nil {
@@ -411,7 +688,7 @@ func (e *BnfExchange) SubscribeOrderStream(ctx context.Context, symbol string, o
 	bnfWsEndpoint := fmt.Sprintf("%s/%s", e.BnfConfig.WsUrl, listenKey)
 
 	// connect bnfStream
-	bnfStream, err := NewStream(ctx, types.StreamOrder, e, bnfWsEndpoint, onConn, onClose)
+	bnfStream, err := NewStream(ctx, streamName, e, bnfWsEndpoint, onConn, onClose)
 	if err != nil {
 		return nil, err
 	}
@@ -440,11 +717,22 @@ func (e *BnfExchange) SubscribeOrderStream(ctx context.Context, symbol string, o
 		return nil, err
 	}
 
+	// keep the listen key alive until the stream is stopped
+	renewTicker := time.NewTicker(LISTEN_KEY_KEEPALIVE)
 	go func() {
-		select {
-		case <-ctx.Done():
-			close(stopC)
-		case <-doneC:
+		defer renewTicker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				close(stopC)
+				return
+			case <-doneC:
+				return
+			case <-renewTicker.C:
+				if err := e.fClient.NewKeepaliveUserStreamService().ListenKey(listenKey).Do(context.Background()); err != nil {
+					log.Warnf("fail to keep listen key alive: %v", err)
+				}
+			}
 		}
 	}()
 
@@ -452,41 +740,61 @@ func (e *BnfExchange) SubscribeOrderStream(ctx context.Context, symbol string, o
 }
 
 func (e *BnfExchange) ToUniSymbol(locSymbol string) string {
-	if locSymbol, ok := e.SymbolMapL2U[locSymbol]; ok {
-		return locSymbol
+	if uniSymbol, ok := e.Symbols.ToUni(locSymbol); ok {
+		return uniSymbol
 	}
 	log.Fatalf("fail to convert local symbol to universal symbol: %v", locSymbol)
 	return ""
 }
 
+func (e *BnfExchange) HasSymbol(uniSymbol string) bool {
+	_, ok := e.Symbols.ToLoc(uniSymbol)
+	return ok
+}
+
 func (e *BnfExchange) ToLocSymbol(uniSymbol string) string {
-	if locSymbol, ok := e.SymbolMapU2L[uniSymbol]; ok {
+	if locSymbol, ok := e.Symbols.ToLoc(uniSymbol); ok {
 		return locSymbol
 	}
 	log.Fatalf("fail to convert universal symbol to local symbol: %v", uniSymbol)
 	return ""
 }
 
+// @dev: margin balance (wallet balance + unrealized PnL) of the USDⓈ-M account, as HPL account value
 func (e *BnfExchange) GetAccountBalance() (float64, error) {
-	return 0, fmt.Errorf("not implemented")
+	account, err := e.fClient.NewGetAccountService().Do(context.Background())
+	if err != nil {
+		return 0, fmt.Errorf("fail to get account balance: %w", err)
+	}
+	return utils.StrToFloat(account.TotalMarginBalance)
 }
 
 func (e *BnfExchange) GetActivePositionByMarket(symbol string) ([]types.Position, error) {
-	symbol = e.ToLocSymbol(symbol)
+	positions, err := e.GetActivePositions()
+	if err != nil {
+		return nil, err
+	}
+	return positions[symbol], nil
+}
+
+func (e *BnfExchange) GetActivePositions() (map[string][]types.Position, error) {
 	account, err := e.fClient.NewGetAccountService().Do(context.Background())
 	if err != nil {
 		return nil, fmt.Errorf("fail to get active positions: %w", err)
 	}
 
-	var positions []types.Position
+	positions := make(map[string][]types.Position)
 	for _, pos := range account.Positions {
-		if pos.Symbol != symbol || pos.PositionAmt == "0" {
+		if pos.PositionAmt == "0" {
 			continue
 		}
 		qty, err := utils.StrToFloat(pos.PositionAmt)
 		if err != nil {
 			return nil, fmt.Errorf("fail to convert position qty: %v", err)
 		}
+		if qty == 0 {
+			continue
+		}
 		entryPrice, err := utils.StrToFloat(pos.EntryPrice)
 		if err != nil {
 			return nil, fmt.Errorf("fail to convert entry price: %v", err)
@@ -495,7 +803,12 @@ func (e *BnfExchange) GetActivePositionByMarket(symbol string) ([]types.Position
 		if qty < 0 {
 			posSide = types.OrderSideSell
 		}
-		positions = append(positions, types.Position{
+		// @dev: a position on a market missing from the symbol map is kept under its local symbol
+		symbol, ok := e.Symbols.ToUni(pos.Symbol)
+		if !ok {
+			symbol = pos.Symbol
+		}
+		positions[symbol] = append(positions[symbol], types.Position{
 			Qty:        qty,
 			EntryPrice: entryPrice,
 			Side:       posSide,
@@ -504,6 +817,52 @@ func (e *BnfExchange) GetActivePositionByMarket(symbol string) ([]types.Position
 	return positions, nil
 }
 
+// GetRealizedPnL sums the realized PnL of the account since `since` from the income history
+func (e *BnfExchange) GetRealizedPnL(since time.Time) (float64, error) {
+	realizedPnL := 0.0
+	startTime := since.UnixMilli()
+	for {
+		incomes, err := e.fClient.NewGetIncomeHistoryService().
+			IncomeType("REALIZED_PNL").
+			StartTime(startTime).
+			Limit(INCOME_HISTORY_LIMIT).
+			Do(context.Background())
+		if err != nil {
+			return 0, fmt.Errorf("fail to get realized PnL: %w", err)
+		}
+		for _, income := range incomes {
+			pnl, err := utils.StrToFloat(income.Income)
+			if err != nil {
+				return 0, fmt.Errorf("fail to convert realized PnL: %v", err)
+			}
+			realizedPnL += pnl
+		}
+		// @dev: the history is sorted by time, the next page starts after the last income
+		if len(incomes) < INCOME_HISTORY_LIMIT {
+			return realizedPnL, nil
+		}
+		startTime = incomes[len(incomes)-1].Time + 1
+	}
+}
+
 func (e *BnfExchange) CloseActivePositionByMarket(symbol string, lev int) error {
-	return fmt.Errorf("not implemented")
+	positions, err := e.GetActivePositionByMarket(symbol)
+	if err != nil {
+		return err
+	}
+	for _, position := range positions {
+		// BNF position qty is signed: negative for a short
+		qty := math.Abs(position.Qty)
+		if qty == 0 {
+			continue
+		}
+		side := types.OrderSideSell
+		if position.Side == types.OrderSideSell {
+			side = types.OrderSideBuy
+		}
+		if err := e.OpenMarketOrder(symbol, side, qty, lev, true); err != nil {
+			return err
+		}
+	}
+	return nil
 }
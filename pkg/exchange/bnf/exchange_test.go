@@ -0,0 +1,119 @@
+package bnf
+
+import (
+	"encoding/json"
+	"errors"
+	"lfg/pkg/market"
+	"lfg/pkg/types"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/adshao/go-binance/v2/futures"
+)
+
+func newTestExchange(t *testing.T, handler http.HandlerFunc) *BnfExchange {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	fClient := futures.NewClient("key", "secret")
+	fClient.BaseURL = server.URL
+	markets, err := market.NewRegistry(types.ExchangeBnf, func() (map[string]*market.Market, error) {
+		return map[string]*market.Market{"BTCUSDT": {
+			Symbol:      "BTCUSDT",
+			TickSize:    0.1,
+			LotMinQty:   0.001,
+			LotStepSize: 0.001,
+			MinNotional: 5,
+		}}, nil
+	})
+	if err != nil {
+		t.Fatalf("fail to load markets: %v", err)
+	}
+	return &BnfExchange{
+		fClient:         fClient,
+		Markets:         markets,
+		Symbols:         market.NewSymbolMap(map[string]string{"BTC_USD": "BTCUSDT"}),
+		AccountLeverage: map[string]int{"BTCUSDT": 5},
+	}
+}
+
+func TestOpenBatchLimitOrdersPartialFailure(t *testing.T) {
+	exchg := newTestExchange(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/fapi/v1/batchOrders" {
+			t.Errorf("unexpected request %v", r.URL.Path)
+			http.NotFound(w, r)
+			return
+		}
+		orders := []map[string]string{}
+		if err := json.Unmarshal([]byte(r.FormValue("batchOrders")), &orders); err != nil {
+			t.Errorf("fail to decode batch: %v", err)
+		}
+		// the order at 90 USD is rejected
+		res := []map[string]any{}
+		for i, o := range orders {
+			if o["price"] == "90" {
+				res = append(res, map[string]any{"code": -2019, "msg": "Margin is insufficient."})
+				continue
+			}
+			res = append(res, map[string]any{"orderId": 11 + i, "symbol": "BTCUSDT", "price": o["price"]})
+		}
+		json.NewEncoder(w).Encode(res)
+	})
+
+	oIds, err := exchg.OpenBatchLimitOrders("BTC_USD", []types.LimitOrderInput{
+		{Side: types.OrderSideBuy, Price: 100, Qty: 1, Tif: types.OrderTIFGTC},
+		{Side: types.OrderSideBuy, Price: 100, Qty: 0.01, Tif: types.OrderTIFGTC}, // below min notional
+		{Side: types.OrderSideBuy, Price: 90, Qty: 1, Tif: types.OrderTIFGTC},
+		{Side: types.OrderSideBuy, Price: 80, Qty: 1, Tif: types.OrderTIFGTC},
+	}, 5)
+
+	want := []string{"11", "", "", "13"}
+	if len(oIds) != len(want) {
+		t.Fatalf("order ids = %q, want %q", oIds, want)
+	}
+	for i := range want {
+		if oIds[i] != want[i] {
+			t.Fatalf("order ids = %q, want %q", oIds, want)
+		}
+	}
+	if err == nil || !strings.Contains(err.Error(), "order #2") || !strings.Contains(err.Error(), "order #3: <APIError> code=-2019") {
+		t.Fatalf("error = %v, want the errors of orders #2 & #3", err)
+	}
+}
+
+func TestOpenMarketOrderMarkPrice(t *testing.T) {
+	markPriceRequests := 0
+	exchg := newTestExchange(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/fapi/v1/premiumIndex":
+			markPriceRequests++
+			json.NewEncoder(w).Encode([]map[string]any{{"symbol": "BTCUSDT", "markPrice": "100"}})
+		case "/fapi/v1/order":
+			json.NewEncoder(w).Encode(map[string]any{"orderId": 1, "symbol": "BTCUSDT"})
+		default:
+			t.Errorf("unexpected request %v", r.URL.Path)
+			http.NotFound(w, r)
+		}
+	})
+
+	// 1 USD is below the min notional
+	if err := exchg.OpenMarketOrder("BTC_USD", types.OrderSideBuy, 0.01, 5, false); !errors.Is(err, market.ErrMinNotional) {
+		t.Fatalf("error = %v, want the min notional", err)
+	}
+	if markPriceRequests != 1 {
+		t.Fatalf("mark price requests = %v, want 1", markPriceRequests)
+	}
+
+	// nothing to check: no mark price request
+	if err := exchg.OpenMarketOrder("BTC_USD", types.OrderSideSell, 0.01, 5, true); err != nil {
+		t.Fatalf("reduce-only order: %v", err)
+	}
+	exchg.GetMarket("BTC_USD").MinNotional = 0
+	if err := exchg.OpenMarketOrder("BTC_USD", types.OrderSideBuy, 0.01, 5, false); err != nil {
+		t.Fatalf("order on a market without min notional: %v", err)
+	}
+	if markPriceRequests != 1 {
+		t.Fatalf("mark price requests = %v, want 1", markPriceRequests)
+	}
+}
@@ -3,8 +3,11 @@ package bnf
 import (
 	"encoding/json"
 	"fmt"
+	"lfg/pkg/order"
 	"lfg/pkg/types"
 	"lfg/pkg/utils"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/adshao/go-binance/v2/futures"
@@ -241,3 +244,43 @@ func ParseKLines(bnfKLines []*futures.Kline, symbol string) ([]types.KLineEvent,
 	}
 	return kLines, nil
 }
+
+func parsePendingOrders(res []*futures.Order) ([]order.Order, error) {
+	orders := make([]order.Order, 0, len(res))
+	for _, pendingOrder := range res {
+		order, err := parsePendingOrder(pendingOrder)
+		if err != nil {
+			return nil, err
+		}
+		orders = append(orders, order)
+	}
+	return orders, nil
+}
+
+func parsePendingOrder(o *futures.Order) (order.Order, error) {
+	price, err := utils.StrToFloat(o.Price)
+	if err != nil {
+		return order.Order{}, err
+	}
+	origQty, err := utils.StrToFloat(o.OrigQuantity)
+	if err != nil {
+		return order.Order{}, err
+	}
+	executedQty, err := utils.StrToFloat(o.ExecutedQuantity)
+	if err != nil {
+		return order.Order{}, err
+	}
+	orderType := types.OrderLimit
+	if o.Type == futures.OrderTypeMarket {
+		orderType = types.OrderMarket
+	}
+	return order.Order{
+		Id:           strconv.FormatInt(o.OrderID, 10),
+		Symbol:       o.Symbol,
+		OrderType:    orderType,
+		OrderSide:    types.OrderSide(strings.ToLower(string(o.Side))),
+		Price:        price,
+		OriginalQty:  origQty,
+		RemainingQty: origQty - executedQty,
+	}, nil
+}
@@ -0,0 +1,41 @@
+package bnf
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+var requestIdCounter int64
+
+// ids correlate WS API responses with their request
+func getRequestId() int64 {
+	return atomic.AddInt64(&requestIdCounter, 1)
+}
+
+// signWsApiParams adds the api key, timestamp and HMAC SHA256 signature to the params of a WS API request
+// ref: https://developers.binance.com/docs/derivatives/usds-margined-futures/websocket-api-general-info
+func (e *BnfExchange) signWsApiParams(params map[string]string) map[string]string {
+	params["apiKey"] = e.fClient.APIKey
+	params["timestamp"] = strconv.FormatInt(time.Now().UnixMilli()-e.fClient.TimeOffset, 10)
+
+	// payload: params sorted by name as a query string
+	keys := make([]string, 0, len(params))
+	for key := range params {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	pairs := make([]string, 0, len(keys))
+	for _, key := range keys {
+		pairs = append(pairs, key+"="+params[key])
+	}
+	mac := hmac.New(sha256.New, []byte(e.fClient.SecretKey))
+	mac.Write([]byte(strings.Join(pairs, "&")))
+	params["signature"] = hex.EncodeToString(mac.Sum(nil))
+	return params
+}
@@ -2,14 +2,18 @@ package bnf
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"lfg/pkg/order"
 	"lfg/pkg/stream"
 	"lfg/pkg/types"
+	"lfg/pkg/utils"
 	"net/url"
+	"strconv"
 	"sync"
 	"time"
 
+	"github.com/adshao/go-binance/v2/futures"
 	"github.com/gorilla/websocket"
 	log "github.com/sirupsen/logrus"
 )
@@ -37,7 +41,11 @@ type BnfStream struct {
 	onConn  func(stream.Stream)
 	onClose func(stream.Stream)
 
-	mu     sync.Mutex
+	// response handlers map
+	apiResponseHandlers map[int64]chan wsApiResponse // handle WS API responses (order mgmt session only)
+	markPrices          map[string]float64           // universal symbol -> latest mark price (order mgmt session only)
+
+	mu     sync.Mutex // guards conn & the connection state, held while writing to conn
 	logger *log.Entry
 }
 
@@ -61,11 +69,84 @@ func NewStream(ctx context.Context, streamName types.Stream, bnfExchg *BnfExchan
 			"stream":  wsUrl,
 			"name":    streamName,
 		}),
-		onConn:  onConn,
-		onClose: onClose,
+		onConn:              onConn,
+		onClose:             onClose,
+		apiResponseHandlers: make(map[int64]chan wsApiResponse),
+		markPrices:          make(map[string]float64),
 	}, nil
 }
 
+func (sm *BnfStream) registerApiResponseHandler(id int64, respChan chan wsApiResponse) {
+	sm.mu.Lock()
+	sm.apiResponseHandlers[id] = respChan
+	sm.mu.Unlock()
+}
+
+func (sm *BnfStream) cleanupApiResponseHandler(id int64) {
+	sm.mu.Lock()
+	delete(sm.apiResponseHandlers, id)
+	sm.mu.Unlock()
+}
+
+func (sm *BnfStream) setMarkPrice(symbol string, price float64) {
+	sm.mu.Lock()
+	sm.markPrices[symbol] = price
+	sm.mu.Unlock()
+}
+
+// getMarkPrice returns the latest streamed mark price of the symbol, 0 if none was received
+func (sm *BnfStream) getMarkPrice(symbol string) float64 {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	return sm.markPrices[symbol]
+}
+
+// handleApiResponse passes a WS API response to its waiting request; false if the message is not a response
+func (sm *BnfStream) handleApiResponse(msg []byte) bool {
+	var res wsApiResponse
+	if err := json.Unmarshal(msg, &res); err != nil || res.Id == 0 {
+		return false
+	}
+	sm.mu.Lock()
+	ch, exists := sm.apiResponseHandlers[res.Id]
+	sm.mu.Unlock()
+	if exists {
+		// @dev: buffered, never blocks if the request already timed out
+		select {
+		case ch <- res:
+		default:
+		}
+	}
+	return true
+}
+
+// failApiResponseHandlers fails every pending WS API request, their responses are lost with the connection
+// @dev: must be called with sm.mu held
+func (sm *BnfStream) failApiResponseHandlers(err error) {
+	for id, ch := range sm.apiResponseHandlers {
+		select {
+		case ch <- wsApiResponse{Id: id, err: err}:
+		default:
+		}
+		delete(sm.apiResponseHandlers, id)
+	}
+}
+
+func (sm *BnfStream) writeMessage(messageType int, data []byte) error {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	if sm.isDisconnected {
+		return fmt.Errorf("websocket disconnected")
+	}
+	return sm.conn.WriteMessage(messageType, data)
+}
+
+func (sm *BnfStream) getConn() *websocket.Conn {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	return sm.conn
+}
+
 func (sm *BnfStream) ConnectAndSubscribe(_ map[string]string, onEvent func(e []byte)) (doneC chan struct{}, stopC chan struct{}, err error) {
 	// connect
 	err = sm.connect()
@@ -94,13 +175,18 @@ func (sm *BnfStream) connect() error {
 		sm.logger.Errorf("fail to connect stream: %v", err)
 		return err
 	}
+	if sm.isClosed {
+		c.Close()
+		return fmt.Errorf("stream already closed")
+	}
 	sm.conn = c
+	sm.isDisconnected = false
 
 	// keep stream connection alive: Binance pings every 3m, respond with matching pong payload
 	// ref: https://binance-docs.github.io/apidocs/futures/en/#websocket-market-streams
-	sm.conn.SetPingHandler(func(msg string) error {
+	c.SetPingHandler(func(msg string) error {
 		sm.logger.Debugf("received ping, sending pong: %s", msg)
-		err := sm.conn.WriteControl(websocket.PongMessage, []byte(msg), time.Now().Add(HS_TIMEOUT_S*time.Second))
+		err := c.WriteControl(websocket.PongMessage, []byte(msg), time.Now().Add(HS_TIMEOUT_S*time.Second))
 		if err != nil {
 			sm.logger.Warnf("fail to send pong: %v", err)
 			return nil // intentionally return nil even err to prevent connection teardown
@@ -130,16 +216,15 @@ func (sm *BnfStream) handleReconnect() {
 				continue
 			}
 			sm.logger.Info("reconnect and resubscribe stream success")
-			sm.mu.Lock()
-			sm.isDisconnected = false
-			sm.mu.Unlock()
 			return
 		}
 	}
 }
 
 func (sm *BnfStream) subscribe(onEvent func(e []byte)) {
+	sm.mu.Lock()
 	sm.isDisconnected = false
+	sm.mu.Unlock()
 
 	for {
 		select {
@@ -152,7 +237,7 @@ func (sm *BnfStream) subscribe(onEvent func(e []byte)) {
 			if sm.IsClosed() {
 				return
 			}
-			_, msg, err := sm.conn.ReadMessage()
+			_, msg, err := sm.getConn().ReadMessage()
 			if err != nil {
 				sm.logger.Errorf("fail to read stream message (trying to reconnect): %v", err)
 				sm.handleReconnect()
@@ -188,36 +273,219 @@ func (sm *BnfStream) autoReset() {
 }
 
 // ╔══════════════════════════╗
-//
-//	Websocket write function
-//
+//   Websocket write function
 // ╚══════════════════════════╝
+
+// request sends a signed WS API request and waits for its result
+func (sm *BnfStream) request(method string, params map[string]string) (json.RawMessage, error) {
+	if sm.IsClosed() {
+		return nil, fmt.Errorf("fail to send %v: websocket already closed", method)
+	}
+	req := wsApiRequest{
+		Id:     getRequestId(),
+		Method: method,
+		Params: sm.exchange.signWsApiParams(params),
+	}
+
+	// marshall to JSON
+	reqBody, err := json.Marshal(req)
+	if err != nil {
+		sm.logger.Errorf("fail to marshal %v: %v", method, err)
+		return nil, err
+	}
+
+	// prepare responseHandler channel and cleanup
+	respChan := make(chan wsApiResponse, 1)
+	sm.registerApiResponseHandler(req.Id, respChan)
+	defer sm.cleanupApiResponseHandler(req.Id)
+
+	// write ws
+	if err := sm.writeMessage(websocket.TextMessage, reqBody); err != nil {
+		sm.logger.Errorf("fail to send %v: %v", method, err)
+		return nil, err
+	}
+
+	// wait for response with timeout
+	select {
+	case resp := <-respChan:
+		if resp.err != nil {
+			return nil, fmt.Errorf("fail to receive %v response: %w", method, resp.err)
+		}
+		if resp.Error != nil {
+			return nil, fmt.Errorf("server returned error: %v: %v", resp.Error.Code, resp.Error.Msg)
+		}
+		if resp.Status != 200 {
+			return nil, fmt.Errorf("server returned status %v", resp.Status)
+		}
+		return resp.Result, nil
+	case <-time.After(time.Duration(HS_TIMEOUT_S) * time.Second):
+		return nil, fmt.Errorf("timeout waiting for response")
+	}
+}
+
+func (sm *BnfStream) placeOrder(params map[string]string) (string, error) {
+	result, err := sm.request("order.place", params)
+	if err != nil {
+		return "", err
+	}
+	var res wsApiOrder
+	if err := json.Unmarshal(result, &res); err != nil {
+		return "", fmt.Errorf("failed to parse response: %v", err)
+	}
+	if res.OrderId == 0 {
+		return "", fmt.Errorf("oId is missing from the response")
+	}
+	return strconv.FormatInt(res.OrderId, 10), nil
+}
+
+func (sm *BnfStream) limitOrderParams(symbol string, orderSide types.OrderSide, price float64, qty float64, reduceOnly bool, orderTif types.OrderTIF) (map[string]string, error) {
+	price, qty, err := sm.exchange.GetMarket(symbol).NormalizeOrder(types.OrderLimit, price, qty, reduceOnly)
+	if err != nil {
+		return nil, err
+	}
+	side, err := convertOrderSide(orderSide)
+	if err != nil {
+		return nil, err
+	}
+	tif, err := convertOrderTIF(orderTif)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]string{
+		"symbol":      sm.exchange.ToLocSymbol(symbol),
+		"side":        string(side),
+		"type":        string(futures.OrderTypeLimit),
+		"timeInForce": string(tif),
+		"price":       utils.FloatToStr(price),
+		"quantity":    utils.FloatToStr(qty),
+		"reduceOnly":  strconv.FormatBool(reduceOnly),
+	}, nil
+}
+
 func (sm *BnfStream) OpenLimitOrder(symbol string, orderSide types.OrderSide, price float64, qty float64, lev int, reduceOnly bool, orderTif types.OrderTIF, cloId string) (string, error) {
-	return "", fmt.Errorf("not implemented")
+	params, err := sm.limitOrderParams(symbol, orderSide, price, qty, reduceOnly, orderTif)
+	if err != nil {
+		return "", fmt.Errorf("fail to open limit order %v %v: %w", orderSide, symbol, err)
+	}
+	if err := sm.exchange.ensureLeverage(symbol, lev); err != nil {
+		return "", err
+	}
+	if cloId != "" {
+		params["newClientOrderId"] = cloId
+	}
+	oId, err := sm.placeOrder(params)
+	if err != nil {
+		return "", fmt.Errorf("fail to open limit order %v %v %v at price %v: %w", orderSide, qty, symbol, price, err)
+	}
+	return oId, nil
 }
 
+// @dev: the WS API has no batch endpoint, orders are placed one by one; failed orders are
+// logged and the error is returned once the whole batch was sent
 func (sm *BnfStream) OpenBatchLimitOrders(symbol string, inputs []types.LimitOrderInput, lev int) error {
-	return fmt.Errorf("not implemented")
+	if len(inputs) == 0 {
+		return fmt.Errorf("inputs length is 0")
+	}
+	failed := 0
+	for _, input := range inputs {
+		if _, err := sm.OpenLimitOrder(symbol, input.Side, input.Price, input.Qty, lev, input.ReduceOnly, input.Tif, ""); err != nil {
+			sm.logger.Warnf("fail to open some limit order in batch: %v", err)
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("fail to open %v of %v limit orders in batch", failed, len(inputs))
+	}
+	return nil
 }
 
+// @dev: BNF modifies the price & qty of a limit order only; side must match the order and
+// lev, reduceOnly & tif are kept from the original order
 func (sm *BnfStream) ModifyOrder(symbol string, oId string, cloId string, orderSide types.OrderSide, price float64, qty float64, lev int, reduceOnly bool, orderTif types.OrderTIF) error {
-	return fmt.Errorf("not implemented")
+	price, qty, err := sm.exchange.GetMarket(symbol).NormalizeOrder(types.OrderLimit, price, qty, reduceOnly)
+	if err != nil {
+		return fmt.Errorf("fail to modify order %v of %v: %w", oId+cloId, symbol, err)
+	}
+	side, err := convertOrderSide(orderSide)
+	if err != nil {
+		return err
+	}
+	params := map[string]string{
+		"symbol":   sm.exchange.ToLocSymbol(symbol),
+		"side":     string(side),
+		"price":    utils.FloatToStr(price),
+		"quantity": utils.FloatToStr(qty),
+	}
+	if cloId != "" {
+		params["origClientOrderId"] = cloId
+	} else {
+		params["orderId"] = oId
+	}
+	if _, err := sm.request("order.modify", params); err != nil {
+		return fmt.Errorf("fail to modify order %v of %v: %w", oId+cloId, symbol, err)
+	}
+	return nil
 }
 
+// @dev: the WS API cannot list open orders, they are fetched through REST
 func (sm *BnfStream) GetPendingOrders(symbol string) ([]order.Order, error) {
-	return nil, fmt.Errorf("not implemented")
+	return sm.exchange.GetPendingOrders(symbol)
 }
 
-func (sm *BnfStream) OpenMarketOrder(symbol string, side types.OrderSide, qty float64, lev int, reduceOnly bool) error {
-	return fmt.Errorf("not implemented")
+func (sm *BnfStream) OpenMarketOrder(symbol string, orderSide types.OrderSide, qty float64, lev int, reduceOnly bool) error {
+	// @dev: the min notional is checked at the streamed mark price, left to the exchange until the first price
+	_, qty, err := sm.exchange.GetMarket(symbol).NormalizeOrder(types.OrderMarket, sm.getMarkPrice(symbol), qty, reduceOnly)
+	if err != nil {
+		return fmt.Errorf("fail to open market order %v %v: %w", orderSide, symbol, err)
+	}
+	if err := sm.exchange.ensureLeverage(symbol, lev); err != nil {
+		return err
+	}
+	side, err := convertOrderSide(orderSide)
+	if err != nil {
+		return err
+	}
+	_, err = sm.placeOrder(map[string]string{
+		"symbol":     sm.exchange.ToLocSymbol(symbol),
+		"side":       string(side),
+		"type":       string(futures.OrderTypeMarket),
+		"quantity":   utils.FloatToStr(qty),
+		"reduceOnly": strconv.FormatBool(reduceOnly),
+	})
+	if err != nil {
+		return fmt.Errorf("fail to open market order %v %v %v: %w", orderSide, qty, symbol, err)
+	}
+	return nil
 }
 
 func (sm *BnfStream) CancelOrder(symbol string, orderId string, cloId string) error {
-	return fmt.Errorf("not implemented")
+	params := map[string]string{
+		"symbol": sm.exchange.ToLocSymbol(symbol),
+	}
+	if cloId != "" {
+		params["origClientOrderId"] = cloId
+	} else {
+		params["orderId"] = orderId
+	}
+	if _, err := sm.request("order.cancel", params); err != nil {
+		return fmt.Errorf("fail to cancel order %v: oId %v: %w", symbol, orderId+cloId, err)
+	}
+	return nil
 }
 
+// @dev: the WS API has no batch endpoint, orders are canceled one by one
 func (sm *BnfStream) CancelBatchOrders(symbol string, orderIds []string) error {
-	return fmt.Errorf("not implemented")
+	failed := 0
+	for _, orderId := range orderIds {
+		if err := sm.CancelOrder(symbol, orderId, ""); err != nil {
+			sm.logger.Warnf("fail to cancel some order in batch: %v", err)
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("fail to cancel %v of %v orders in batch", failed, len(orderIds))
+	}
+	return nil
 }
 
 // Close() is the final function to be called; the stream cannot be reopened afterward
@@ -232,13 +500,15 @@ func (sm *BnfStream) Close() {
 	if sm.onClose != nil {
 		sm.onClose(sm)
 	}
-	// close the websocket connection
-	err := sm.conn.Close()
-	if err != nil {
-		sm.logger.Fatalf("fail to close stream: %v", err)
+	// close the websocket connection, unless already closed by a disconnection
+	if !sm.isDisconnected {
+		if err := sm.conn.Close(); err != nil {
+			sm.logger.Fatalf("fail to close stream: %v", err)
+		}
 	}
 	sm.isDisconnected = true
 	sm.isClosed = true
+	sm.failApiResponseHandlers(fmt.Errorf("websocket closed"))
 
 	select {
 	case <-sm.doneC:
@@ -259,6 +529,7 @@ func (sm *BnfStream) forceDisconnect() {
 
 	sm.conn.Close()
 	sm.isDisconnected = true
+	sm.failApiResponseHandlers(fmt.Errorf("websocket connection lost"))
 }
 
 func (sm *BnfStream) IsDisconnected() bool {
@@ -0,0 +1,167 @@
+package bnf
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"lfg/pkg/market"
+	"lfg/pkg/types"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/adshao/go-binance/v2/futures"
+	"github.com/gorilla/websocket"
+)
+
+// serveWsApi answers the WS API requests in batches of `batch`, in reverse order of arrival;
+// the requests canceling order "reject" fail
+func serveWsApi(t *testing.T, batch int) *httptest.Server {
+	upgrader := websocket.Upgrader{}
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Errorf("fail to upgrade: %v", err)
+			return
+		}
+		defer conn.Close()
+		for {
+			reqs := []wsApiRequest{}
+			for len(reqs) < batch {
+				var req wsApiRequest
+				if err := conn.ReadJSON(&req); err != nil {
+					return
+				}
+				reqs = append(reqs, req)
+			}
+			for i := len(reqs) - 1; i >= 0; i-- {
+				req := reqs[i]
+				res := map[string]any{"id": req.Id, "status": 200, "result": map[string]any{"orderId": req.Params["orderId"]}}
+				if req.Params["orderId"] == "reject" {
+					res = map[string]any{"id": req.Id, "status": 400, "error": map[string]any{"code": -2011, "msg": "Unknown order sent."}}
+				}
+				if req.Params["signature"] == "" || req.Params["apiKey"] != "key" {
+					res = map[string]any{"id": req.Id, "status": 401, "error": map[string]any{"code": -1022, "msg": "Signature for this request is not valid."}}
+				}
+				if err := conn.WriteJSON(res); err != nil {
+					return
+				}
+			}
+		}
+	}))
+}
+
+func connectTestStream(t *testing.T, server *httptest.Server) *BnfStream {
+	exchg := &BnfExchange{fClient: futures.NewClient("key", "secret")}
+	wsUrl := "ws" + strings.TrimPrefix(server.URL, "http")
+	sm, err := NewStream(context.Background(), "test", exchg, wsUrl, nil, nil)
+	if err != nil {
+		t.Fatalf("fail to create stream: %v", err)
+	}
+	if _, _, err := sm.ConnectAndSubscribe(nil, func(msg []byte) { sm.handleApiResponse(msg) }); err != nil {
+		t.Fatalf("fail to connect stream: %v", err)
+	}
+	t.Cleanup(sm.Close)
+	return sm
+}
+
+func TestRequestCorrelation(t *testing.T) {
+	const requests = 8
+	server := serveWsApi(t, requests)
+	defer server.Close()
+	sm := connectTestStream(t, server)
+
+	// the responses arrive in reverse order, each request must get its own
+	var wg sync.WaitGroup
+	errs := make(chan string, requests)
+	for i := 0; i < requests; i++ {
+		orderId := string(rune('a' + i))
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result, err := sm.request("order.cancel", map[string]string{"symbol": "BTCUSDT", "orderId": orderId})
+			if err != nil {
+				errs <- orderId + ": " + err.Error()
+				return
+			}
+			var res struct {
+				OrderId string `json:"orderId"`
+			}
+			if err := json.Unmarshal(result, &res); err != nil || res.OrderId != orderId {
+				errs <- orderId + ": got the response of " + res.OrderId
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+}
+
+func TestRequestError(t *testing.T) {
+	server := serveWsApi(t, 1)
+	defer server.Close()
+	sm := connectTestStream(t, server)
+
+	_, err := sm.request("order.cancel", map[string]string{"symbol": "BTCUSDT", "orderId": "reject"})
+	if err == nil || !strings.Contains(err.Error(), "-2011") {
+		t.Fatalf("error = %v, want the server error", err)
+	}
+
+	// late responses of other ids are ignored
+	if !sm.handleApiResponse([]byte(`{"id": 123456789, "status": 200, "result": {}}`)) {
+		t.Fatalf("response of an unknown request not handled")
+	}
+	if sm.handleApiResponse([]byte(`{"e": "ORDER_TRADE_UPDATE"}`)) {
+		t.Fatalf("event handled as a response")
+	}
+}
+
+func TestRequestConnectionLost(t *testing.T) {
+	// the server drops the connection instead of answering
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Errorf("fail to upgrade: %v", err)
+			return
+		}
+		conn.ReadMessage()
+		conn.Close()
+	}))
+	defer server.Close()
+	sm := connectTestStream(t, server)
+
+	start := time.Now()
+	_, err := sm.request("order.cancel", map[string]string{"symbol": "BTCUSDT", "orderId": "a"})
+	if err == nil || !strings.Contains(err.Error(), "connection lost") {
+		t.Fatalf("error = %v, want the connection lost", err)
+	}
+	if elapsed := time.Since(start); elapsed >= HS_TIMEOUT_S*time.Second {
+		t.Fatalf("request failed after %v, want before the response timeout", elapsed)
+	}
+}
+
+func TestStreamMarketOrderMarkPrice(t *testing.T) {
+	exchg := newTestExchange(t, func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request %v", r.URL.Path)
+		http.NotFound(w, r)
+	})
+	sm, err := NewStream(context.Background(), types.StreamOrderMgmt, exchg, "ws://unused", nil, nil)
+	if err != nil {
+		t.Fatalf("fail to create stream: %v", err)
+	}
+
+	// the min notional is checked at the streamed mark price, without a REST call
+	sm.setMarkPrice("BTC_USD", 100)
+	if err := sm.OpenMarketOrder("BTC_USD", types.OrderSideBuy, 0.01, 5, false); !errors.Is(err, market.ErrMinNotional) {
+		t.Fatalf("error = %v, want the min notional", err)
+	}
+	if price := sm.getMarkPrice("ETH_USD"); price != 0 {
+		t.Fatalf("mark price of a symbol not streamed = %v, want 0", price)
+	}
+}
@@ -1,11 +1,15 @@
 package bnf
 
+import "encoding/json"
+
 type bnfConfig struct {
-	WsUrl string `json:"wsUrl"`
+	WsUrl    string `json:"wsUrl"`
+	WsApiUrl string `json:"wsApiUrl"` // order management session
 }
 
 type bnfMarketFilter struct {
 	symbol            string
+	tickSize          float64
 	minNotional       float64
 	lotMinQty         float64
 	lotMaxQty         float64
@@ -30,3 +34,33 @@ type wsDepthEvent struct {
 	Bids             [][]string `json:"b"`
 	Asks             [][]string `json:"a"`
 }
+
+// ╔══════════════╗
+//      WS API
+// ╚══════════════╝
+
+type wsApiRequest struct {
+	Id     int64             `json:"id"`
+	Method string            `json:"method"`
+	Params map[string]string `json:"params"`
+}
+
+type wsApiResponse struct {
+	Id     int64           `json:"id"`
+	Status int             `json:"status"`
+	Result json.RawMessage `json:"result"`
+	Error  *wsApiError     `json:"error"`
+
+	err error // set locally when the connection is lost before the response
+}
+
+type wsApiError struct {
+	Code int    `json:"code"`
+	Msg  string `json:"msg"`
+}
+
+type wsApiOrder struct {
+	OrderId       int64  `json:"orderId"`
+	ClientOrderId string `json:"clientOrderId"`
+	Status        string `json:"status"`
+}
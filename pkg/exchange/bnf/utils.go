@@ -6,8 +6,10 @@ import (
 	"lfg/pkg/market"
 	"lfg/pkg/types"
 	"lfg/pkg/utils"
+	"strings"
 
 	"github.com/adshao/go-binance/v2/futures"
+	log "github.com/sirupsen/logrus"
 )
 
 func loadMarkets(fClient *futures.Client) (map[string]*market.Market, error) {
@@ -15,10 +17,22 @@ func loadMarkets(fClient *futures.Client) (map[string]*market.Market, error) {
 	if err != nil {
 		return nil, err
 	}
+	// @dev: leverage brackets & commission rates are account specific (signed endpoints),
+	// markets still load without them
+	maxLeverages, err := getMaxLeverages(fClient)
+	if err != nil {
+		log.Warnf("fail to get leverage brackets, max leverage is unknown: %v", err)
+	}
+	makerFeePct, takerFeePct, err := getCommissionRates(fClient)
+	if err != nil {
+		log.Warnf("fail to get commission rates, default to the base fee tier: %v", err)
+	}
+
 	var markets = make(map[string]*market.Market)
 	for _, marketFilter := range marketFilters {
 		// market ID does not apply on bnf, default to 0
 		market := market.New(types.ExchangeBnf, 0, marketFilter.symbol)
+		market.TickSize = marketFilter.tickSize
 		market.MinNotional = marketFilter.minNotional
 		market.LotMinQty = marketFilter.lotMinQty
 		market.LotMaxQty = marketFilter.lotMaxQty
@@ -26,16 +40,57 @@ func loadMarkets(fClient *futures.Client) (map[string]*market.Market, error) {
 		market.MarketLotMinQty = marketFilter.marketLotMinQty
 		market.MarketLotMaxQty = marketFilter.marketLotMaxQty
 		market.MarketLotStepSize = marketFilter.marketLotStepSize
-
-		// ref: https://www.binance.com/en/fee/futureFee
-		market.MakerFeePct = 0.0002 // 2 bps
-		market.TakerFeePct = 0.0005 // 5 bps
+		market.MaxLeverage = float64(maxLeverages[marketFilter.symbol])
+		market.MakerFeePct = makerFeePct
+		market.TakerFeePct = takerFeePct
 
 		markets[marketFilter.symbol] = market
 	}
 	return markets, nil
 }
 
+// deriveUniSymbol returns the universal symbol of a USDT margined market e.g. `XYZUSDT` -> `XYZ_USD`
+func deriveUniSymbol(locSymbol string) (string, bool) {
+	base, ok := strings.CutSuffix(locSymbol, "USDT")
+	if !ok || base == "" {
+		return "", false
+	}
+	return base + "_USD", true
+}
+
+// getMaxLeverages returns the max leverage of each symbol, the initial leverage of its lowest bracket
+func getMaxLeverages(fClient *futures.Client) (map[string]int, error) {
+	leverageBrackets, err := fClient.NewGetLeverageBracketService().Do(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	maxLeverages := make(map[string]int, len(leverageBrackets))
+	for _, leverageBracket := range leverageBrackets {
+		for _, bracket := range leverageBracket.Brackets {
+			maxLeverages[leverageBracket.Symbol] = max(maxLeverages[leverageBracket.Symbol], bracket.InitialLeverage)
+		}
+	}
+	return maxLeverages, nil
+}
+
+// getCommissionRates returns the maker & taker fees of the account fee tier, read from
+// FEE_REF_SYMBOL as rates are only queried per symbol; base tier fees are returned on error
+func getCommissionRates(fClient *futures.Client) (float64, float64, error) {
+	commissionRate, err := fClient.NewCommissionRateService().Symbol(FEE_REF_SYMBOL).Do(context.Background())
+	if err != nil {
+		return BASE_MAKER_FEE_PCT, BASE_TAKER_FEE_PCT, err
+	}
+	makerFeePct, err := utils.StrToFloat(commissionRate.MakerCommissionRate)
+	if err != nil {
+		return BASE_MAKER_FEE_PCT, BASE_TAKER_FEE_PCT, err
+	}
+	takerFeePct, err := utils.StrToFloat(commissionRate.TakerCommissionRate)
+	if err != nil {
+		return BASE_MAKER_FEE_PCT, BASE_TAKER_FEE_PCT, err
+	}
+	return makerFeePct, takerFeePct, nil
+}
+
 func getMarketFilters(fClient *futures.Client) (map[string]bnfMarketFilter, error) {
 	exchangeInfo, err := fClient.NewExchangeInfoService().Do(context.Background())
 	if err != nil {
@@ -44,8 +99,18 @@ func getMarketFilters(fClient *futures.Client) (map[string]bnfMarketFilter, erro
 
 	marketFilters := make(map[string]bnfMarketFilter)
 	for _, symbol := range exchangeInfo.Symbols {
-		minNotional, lotMinQty, lotMaxQty, lotStepSize, marketLotMinQty, marketLotMaxQty, marketLotStepSize := 0.0, 0.0, 0.0, 0.0, 0.0, 0.0, 0.0
+		// @dev: symbols pending listing, settling or delisted are not markets
+		if symbol.Status != string(futures.SymbolStatusTypeTrading) {
+			continue
+		}
+		tickSize, minNotional, lotMinQty, lotMaxQty, lotStepSize, marketLotMinQty, marketLotMaxQty, marketLotStepSize := 0.0, 0.0, 0.0, 0.0, 0.0, 0.0, 0.0, 0.0
 		for _, filter := range symbol.Filters {
+			if filter["filterType"] == "PRICE_FILTER" {
+				tickSize, err = extractFilter(filter, "tickSize")
+				if err != nil {
+					return nil, err
+				}
+			}
 			if filter["filterType"] == "MIN_NOTIONAL" {
 				minNotional, err = extractFilter(filter, "notional")
 				if err != nil {
@@ -83,6 +148,7 @@ func getMarketFilters(fClient *futures.Client) (map[string]bnfMarketFilter, erro
 		}
 		marketFilters[symbol.Symbol] = bnfMarketFilter{
 			symbol:            symbol.Symbol,
+			tickSize:          tickSize,
 			minNotional:       minNotional,
 			lotMinQty:         lotMinQty,
 			lotMaxQty:         lotMaxQty,
@@ -0,0 +1,673 @@
+package dummy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"lfg/config"
+	"lfg/pkg/market"
+	"lfg/pkg/order"
+	"lfg/pkg/stream"
+	"lfg/pkg/types"
+	"math"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	DEFAULT_BALANCE    = 10000.0 // USD
+	TRADE_MAX_DELAY_MS = 5000    // trades older than this do not fill orders
+	ORDER_EVENT        = "ORDER_UPDATE"
+	FEE_ASSET          = "USD"
+	DUST_QTY           = 1e-12 // position qty below is closed, float noise of partial closes
+)
+
+// DummyExchange is a paper trading exchange: market data comes from the source, while
+// balance, positions & orders are simulated. Market orders fill at the last trade price with
+// slippage, limit orders fill at their price once a trade crosses it, without partial fills.
+// Fees are charged from the market MakerFeePct & TakerFeePct.
+type DummyExchange struct {
+	source      Source
+	ctx         context.Context // lifetime of the price feeds
+	cancel      context.CancelFunc
+	slippagePct float64
+
+	mu         sync.Mutex
+	balance    float64                   // initial balance + realized PnL - fees, in USD
+	positions  map[string]*paperPosition // local symbol -> position
+	orders     map[string]*paperOrder    // oId -> resting limit order
+	lastPrices map[string]float64        // local symbol -> last trade price
+	pnls       []paperPnL                // realized PnL of the fills, oldest first
+	feeds      map[string]bool           // local symbols with a trade feed
+	streams    map[int64]*DummyStream
+	nextId     int64 // order & stream ids
+}
+
+// New returns a paper exchange on the market data of the source; its price feeds run until ctx is done or Close
+func New(ctx context.Context, source Source, paperConfig *config.PaperConfig) (*DummyExchange, error) {
+	if paperConfig.Balance < 0 {
+		return nil, fmt.Errorf("paper balance must not be negative")
+	}
+	if paperConfig.SlippagePct < 0 || paperConfig.SlippagePct >= 1 {
+		return nil, fmt.Errorf("paper slippage must be in [0, 1)")
+	}
+	balance := paperConfig.Balance
+	if balance == 0 {
+		balance = DEFAULT_BALANCE
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	return &DummyExchange{
+		source:      source,
+		ctx:         ctx,
+		cancel:      cancel,
+		slippagePct: paperConfig.SlippagePct,
+		balance:     balance,
+		positions:   make(map[string]*paperPosition),
+		orders:      make(map[string]*paperOrder),
+		lastPrices:  make(map[string]float64),
+		feeds:       make(map[string]bool),
+		streams:     make(map[int64]*DummyStream),
+	}, nil
+}
+
+func (e *DummyExchange) Name() types.ExchangeName {
+	return types.ExchangeDummy
+}
+
+// Close stops the price feeds; resting orders no longer fill and new orders are rejected
+func (e *DummyExchange) Close() {
+	e.cancel()
+}
+
+// ╔═════════════╗
+//       Info
+// ╚═════════════╝
+
+func (e *DummyExchange) GetMarket(symbol string) *market.Market {
+	return e.source.GetMarket(symbol)
+}
+
+func (e *DummyExchange) RefreshMarkets() error {
+	return e.source.RefreshMarkets()
+}
+
+func (e *DummyExchange) SubscribeMarketEvents(ctx context.Context, onEvent func(types.MarketEvent)) {
+	e.source.SubscribeMarketEvents(ctx, onEvent)
+}
+
+// ╔═════════════╗
+//      Price
+// ╚═════════════╝
+
+func (e *DummyExchange) GetKLines(symbol string, interval types.Interval, window int) ([]types.KLineEvent, error) {
+	return e.source.GetKLines(symbol, interval, window)
+}
+
+// lastPrice returns the last trade price of the symbol, or the close of the last 1m kline
+// until the price feed receives a trade
+func (e *DummyExchange) lastPrice(symbol string) (float64, error) {
+	if err := e.watch(symbol); err != nil {
+		return 0, err
+	}
+	locSymbol := e.ToLocSymbol(symbol)
+	e.mu.Lock()
+	price := e.lastPrices[locSymbol]
+	e.mu.Unlock()
+	if price > 0 {
+		return price, nil
+	}
+
+	kLines, err := e.source.GetKLines(symbol, types.Interval1m, 1)
+	if err != nil {
+		return 0, err
+	}
+	if len(kLines) == 0 {
+		return 0, fmt.Errorf("cannot get klines")
+	}
+	price = kLines[len(kLines)-1].Kline.C
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.lastPrices[locSymbol] == 0 {
+		e.lastPrices[locSymbol] = price
+	}
+	return e.lastPrices[locSymbol], nil
+}
+
+// watch subscribes the trade feed of the symbol once, trades fill the resting orders
+func (e *DummyExchange) watch(symbol string) error {
+	if e.ctx.Err() != nil {
+		return fmt.Errorf("paper exchange closed")
+	}
+	locSymbol := e.ToLocSymbol(symbol)
+	e.mu.Lock()
+	if e.feeds[locSymbol] {
+		e.mu.Unlock()
+		return nil
+	}
+	e.feeds[locSymbol] = true
+	e.mu.Unlock()
+
+	_, err := e.source.SubscribeTradeStream(e.ctx, symbol, nil, func(_ stream.Stream, trade types.TradeEvent) {
+		e.onTrade(locSymbol, trade.Price)
+	}, nil, TRADE_MAX_DELAY_MS)
+	if err != nil {
+		e.mu.Lock()
+		delete(e.feeds, locSymbol)
+		e.mu.Unlock()
+		return fmt.Errorf("fail to subscribe price feed of %v: %w", symbol, err)
+	}
+	return nil
+}
+
+// onTrade fills the resting orders crossed by the trade, oldest first
+func (e *DummyExchange) onTrade(locSymbol string, price float64) {
+	e.mu.Lock()
+	e.lastPrices[locSymbol] = price
+	events := []types.OrderEvent{}
+	for _, o := range e.pendingOrders(locSymbol) {
+		crossed := (o.side == types.OrderSideBuy && price <= o.price) || (o.side == types.OrderSideSell && price >= o.price)
+		if !crossed {
+			continue
+		}
+		delete(e.orders, o.oId)
+		if o.reduceOnly {
+			// @dev: the position may have been reduced since the order was placed
+			o.qty = math.Min(o.qty, e.reducibleQty(locSymbol, o.side))
+			if o.qty <= 0 {
+				events = append(events, o.event(types.OrderStatusCanceled, 0, 0, 0))
+				continue
+			}
+		}
+		events = append(events, e.execute(o, o.price, o.makerFeePct))
+	}
+	e.mu.Unlock()
+	e.emit(events...)
+}
+
+func (e *DummyExchange) SubscribeTradeStream(ctx context.Context, symbol string, onConn func(stream.Stream), onEvent func(stream.Stream, types.TradeEvent), onClose func(stream.Stream), maxDelayMs int64) (stream.Stream, error) {
+	return e.source.SubscribeTradeStream(ctx, symbol, onConn, onEvent, onClose, maxDelayMs)
+}
+
+func (e *DummyExchange) SubscribeKLineStream(ctx context.Context, symbol string, interval types.Interval, onConn func(stream.Stream), onEvent func(stream.Stream, types.KLineEvent), onClose func(stream.Stream), maxDelayMs int64) (stream.Stream, error) {
+	return e.source.SubscribeKLineStream(ctx, symbol, interval, onConn, onEvent, onClose, maxDelayMs)
+}
+
+func (e *DummyExchange) SubscribeMarkPriceStream(ctx context.Context, symbol string, onConn func(stream.Stream), onEvent func(stream.Stream, types.MarkPriceEvent), onClose func(stream.Stream), maxDelayMs int64) (stream.Stream, error) {
+	return e.source.SubscribeMarkPriceStream(ctx, symbol, onConn, onEvent, onClose, maxDelayMs)
+}
+
+func (e *DummyExchange) SubscribeBookDepthStream(ctx context.Context, symbol string, onConn func(stream.Stream), onEvent func(stream.Stream, types.BookDepthEvent), onClose func(stream.Stream), maxDelayMs int64) (stream.Stream, error) {
+	return e.source.SubscribeBookDepthStream(ctx, symbol, onConn, onEvent, onClose, maxDelayMs)
+}
+
+// ╔═════════════╗
+//      Order
+// ╚═════════════╝
+
+func (e *DummyExchange) GetPendingOrders(symbol string) ([]order.Order, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	orders := []order.Order{}
+	for _, o := range e.pendingOrders(e.ToLocSymbol(symbol)) {
+		orders = append(orders, o.order())
+	}
+	return orders, nil
+}
+
+func (e *DummyExchange) GetAllPendingOrders() ([]order.Order, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	orders := []order.Order{}
+	for _, o := range e.orders {
+		orders = append(orders, o.order())
+	}
+	return orders, nil
+}
+
+func (e *DummyExchange) OpenMarketOrder(symbol string, side types.OrderSide, qty float64, lev int, reduceOnly bool) error {
+	price, err := e.lastPrice(symbol)
+	if err != nil {
+		return fmt.Errorf("fail to open market order %v %v: %w", side, symbol, err)
+	}
+	mkt := e.GetMarket(symbol)
+	locSymbol := e.ToLocSymbol(symbol)
+	e.mu.Lock()
+	reducible := e.reducibleQty(locSymbol, side)
+	e.mu.Unlock()
+	if reduceOnly && reducible > 0 && qty >= reducible && mkt != nil {
+		// @dev: closing the whole position fills its exact qty, which may be off the market lot step
+		qty = reducible
+	} else {
+		_, qty, err = mkt.NormalizeOrder(types.OrderMarket, price, qty, reduceOnly)
+		if err != nil {
+			return fmt.Errorf("fail to open market order %v %v: %w", side, symbol, err)
+		}
+	}
+	if err := mkt.CheckLeverage(lev); err != nil {
+		return err
+	}
+	fillPrice := price * (1 - e.slippagePct)
+	if side == types.OrderSideBuy {
+		fillPrice = price * (1 + e.slippagePct)
+	}
+
+	e.mu.Lock()
+	o := e.newOrder(locSymbol, types.OrderMarket, side, 0, qty, lev, reduceOnly, types.OrderTIFIOC, "")
+	if err := e.checkOrder(o, fillPrice); err != nil {
+		e.mu.Unlock()
+		return fmt.Errorf("fail to open market order %v %v: %w", side, symbol, err)
+	}
+	evt := e.execute(o, fillPrice, mkt.TakerFeePct)
+	e.mu.Unlock()
+	e.emit(evt)
+	return nil
+}
+
+// marketable limit orders fill at once at the last price as taker, except post-only orders which are rejected
+func (e *DummyExchange) OpenLimitOrder(symbol string, side types.OrderSide, price float64, qty float64, lev int, reduceOnly bool, tif types.OrderTIF, cloId string) (string, error) {
+	lastPrice, err := e.lastPrice(symbol)
+	if err != nil {
+		return "", fmt.Errorf("fail to open limit order %v %v: %w", side, symbol, err)
+	}
+	mkt := e.GetMarket(symbol)
+	price, qty, err = mkt.NormalizeOrder(types.OrderLimit, price, qty, reduceOnly)
+	if err != nil {
+		return "", fmt.Errorf("fail to open limit order %v %v: %w", side, symbol, err)
+	}
+	if err := mkt.CheckLeverage(lev); err != nil {
+		return "", err
+	}
+
+	e.mu.Lock()
+	o := e.newOrder(e.ToLocSymbol(symbol), types.OrderLimit, side, price, qty, lev, reduceOnly, tif, cloId)
+	o.makerFeePct = mkt.MakerFeePct
+	if err := e.checkOrder(o, price); err != nil {
+		e.mu.Unlock()
+		return "", fmt.Errorf("fail to open limit order %v %v: %w", side, symbol, err)
+	}
+	marketable := (side == types.OrderSideBuy && price >= lastPrice) || (side == types.OrderSideSell && price <= lastPrice)
+	var evt types.OrderEvent
+	switch {
+	case marketable && (tif == types.OrderTIFALO || tif == types.OrderTIFGTX):
+		e.mu.Unlock()
+		return "", fmt.Errorf("fail to open limit order %v %v: post-only order at %v would cross the last price %v", side, symbol, price, lastPrice)
+	case marketable:
+		evt = e.execute(o, lastPrice, mkt.TakerFeePct)
+	case tif == types.OrderTIFIOC || tif == types.OrderTIFFOK:
+		evt = o.event(types.OrderStatusExpired, 0, 0, 0)
+	default:
+		e.orders[o.oId] = o
+		evt = o.event(types.OrderStatusNew, 0, 0, 0)
+	}
+	e.mu.Unlock()
+	e.emit(evt)
+	return o.oId, nil
+}
+
+// orders failing the market filters or the margin check are logged and skipped, like on HPL;
+// an error is returned only if no order was placed
+// OpenBatchLimitOrders returns one order id per input, "" for the orders that failed, whose errors are joined
+func (e *DummyExchange) OpenBatchLimitOrders(symbol string, inputs []types.LimitOrderInput, lev int) ([]string, error) {
+	if len(inputs) == 0 {
+		return nil, fmt.Errorf("inputs length is 0")
+	}
+	oIds := make([]string, len(inputs))
+	errs := []error{}
+	for i, input := range inputs {
+		oId, err := e.OpenLimitOrder(symbol, input.Side, input.Price, input.Qty, lev, input.ReduceOnly, input.Tif, "")
+		if err != nil {
+			errs = append(errs, fmt.Errorf("order #%v: %w", i+1, err))
+			continue
+		}
+		oIds[i] = oId
+	}
+	if len(errs) > 0 {
+		return oIds, fmt.Errorf("fail to open %v of %v limit orders in batch: %w", len(errs), len(inputs), errors.Join(errs...))
+	}
+	return oIds, nil
+}
+
+// modifyOrder updates the price & qty of a resting order, which fills at once if it becomes marketable
+func (e *DummyExchange) modifyOrder(symbol string, oId string, cloId string, price float64, qty float64) error {
+	lastPrice, err := e.lastPrice(symbol)
+	if err != nil {
+		return fmt.Errorf("fail to modify order %v of %v: %w", oId+cloId, symbol, err)
+	}
+	mkt := e.GetMarket(symbol)
+
+	e.mu.Lock()
+	o := e.findOrder(e.ToLocSymbol(symbol), oId, cloId)
+	if o == nil {
+		e.mu.Unlock()
+		return fmt.Errorf("fail to modify order %v of %v: order not found", oId+cloId, symbol)
+	}
+	price, qty, err = mkt.NormalizeOrder(types.OrderLimit, price, qty, o.reduceOnly)
+	if err != nil {
+		e.mu.Unlock()
+		return fmt.Errorf("fail to modify order %v of %v: %w", oId+cloId, symbol, err)
+	}
+	o.price, o.qty = price, qty
+	var evt types.OrderEvent
+	if (o.side == types.OrderSideBuy && price >= lastPrice) || (o.side == types.OrderSideSell && price <= lastPrice) {
+		delete(e.orders, o.oId)
+		evt = e.execute(o, lastPrice, mkt.TakerFeePct)
+	} else {
+		evt = o.event(types.OrderStatusNew, 0, 0, 0)
+	}
+	e.mu.Unlock()
+	e.emit(evt)
+	return nil
+}
+
+func (e *DummyExchange) CancelOrder(symbol string, orderId string, cloId string) error {
+	e.mu.Lock()
+	o := e.findOrder(e.ToLocSymbol(symbol), orderId, cloId)
+	if o == nil {
+		e.mu.Unlock()
+		return fmt.Errorf("fail to cancel order %v of %v: order not found", orderId+cloId, symbol)
+	}
+	delete(e.orders, o.oId)
+	e.mu.Unlock()
+	e.emit(o.event(types.OrderStatusCanceled, 0, 0, 0))
+	return nil
+}
+
+func (e *DummyExchange) CancelAllOrders(symbol string) error {
+	e.mu.Lock()
+	events := []types.OrderEvent{}
+	for _, o := range e.pendingOrders(e.ToLocSymbol(symbol)) {
+		delete(e.orders, o.oId)
+		events = append(events, o.event(types.OrderStatusCanceled, 0, 0, 0))
+	}
+	e.mu.Unlock()
+	e.emit(events...)
+	return nil
+}
+
+func (e *DummyExchange) CancelBatchOrders(symbol string, orderIds []string) error {
+	failed := 0
+	for _, orderId := range orderIds {
+		if err := e.CancelOrder(symbol, orderId, ""); err != nil {
+			log.Warnf("fail to cancel some order in batch: %v", err)
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("fail to cancel %v of %v orders in batch", failed, len(orderIds))
+	}
+	return nil
+}
+
+// ╔═════════════╗
+//     Account
+// ╚═════════════╝
+
+// @dev: margin balance (wallet balance + unrealized PnL), as BNF
+func (e *DummyExchange) GetAccountBalance() (float64, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.equity(), nil
+}
+
+func (e *DummyExchange) GetActivePositionByMarket(symbol string) ([]types.Position, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	positions := []types.Position{}
+	pos, exists := e.positions[e.ToLocSymbol(symbol)]
+	if !exists || pos.qty == 0 {
+		return positions, nil
+	}
+	posSide := types.OrderSideBuy
+	if pos.qty < 0 {
+		posSide = types.OrderSideSell
+	}
+	return append(positions, types.Position{
+		Qty:        pos.qty,
+		EntryPrice: pos.entryPrice,
+		Side:       posSide,
+	}), nil
+}
+
+func (e *DummyExchange) GetActivePositions() (map[string][]types.Position, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	positions := make(map[string][]types.Position)
+	for locSymbol, pos := range e.positions {
+		if pos.qty == 0 {
+			continue
+		}
+		posSide := types.OrderSideBuy
+		if pos.qty < 0 {
+			posSide = types.OrderSideSell
+		}
+		symbol := e.ToUniSymbol(locSymbol)
+		positions[symbol] = append(positions[symbol], types.Position{
+			Qty:        pos.qty,
+			EntryPrice: pos.entryPrice,
+			Side:       posSide,
+		})
+	}
+	return positions, nil
+}
+
+// GetRealizedPnL sums the realized PnL of the paper fills since `since`
+func (e *DummyExchange) GetRealizedPnL(since time.Time) (float64, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	realizedPnL := 0.0
+	for i := len(e.pnls) - 1; i >= 0 && !e.pnls[i].time.Before(since); i-- {
+		realizedPnL += e.pnls[i].pnl
+	}
+	return realizedPnL, nil
+}
+
+func (e *DummyExchange) CloseActivePositionByMarket(symbol string, lev int) error {
+	positions, err := e.GetActivePositionByMarket(symbol)
+	if err != nil {
+		return err
+	}
+	for _, pos := range positions {
+		side := types.OrderSideSell
+		if pos.Qty < 0 {
+			side = types.OrderSideBuy
+		}
+		if err := e.OpenMarketOrder(symbol, side, math.Abs(pos.Qty), lev, true); err != nil {
+			return fmt.Errorf("fail to close position of %v: %w", symbol, err)
+		}
+	}
+	return nil
+}
+
+// ╔═══════════════════╗
+//    OrderMgmtStream
+// ╚═══════════════════╝
+
+// ConnectOrderMgmtStream returns a stream managing the paper orders, delivering the order updates of the symbol
+func (e *DummyExchange) ConnectOrderMgmtStream(ctx context.Context, symbol string, onConn func(stream.Stream), onEvent func(stream.Stream, types.OrderEvent), onClose func(stream.Stream)) (stream.Stream, error) {
+	return e.newStream(ctx, symbol, onConn, onEvent, onClose), nil
+}
+
+func (e *DummyExchange) SubscribeOrderStream(ctx context.Context, symbol string, onConn func(stream.Stream), onEvent func(stream.Stream, types.OrderEvent), onClose func(stream.Stream)) (stream.Stream, error) {
+	return e.newStream(ctx, symbol, onConn, onEvent, onClose), nil
+}
+
+// emit delivers the order events to the streams of their symbol
+func (e *DummyExchange) emit(events ...types.OrderEvent) {
+	e.mu.Lock()
+	streams := make([]*DummyStream, 0, len(e.streams))
+	for _, s := range e.streams {
+		streams = append(streams, s)
+	}
+	e.mu.Unlock()
+	for _, evt := range events {
+		for _, s := range streams {
+			if s.symbol == evt.Symbol && !s.IsClosed() && s.onEvent != nil {
+				s.onEvent(s, evt)
+			}
+		}
+	}
+}
+
+// ╔═════════════╗
+//     Symbol
+// ╚═════════════╝
+
+func (e *DummyExchange) HasSymbol(uniSymbol string) bool {
+	return e.source.HasSymbol(uniSymbol)
+}
+
+func (e *DummyExchange) ToUniSymbol(locSymbol string) string {
+	return e.source.ToUniSymbol(locSymbol)
+}
+
+func (e *DummyExchange) ToLocSymbol(uniSymbol string) string {
+	return e.source.ToLocSymbol(uniSymbol)
+}
+
+// ╔═════════════╗
+//   Simulation
+// ╚═════════════╝
+// @dev: functions below must be called with e.mu held
+
+func (e *DummyExchange) newOrder(locSymbol string, orderType types.OrderType, side types.OrderSide, price float64, qty float64, lev int, reduceOnly bool, tif types.OrderTIF, cloId string) *paperOrder {
+	e.nextId++
+	return &paperOrder{
+		seq:        e.nextId,
+		oId:        strconv.FormatInt(e.nextId, 10),
+		cloId:      cloId,
+		symbol:     locSymbol,
+		orderType:  orderType,
+		side:       side,
+		price:      price,
+		qty:        qty,
+		lev:        max(lev, 1),
+		reduceOnly: reduceOnly,
+		tif:        tif,
+	}
+}
+
+// checkOrder caps reduce-only orders to the position, and checks the margin of the qty opening a position
+func (e *DummyExchange) checkOrder(o *paperOrder, price float64) error {
+	reducible := e.reducibleQty(o.symbol, o.side)
+	if o.reduceOnly {
+		if reducible <= 0 {
+			return fmt.Errorf("reduce-only order would not reduce the position")
+		}
+		o.qty = math.Min(o.qty, reducible)
+		return nil
+	}
+	margin := math.Max(0, o.qty-reducible) * price / float64(o.lev)
+	if available := e.equity() - e.usedMargin(); margin > available {
+		return fmt.Errorf("insufficient margin: %v USD required, %v USD available", margin, available)
+	}
+	return nil
+}
+
+// reducibleQty returns the position qty an order of the side would close
+func (e *DummyExchange) reducibleQty(locSymbol string, side types.OrderSide) float64 {
+	pos, exists := e.positions[locSymbol]
+	if !exists {
+		return 0
+	}
+	if (side == types.OrderSideBuy && pos.qty < 0) || (side == types.OrderSideSell && pos.qty > 0) {
+		return math.Abs(pos.qty)
+	}
+	return 0
+}
+
+// execute fills the whole order at price and returns its filled event
+func (e *DummyExchange) execute(o *paperOrder, price float64, feePct float64) types.OrderEvent {
+	signedQty := o.qty
+	if o.side == types.OrderSideSell {
+		signedQty = -o.qty
+	}
+	pos, exists := e.positions[o.symbol]
+	if !exists {
+		pos = &paperPosition{}
+		e.positions[o.symbol] = pos
+	}
+
+	realizedPnL := 0.0
+	if pos.qty != 0 && (pos.qty > 0) != (signedQty > 0) {
+		// close up to the position, the rest opens the opposite side
+		closeQty := math.Min(math.Abs(signedQty), math.Abs(pos.qty))
+		realizedPnL = closeQty * (price - pos.entryPrice) * math.Copysign(1, pos.qty)
+		pos.qty += math.Copysign(closeQty, signedQty)
+		signedQty -= math.Copysign(closeQty, signedQty)
+		if math.Abs(pos.qty) < DUST_QTY {
+			pos.qty, pos.entryPrice = 0, 0
+		}
+	}
+	if math.Abs(signedQty) >= DUST_QTY {
+		newQty := pos.qty + signedQty
+		pos.entryPrice = (math.Abs(pos.qty)*pos.entryPrice + math.Abs(signedQty)*price) / math.Abs(newQty)
+		pos.qty = newQty
+		pos.lev = o.lev
+	}
+	if pos.qty == 0 {
+		delete(e.positions, o.symbol)
+	}
+
+	fee := o.qty * price * feePct
+	e.balance += realizedPnL - fee
+	if realizedPnL != 0 {
+		e.pnls = append(e.pnls, paperPnL{time: time.Now(), pnl: realizedPnL})
+	}
+	return o.event(types.OrderStatusFilled, price, realizedPnL, fee)
+}
+
+// equity returns the wallet balance with the unrealized PnL of the positions at the last prices
+func (e *DummyExchange) equity() float64 {
+	equity := e.balance
+	for locSymbol, pos := range e.positions {
+		if price := e.lastPrices[locSymbol]; price > 0 {
+			equity += pos.qty * (price - pos.entryPrice)
+		}
+	}
+	return equity
+}
+
+// usedMargin returns the initial margin of the positions & the resting orders opening positions
+func (e *DummyExchange) usedMargin() float64 {
+	used := 0.0
+	for _, pos := range e.positions {
+		used += math.Abs(pos.qty) * pos.entryPrice / float64(max(pos.lev, 1))
+	}
+	for _, o := range e.orders {
+		if !o.reduceOnly {
+			used += o.qty * o.price / float64(o.lev)
+		}
+	}
+	return used
+}
+
+// pendingOrders returns the resting orders of the symbol, oldest first
+func (e *DummyExchange) pendingOrders(locSymbol string) []*paperOrder {
+	orders := []*paperOrder{}
+	for _, o := range e.orders {
+		if o.symbol == locSymbol {
+			orders = append(orders, o)
+		}
+	}
+	sort.Slice(orders, func(i, j int) bool { return orders[i].seq < orders[j].seq })
+	return orders
+}
+
+func (e *DummyExchange) findOrder(locSymbol string, oId string, cloId string) *paperOrder {
+	if oId != "" {
+		if o, exists := e.orders[oId]; exists && o.symbol == locSymbol {
+			return o
+		}
+		return nil
+	}
+	for _, o := range e.orders {
+		if cloId != "" && o.cloId == cloId && o.symbol == locSymbol {
+			return o
+		}
+	}
+	return nil
+}
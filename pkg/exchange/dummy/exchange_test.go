@@ -0,0 +1,411 @@
+package dummy
+
+import (
+	"context"
+	"lfg/config"
+	"lfg/pkg/market"
+	"lfg/pkg/stream"
+	"lfg/pkg/types"
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeSource implements the calls made by the paper exchange; any other call panics
+type fakeSource struct {
+	Source
+	market  *market.Market
+	price   float64 // close of the last kline
+	onTrade func(stream.Stream, types.TradeEvent)
+	feedCtx context.Context
+}
+
+func (f *fakeSource) GetMarket(symbol string) *market.Market { return f.market }
+func (f *fakeSource) ToLocSymbol(uniSymbol string) string    { return "BTCUSDT" }
+func (f *fakeSource) ToUniSymbol(locSymbol string) string    { return "BTC_USD" }
+
+func (f *fakeSource) GetKLines(symbol string, interval types.Interval, window int) ([]types.KLineEvent, error) {
+	return []types.KLineEvent{{Kline: types.KLine{C: f.price}}}, nil
+}
+
+func (f *fakeSource) SubscribeTradeStream(ctx context.Context, symbol string, onConn func(stream.Stream), onEvent func(stream.Stream, types.TradeEvent), onClose func(stream.Stream), maxDelayMs int64) (stream.Stream, error) {
+	f.onTrade = onEvent
+	f.feedCtx = ctx
+	return nil, nil
+}
+
+func (f *fakeSource) trade(price float64) {
+	f.onTrade(nil, types.TradeEvent{Price: price})
+}
+
+// newTestExchange returns a paper exchange with 1000 USD at a last price of 100, and the order events it emits
+func newTestExchange(t *testing.T, slippagePct float64) (*DummyExchange, *fakeSource, *[]types.OrderEvent) {
+	src := &fakeSource{
+		market: &market.Market{
+			Symbol:      "BTCUSDT",
+			TickSize:    0.1,
+			LotMinQty:   0.001,
+			LotStepSize: 0.001,
+			MinNotional: 5,
+			MaxLeverage: 20,
+			MakerFeePct: 0.0002,
+			TakerFeePct: 0.0005,
+		},
+		price: 100,
+	}
+	e, err := New(context.Background(), src, &config.PaperConfig{Balance: 1000, SlippagePct: slippagePct})
+	if err != nil {
+		t.Fatalf("fail to create paper exchange: %v", err)
+	}
+	t.Cleanup(e.Close)
+	events := []types.OrderEvent{}
+	_, err = e.SubscribeOrderStream(context.Background(), "BTC_USD", nil, func(_ stream.Stream, evt types.OrderEvent) {
+		events = append(events, evt)
+	}, nil)
+	if err != nil {
+		t.Fatalf("fail to subscribe order stream: %v", err)
+	}
+	return e, src, &events
+}
+
+func almostEqual(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+func lastEvent(t *testing.T, events []types.OrderEvent) types.OrderEvent {
+	if len(events) == 0 {
+		t.Fatalf("no order event emitted")
+	}
+	return events[len(events)-1]
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name        string
+		paperConfig config.PaperConfig
+		wantBalance float64
+		wantErr     bool
+	}{
+		{"default balance", config.PaperConfig{}, DEFAULT_BALANCE, false},
+		{"balance", config.PaperConfig{Balance: 500}, 500, false},
+		{"negative balance", config.PaperConfig{Balance: -1}, 0, true},
+		{"slippage of 100%", config.PaperConfig{SlippagePct: 1}, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, err := New(context.Background(), &fakeSource{}, &tt.paperConfig)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("want error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if balance, _ := e.GetAccountBalance(); balance != tt.wantBalance {
+				t.Fatalf("balance = %v, want %v", balance, tt.wantBalance)
+			}
+		})
+	}
+}
+
+func TestOpenMarketOrder(t *testing.T) {
+	tests := []struct {
+		name       string
+		side       types.OrderSide
+		qty        float64
+		lev        int
+		reduceOnly bool
+		wantPrice  float64 // last price 100 with 0.1% slippage
+		wantFee    float64
+		wantEquity float64 // marked at the last price
+		wantErr    string
+	}{
+		{
+			name: "buy fills above the last price",
+			side: types.OrderSideBuy, qty: 1, lev: 1,
+			wantPrice: 100.1, wantFee: 0.05005, wantEquity: 999.84995,
+		},
+		{
+			name: "sell fills below the last price",
+			side: types.OrderSideSell, qty: 1, lev: 1,
+			wantPrice: 99.9, wantFee: 0.04995, wantEquity: 999.85005,
+		},
+		{
+			name: "qty rounded to the lot step",
+			side: types.OrderSideBuy, qty: 1.0009, lev: 1,
+			wantPrice: 100.1, wantFee: 0.05005, wantEquity: 999.84995,
+		},
+		{
+			name: "margin within balance with leverage",
+			side: types.OrderSideBuy, qty: 20, lev: 10,
+			wantPrice: 100.1, wantFee: 1.001, wantEquity: 996.999,
+		},
+		{
+			name: "insufficient margin",
+			side: types.OrderSideBuy, qty: 20, lev: 1,
+			wantErr: "insufficient margin",
+		},
+		{
+			name: "leverage above the market max",
+			side: types.OrderSideBuy, qty: 1, lev: 21,
+			wantErr: market.ErrMaxLeverage.Error(),
+		},
+		{
+			name: "below min notional",
+			side: types.OrderSideBuy, qty: 0.01, lev: 1,
+			wantErr: market.ErrMinNotional.Error(),
+		},
+		{
+			name: "reduce-only without position",
+			side: types.OrderSideSell, qty: 1, lev: 1, reduceOnly: true,
+			wantErr: "would not reduce the position",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, _, events := newTestExchange(t, 0.001)
+			err := e.OpenMarketOrder("BTC_USD", tt.side, tt.qty, tt.lev, tt.reduceOnly)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want %q", err, tt.wantErr)
+				}
+				if len(*events) != 0 {
+					t.Fatalf("rejected order emitted %v", *events)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			evt := lastEvent(t, *events)
+			if evt.OrderStatus != types.OrderStatusFilled || !almostEqual(evt.AvgPrice, tt.wantPrice) || !almostEqual(evt.Fee, tt.wantFee) {
+				t.Fatalf("event = %v @ %v fee %v, want filled @ %v fee %v", evt.OrderStatus, evt.AvgPrice, evt.Fee, tt.wantPrice, tt.wantFee)
+			}
+			if equity, _ := e.GetAccountBalance(); !almostEqual(equity, tt.wantEquity) {
+				t.Fatalf("equity = %v, want %v", equity, tt.wantEquity)
+			}
+		})
+	}
+}
+
+func TestOpenLimitOrder(t *testing.T) {
+	tests := []struct {
+		name       string
+		side       types.OrderSide
+		price      float64
+		qty        float64
+		tif        types.OrderTIF
+		wantStatus types.OrderStatus
+		wantPrice  float64 // fill price
+		wantFee    float64
+		wantErr    string
+	}{
+		{
+			name: "resting buy",
+			side: types.OrderSideBuy, price: 99, qty: 1, tif: types.OrderTIFGTC,
+			wantStatus: types.OrderStatusNew,
+		},
+		{
+			name: "marketable buy fills at the last price as taker",
+			side: types.OrderSideBuy, price: 101, qty: 1, tif: types.OrderTIFGTC,
+			wantStatus: types.OrderStatusFilled, wantPrice: 100, wantFee: 0.05,
+		},
+		{
+			name: "marketable sell fills at the last price as taker",
+			side: types.OrderSideSell, price: 99, qty: 1, tif: types.OrderTIFGTC,
+			wantStatus: types.OrderStatusFilled, wantPrice: 100, wantFee: 0.05,
+		},
+		{
+			name: "resting post-only",
+			side: types.OrderSideBuy, price: 99, qty: 1, tif: types.OrderTIFALO,
+			wantStatus: types.OrderStatusNew,
+		},
+		{
+			name: "crossing post-only",
+			side: types.OrderSideBuy, price: 101, qty: 1, tif: types.OrderTIFALO,
+			wantErr: "would cross the last price",
+		},
+		{
+			name: "crossing good till crossing",
+			side: types.OrderSideSell, price: 99, qty: 1, tif: types.OrderTIFGTX,
+			wantErr: "would cross the last price",
+		},
+		{
+			name: "non-marketable immediate or cancel",
+			side: types.OrderSideBuy, price: 99, qty: 1, tif: types.OrderTIFIOC,
+			wantStatus: types.OrderStatusExpired,
+		},
+		{
+			name: "insufficient margin",
+			side: types.OrderSideBuy, price: 99, qty: 20, tif: types.OrderTIFGTC,
+			wantErr: "insufficient margin",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, _, events := newTestExchange(t, 0.001)
+			oId, err := e.OpenLimitOrder("BTC_USD", tt.side, tt.price, tt.qty, 1, false, tt.tif, "")
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			evt := lastEvent(t, *events)
+			if evt.OId != oId || evt.OrderStatus != tt.wantStatus {
+				t.Fatalf("event = %v %v, want %v %v", evt.OId, evt.OrderStatus, oId, tt.wantStatus)
+			}
+			if !almostEqual(evt.AvgPrice, tt.wantPrice) || !almostEqual(evt.Fee, tt.wantFee) {
+				t.Fatalf("fill = %v fee %v, want %v fee %v", evt.AvgPrice, evt.Fee, tt.wantPrice, tt.wantFee)
+			}
+			pending, _ := e.GetPendingOrders("BTC_USD")
+			if resting := tt.wantStatus == types.OrderStatusNew; resting != (len(pending) == 1) {
+				t.Fatalf("pending orders = %v, want resting %v", pending, resting)
+			}
+		})
+	}
+}
+
+func TestLimitOrderFillAndPnL(t *testing.T) {
+	start := time.Now()
+	e, src, events := newTestExchange(t, 0.001)
+	if err := e.OpenMarketOrder("BTC_USD", types.OrderSideBuy, 1, 1, false); err != nil {
+		t.Fatalf("fail to open position: %v", err)
+	}
+	if _, err := e.OpenLimitOrder("BTC_USD", types.OrderSideSell, 110, 0.5, 1, true, types.OrderTIFGTC, "tp"); err != nil {
+		t.Fatalf("fail to open take profit: %v", err)
+	}
+
+	steps := []struct {
+		trade       float64
+		wantFilled  bool
+		wantQty     float64 // position after the trade
+		wantEquity  float64
+		wantPnL     float64 // realized by the fill
+		wantFee     float64
+		description string
+	}{
+		{109, false, 1, 999.94995 + 8.9, 0, 0, "below the order price"},
+		{110, true, 0.5, 999.94995 + 4.95 - 0.011 + 0.5*9.9, 4.95, 0.011, "crossing the order price fills as maker"},
+		{111, false, 0.5, 1010.33895, 0, 0, "unrealized PnL at the last trade"},
+	}
+	for _, step := range steps {
+		count := len(*events)
+		src.trade(step.trade)
+		if filled := len(*events) > count; filled != step.wantFilled {
+			t.Fatalf("%v: filled = %v, want %v", step.description, filled, step.wantFilled)
+		}
+		if step.wantFilled {
+			evt := lastEvent(t, *events)
+			if evt.ClientOId != "tp" || evt.OrderStatus != types.OrderStatusFilled || evt.AvgPrice != 110 ||
+				!almostEqual(evt.RealizedPnL, step.wantPnL) || !almostEqual(evt.Fee, step.wantFee) {
+				t.Fatalf("%v: event = %+v", step.description, evt)
+			}
+		}
+		positions, _ := e.GetActivePositionByMarket("BTC_USD")
+		if len(positions) != 1 || !almostEqual(positions[0].Qty, step.wantQty) || !almostEqual(positions[0].EntryPrice, 100.1) {
+			t.Fatalf("%v: positions = %+v, want %v @ 100.1", step.description, positions, step.wantQty)
+		}
+		if equity, _ := e.GetAccountBalance(); !almostEqual(equity, step.wantEquity) {
+			t.Fatalf("%v: equity = %v, want %v", step.description, equity, step.wantEquity)
+		}
+	}
+
+	if realizedPnL, _ := e.GetRealizedPnL(start); !almostEqual(realizedPnL, 4.95) {
+		t.Fatalf("realized PnL = %v, want 4.95", realizedPnL)
+	}
+	if realizedPnL, _ := e.GetRealizedPnL(time.Now()); realizedPnL != 0 {
+		t.Fatalf("realized PnL since now = %v, want 0", realizedPnL)
+	}
+	if positions, _ := e.GetActivePositions(); len(positions) != 1 || !almostEqual(positions["BTC_USD"][0].Qty, 0.5) {
+		t.Fatalf("active positions = %+v, want 0.5 BTC_USD", positions)
+	}
+}
+
+func TestReduceOnly(t *testing.T) {
+	e, src, events := newTestExchange(t, 0)
+	if err := e.OpenMarketOrder("BTC_USD", types.OrderSideBuy, 1, 1, false); err != nil {
+		t.Fatalf("fail to open position: %v", err)
+	}
+	if _, err := e.OpenLimitOrder("BTC_USD", types.OrderSideSell, 110, 1, 1, true, types.OrderTIFGTC, ""); err != nil {
+		t.Fatalf("fail to open take profit: %v", err)
+	}
+
+	// closing more than the position is capped to it
+	if err := e.OpenMarketOrder("BTC_USD", types.OrderSideSell, 2, 1, true); err != nil {
+		t.Fatalf("fail to close position: %v", err)
+	}
+	if evt := lastEvent(t, *events); evt.FilledQty != 1 {
+		t.Fatalf("filled qty = %v, want 1", evt.FilledQty)
+	}
+	if positions, _ := e.GetActivePositionByMarket("BTC_USD"); len(positions) != 0 {
+		t.Fatalf("positions = %+v, want none", positions)
+	}
+
+	// the resting reduce-only order is canceled once the position is gone
+	src.trade(110)
+	if evt := lastEvent(t, *events); evt.OrderStatus != types.OrderStatusCanceled {
+		t.Fatalf("take profit status = %v, want %v", evt.OrderStatus, types.OrderStatusCanceled)
+	}
+	if positions, _ := e.GetActivePositionByMarket("BTC_USD"); len(positions) != 0 {
+		t.Fatalf("reduce-only order opened a position: %+v", positions)
+	}
+	if err := e.CloseActivePositionByMarket("BTC_USD", 1); err != nil {
+		t.Fatalf("closing without position: unexpected error: %v", err)
+	}
+}
+
+func TestCloseOffStepPosition(t *testing.T) {
+	e, src, events := newTestExchange(t, 0)
+	src.market.MarketLotStepSize = 0.01
+	// a marketable limit order fills at once, on the finer limit lot step
+	if _, err := e.OpenLimitOrder("BTC_USD", types.OrderSideBuy, 101, 1.005, 1, false, types.OrderTIFGTC, ""); err != nil {
+		t.Fatalf("fail to open position: %v", err)
+	}
+
+	// the close fills the exact position qty instead of the qty rounded to the market lot step
+	if err := e.CloseActivePositionByMarket("BTC_USD", 1); err != nil {
+		t.Fatalf("fail to close position: %v", err)
+	}
+	if evt := lastEvent(t, *events); !almostEqual(evt.FilledQty, 1.005) {
+		t.Fatalf("filled qty = %v, want 1.005", evt.FilledQty)
+	}
+	if positions, _ := e.GetActivePositionByMarket("BTC_USD"); len(positions) != 0 {
+		t.Fatalf("positions = %+v, want none", positions)
+	}
+
+	// a partial close is still rounded to the market lot step
+	if _, err := e.OpenLimitOrder("BTC_USD", types.OrderSideBuy, 101, 1.005, 1, false, types.OrderTIFGTC, ""); err != nil {
+		t.Fatalf("fail to open position: %v", err)
+	}
+	if err := e.OpenMarketOrder("BTC_USD", types.OrderSideSell, 0.505, 1, true); err != nil {
+		t.Fatalf("fail to reduce position: %v", err)
+	}
+	if evt := lastEvent(t, *events); !almostEqual(evt.FilledQty, 0.5) {
+		t.Fatalf("filled qty = %v, want 0.5", evt.FilledQty)
+	}
+}
+
+func TestClose(t *testing.T) {
+	e, src, _ := newTestExchange(t, 0)
+	if err := e.OpenMarketOrder("BTC_USD", types.OrderSideBuy, 1, 1, false); err != nil {
+		t.Fatalf("fail to open position: %v", err)
+	}
+	if src.feedCtx.Err() != nil {
+		t.Fatalf("price feed stopped before close")
+	}
+
+	e.Close()
+	if src.feedCtx.Err() == nil {
+		t.Fatalf("price feed not stopped on close")
+	}
+	if err := e.OpenMarketOrder("BTC_USD", types.OrderSideSell, 1, 1, true); err == nil {
+		t.Fatalf("order accepted after close")
+	}
+}
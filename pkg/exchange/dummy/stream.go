@@ -0,0 +1,103 @@
+package dummy
+
+import (
+	"context"
+	"fmt"
+	"lfg/pkg/order"
+	"lfg/pkg/stream"
+	"lfg/pkg/types"
+	"sync"
+)
+
+// DummyStream delivers the paper order updates of a symbol and manages the paper orders;
+// there is no connection, the stream is closed with its context
+type DummyStream struct {
+	exchange *DummyExchange
+	id       int64
+	symbol   string // local symbol
+	onEvent  func(stream.Stream, types.OrderEvent)
+	onClose  func(stream.Stream)
+
+	mu       sync.Mutex
+	isClosed bool
+}
+
+func (e *DummyExchange) newStream(ctx context.Context, symbol string, onConn func(stream.Stream), onEvent func(stream.Stream, types.OrderEvent), onClose func(stream.Stream)) *DummyStream {
+	e.mu.Lock()
+	e.nextId++
+	s := &DummyStream{
+		exchange: e,
+		id:       e.nextId,
+		symbol:   e.ToLocSymbol(symbol),
+		onEvent:  onEvent,
+		onClose:  onClose,
+	}
+	e.streams[s.id] = s
+	e.mu.Unlock()
+
+	if onConn != nil {
+		onConn(s)
+	}
+	go func() {
+		<-ctx.Done()
+		s.Close()
+	}()
+	return s
+}
+
+func (s *DummyStream) ConnectAndSubscribe(_ map[string]string, _ func(e []byte)) (doneC chan struct{}, stopC chan struct{}, err error) {
+	return nil, nil, fmt.Errorf("paper stream has no connection")
+}
+
+func (s *DummyStream) Close() {
+	s.mu.Lock()
+	if s.isClosed {
+		s.mu.Unlock()
+		return
+	}
+	s.isClosed = true
+	s.mu.Unlock()
+
+	s.exchange.mu.Lock()
+	delete(s.exchange.streams, s.id)
+	s.exchange.mu.Unlock()
+	if s.onClose != nil {
+		s.onClose(s)
+	}
+}
+
+func (s *DummyStream) IsClosed() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.isClosed
+}
+
+func (s *DummyStream) OpenLimitOrder(symbol string, orderSide types.OrderSide, price float64, qty float64, lev int, reduceOnly bool, orderTif types.OrderTIF, cloId string) (string, error) {
+	return s.exchange.OpenLimitOrder(symbol, orderSide, price, qty, lev, reduceOnly, orderTif, cloId)
+}
+
+func (s *DummyStream) OpenMarketOrder(symbol string, side types.OrderSide, qty float64, lev int, reduceOnly bool) error {
+	return s.exchange.OpenMarketOrder(symbol, side, qty, lev, reduceOnly)
+}
+
+func (s *DummyStream) OpenBatchLimitOrders(symbol string, inputs []types.LimitOrderInput, lev int) error {
+	_, err := s.exchange.OpenBatchLimitOrders(symbol, inputs, lev)
+	return err
+}
+
+// @dev: as on BNF, only the price & qty of a resting order are modified
+func (s *DummyStream) ModifyOrder(symbol string, oId string, cloId string, orderSide types.OrderSide, price float64, qty float64, lev int, reduceOnly bool, orderTif types.OrderTIF) error {
+	return s.exchange.modifyOrder(symbol, oId, cloId, price, qty)
+}
+
+func (s *DummyStream) CancelOrder(symbol string, orderId string, cloId string) error {
+	return s.exchange.CancelOrder(symbol, orderId, cloId)
+}
+
+func (s *DummyStream) CancelBatchOrders(symbol string, orderIds []string) error {
+	return s.exchange.CancelBatchOrders(symbol, orderIds)
+}
+
+func (s *DummyStream) GetPendingOrders(symbol string) ([]order.Order, error) {
+	return s.exchange.GetPendingOrders(symbol)
+}
@@ -0,0 +1,97 @@
+package dummy
+
+import (
+	"context"
+	"lfg/pkg/market"
+	"lfg/pkg/order"
+	"lfg/pkg/stream"
+	"lfg/pkg/types"
+	"time"
+)
+
+// Source provides the market data the paper exchange trades on,
+// implemented by the real exchange adapters or a replay of recorded data
+type Source interface {
+	GetMarket(symbol string) *market.Market
+	RefreshMarkets() error
+	SubscribeMarketEvents(ctx context.Context, onEvent func(types.MarketEvent))
+
+	GetKLines(symbol string, interval types.Interval, window int) ([]types.KLineEvent, error)
+	SubscribeTradeStream(ctx context.Context, symbol string, onConn func(stream.Stream), onEvent func(stream.Stream, types.TradeEvent), onClose func(stream.Stream), maxDelayMs int64) (stream.Stream, error)
+	SubscribeKLineStream(ctx context.Context, symbol string, interval types.Interval, onConn func(stream.Stream), onEvent func(stream.Stream, types.KLineEvent), onClose func(stream.Stream), maxDelayMs int64) (stream.Stream, error)
+	SubscribeMarkPriceStream(ctx context.Context, symbol string, onConn func(stream.Stream), onEvent func(stream.Stream, types.MarkPriceEvent), onClose func(stream.Stream), maxDelayMs int64) (stream.Stream, error)
+	SubscribeBookDepthStream(ctx context.Context, symbol string, onConn func(stream.Stream), onEvent func(stream.Stream, types.BookDepthEvent), onClose func(stream.Stream), maxDelayMs int64) (stream.Stream, error)
+
+	HasSymbol(uniSymbol string) bool
+	ToUniSymbol(locSymbol string) string
+	ToLocSymbol(uniSymbol string) string
+}
+
+// paperOrder is an order of the paper exchange; symbol is local
+type paperOrder struct {
+	seq         int64 // placement order
+	oId         string
+	cloId       string
+	symbol      string
+	orderType   types.OrderType
+	side        types.OrderSide
+	price       float64 // 0 for market orders
+	qty         float64
+	lev         int
+	reduceOnly  bool
+	tif         types.OrderTIF
+	makerFeePct float64 // limit only, charged when the resting order fills
+}
+
+// event returns the order update of the status; the whole qty is filled at avgPrice once filled
+func (o *paperOrder) event(status types.OrderStatus, avgPrice float64, realizedPnL float64, fee float64) types.OrderEvent {
+	filledQty := 0.0
+	if status == types.OrderStatusFilled {
+		filledQty = o.qty
+	}
+	return types.OrderEvent{
+		Event:        ORDER_EVENT,
+		Time:         time.Now(),
+		Symbol:       o.symbol,
+		OId:          o.oId,
+		ClientOId:    o.cloId,
+		Side:         o.side,
+		IsReduceOnly: o.reduceOnly,
+		OrderStatus:  status,
+		Price:        o.price,
+		OrigQty:      o.qty,
+		OrderTif:     o.tif,
+		OrderType:    o.orderType,
+		AvgPrice:     avgPrice,
+		FilledQty:    filledQty,
+		RealizedPnL:  realizedPnL,
+		Fee:          fee,
+		FeeAsset:     FEE_ASSET,
+	}
+}
+
+// order returns the pending order of a resting limit order
+func (o *paperOrder) order() order.Order {
+	return order.Order{
+		Id:           o.oId,
+		Symbol:       o.symbol,
+		OrderType:    types.OrderLimit,
+		OrderSide:    o.side,
+		Price:        o.price,
+		OriginalQty:  o.qty,
+		RemainingQty: o.qty,
+	}
+}
+
+// paperPosition is the net position of a symbol (one-way mode)
+type paperPosition struct {
+	qty        float64 // negative when short
+	entryPrice float64
+	lev        int
+}
+
+// paperPnL is the realized PnL of a fill closing a position
+type paperPnL struct {
+	time time.Time
+	pnl  float64
+}
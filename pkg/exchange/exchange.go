@@ -5,27 +5,33 @@ import (
 	"errors"
 	"lfg/config"
 	"lfg/pkg/exchange/bnf"
+	"lfg/pkg/exchange/dummy"
 	"lfg/pkg/exchange/hpl"
 	"lfg/pkg/market"
 	"lfg/pkg/order"
 	"lfg/pkg/stream"
 	"lfg/pkg/types"
+	"time"
 )
 
 type Exchange interface {
 	Name() types.ExchangeName
 	GetMarket(symbol string) *market.Market
+	RefreshMarkets() error                                                      // reloads the markets, emitting the listings & delistings
+	SubscribeMarketEvents(ctx context.Context, onEvent func(types.MarketEvent)) // until ctx is done
 
 	GetPendingOrders(symbol string) ([]order.Order, error)
+	GetAllPendingOrders() ([]order.Order, error) // of every market
 	OpenMarketOrder(symbol string, side types.OrderSide, qty float64, lev int, reduceOnly bool) error
 	OpenLimitOrder(symbol string, side types.OrderSide, price float64, qty float64, lev int, reduceOnly bool, tif types.OrderTIF, cloId string) (string, error)
-	OpenBatchLimitOrders(symbol string, inputs []types.LimitOrderInput, lev int) ([]string, error)
+	OpenBatchLimitOrders(symbol string, inputs []types.LimitOrderInput, lev int) ([]string, error) // one order id per input, "" for the failed orders whose errors are joined
 	CancelOrder(symbol string, orderId string, cloId string) error
 	CancelAllOrders(symbol string) error
 	CancelBatchOrders(symbol string, orderIds []string) error
 	GetKLines(symbol string, interval types.Interval, window int) ([]types.KLineEvent, error)
 	GetAccountBalance() (float64, error) // in USD
 	GetActivePositionByMarket(symbol string) ([]types.Position, error)
+	GetActivePositions() (map[string][]types.Position, error) // of every market, by universal symbol
 	CloseActivePositionByMarket(symbol string, lev int) error
 
 	// ╔═════ WS callback functions ═════╗
@@ -40,17 +46,39 @@ type Exchange interface {
 	SubscribeBookDepthStream(ctx context.Context, symbol string, onConn func(stream.Stream), onEvent func(stream.Stream, types.BookDepthEvent), onClose func(stream.Stream), maxDelayMs int64) (stream.Stream, error)
 	SubscribeOrderStream(ctx context.Context, symbol string, onConn func(stream.Stream), onEvent func(stream.Stream, types.OrderEvent), onClose func(stream.Stream)) (stream.Stream, error) // maxDelayMs is not valid with order update (as every event is crucial)
 
+	HasSymbol(uniSymbol string) bool // non-fatal check before ToLocSymbol
 	ToUniSymbol(locSymbol string) string
 	ToLocSymbol(uniSymbol string) string
 }
 
-// creates a new exchange instance based on the provided name and credentials
-func NewExchange(exchgId string, exchgConfig *config.ExchangeConfig) (Exchange, error) {
+// RealizedPnLReporter is implemented by the exchanges reporting the realized PnL of the account,
+// e.g. to rebuild the daily loss of a risk policy after a restart
+type RealizedPnLReporter interface {
+	GetRealizedPnL(since time.Time) (float64, error) // in USD
+}
+
+// creates a new exchange instance based on the provided name and credentials;
+// the background work of the exchange (e.g. the paper trading price feeds) stops with ctx
+func NewExchange(ctx context.Context, exchgId string, exchgConfig *config.ExchangeConfig) (Exchange, error) {
 	switch exchgConfig.ExchangeName {
 	case types.ExchangeBnf:
 		return bnf.New(exchgConfig)
 	case types.ExchangeHpl:
 		return hpl.New(exchgConfig)
+	case types.ExchangeDummy:
+		// @dev: paper trading on the market data of the source exchange
+		paperConfig := exchgConfig.Paper
+		if paperConfig == nil || paperConfig.Source == "" || paperConfig.Source == types.ExchangeDummy {
+			return nil, errors.New("dummy exchange requires a paper source exchange")
+		}
+		sourceConfig := *exchgConfig
+		sourceConfig.ExchangeName = paperConfig.Source
+		sourceConfig.Paper = nil
+		source, err := NewExchange(ctx, exchgId, &sourceConfig)
+		if err != nil {
+			return nil, err
+		}
+		return dummy.New(ctx, source, paperConfig)
 	default:
 		return nil, errors.New("unsupported exchange")
 	}
@@ -19,14 +19,14 @@ func convertOrderTif(orderTif types.OrderTIF) (tifType, error) {
 }
 
 func (e *HplExchange) convertSymbolToMarketIdx(locSymbol string) (int, error) {
-	if market, exists := e.Markets[locSymbol]; exists {
+	if market := e.Markets.Get(locSymbol); market != nil {
 		return int(market.Id), nil
 	}
 	return 0, fmt.Errorf("marketIdx not found from symbol: %v", locSymbol)
 }
 
 func (e *HplExchange) convertMarketIdxToSymbol(marketIdx int64) (string, error) {
-	for symbol, market := range e.Markets {
+	for symbol, market := range e.Markets.All() {
 		if market.Id == marketIdx {
 			return symbol, nil
 		}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"crypto/ecdsa"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"lfg/config"
 	"lfg/pkg/exchange/bnf"
@@ -30,9 +31,8 @@ type HplExchange struct {
 	HplConfig *hplConfig
 	IsMainnet bool
 
-	Markets      map[string]*market.Market
-	SymbolMapU2L map[string]string
-	SymbolMapL2U map[string]string
+	Markets *market.Registry
+	Symbols *market.SymbolMap
 
 	AccountPrivKey  *ecdsa.PrivateKey
 	AccountAddress  common.Address
@@ -52,8 +52,7 @@ func New(exchgConfig *config.ExchangeConfig) (*HplExchange, error) {
 	}
 
 	// (2) load symbol
-	symbolMapU2L := utils.LoadExchangeSymbolMap(string(types.ExchangeHpl))
-	symbolMapL2U := utils.ReverseStrMap(symbolMapU2L)
+	symbols := market.NewSymbolMap(utils.LoadExchangeSymbolMap(string(types.ExchangeHpl)))
 
 	// (3) load config
 	rawConfig, err := os.ReadFile(filepath.Join("pkg", "exchange", "hpl", "config", configFile))
@@ -78,7 +77,15 @@ func New(exchgConfig *config.ExchangeConfig) (*HplExchange, error) {
 	address := crypto.PubkeyToAddress(*pubKey)
 
 	// (4) load markets
-	markets, err := loadMarkets(hplConfig.ApiUrl)
+	markets, err := market.NewRegistry(types.ExchangeHpl, func() (map[string]*market.Market, error) {
+		markets, err := loadMarkets(hplConfig.ApiUrl, address.String())
+		if err != nil {
+			return nil, err
+		}
+		// @dev: markets missing from the symbol map (e.g. new listings) are mapped before their listing is emitted
+		symbols.Extend(markets, deriveUniSymbol)
+		return markets, nil
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -92,8 +99,7 @@ func New(exchgConfig *config.ExchangeConfig) (*HplExchange, error) {
 	hplExchange := &HplExchange{
 		HplConfig:       &hplConfig,
 		IsMainnet:       isMainnet,
-		SymbolMapU2L:    symbolMapU2L,
-		SymbolMapL2U:    symbolMapL2U,
+		Symbols:         symbols,
 		Markets:         markets,
 		AccountPrivKey:  privKey,
 		AccountAddress:  address,
@@ -113,11 +119,18 @@ func (*HplExchange) Name() types.ExchangeName {
 // ╚═════════════╝
 
 func (e *HplExchange) GetMarket(symbol string) *market.Market {
-	symbol = e.ToLocSymbol(symbol)
-	if market, exists := e.Markets[symbol]; exists {
-		return market
-	}
-	return nil
+	return e.Markets.Get(e.ToLocSymbol(symbol))
+}
+
+func (e *HplExchange) RefreshMarkets() error {
+	return e.Markets.Refresh()
+}
+
+func (e *HplExchange) SubscribeMarketEvents(ctx context.Context, onEvent func(types.MarketEvent)) {
+	e.Markets.Subscribe(ctx, func(event types.MarketEvent) {
+		event.Symbol, _ = e.Symbols.ToUni(event.LocSymbol)
+		onEvent(event)
+	})
 }
 
 // ╔═════════════╗
@@ -209,6 +222,21 @@ func (e *HplExchange) GetPendingOrders(symbol string) ([]order.Order, error) {
 	// convert
 	symbol = e.ToLocSymbol(symbol)
 
+	pendingOrders, err := e.GetAllPendingOrders()
+	if err != nil {
+		return nil, err
+	}
+	orders := make([]order.Order, 0)
+	for _, order := range pendingOrders {
+		// HPL, well, only returns symbol name in this endpoint e.g. "BTC" not "BTC/USD"
+		if order.Symbol == strings.Split(symbol, "/")[0] {
+			orders = append(orders, order)
+		}
+	}
+	return orders, nil
+}
+
+func (e *HplExchange) GetAllPendingOrders() ([]order.Order, error) {
 	// params
 	req := metadataRequest{
 		Type: "openOrders",
@@ -240,20 +268,12 @@ func (e *HplExchange) GetPendingOrders(symbol string) ([]order.Order, error) {
 		if err != nil {
 			return nil, err
 		}
-		// HPL, well, only returns symbol name in this endpoint e.g. "BTC" not "BTC/USD"
-		if order.Symbol == strings.Split(symbol, "/")[0] {
-			orders = append(orders, order)
-		}
+		orders = append(orders, order)
 	}
 	return orders, nil
 }
 
 func (e *HplExchange) OpenMarketOrder(symbol string, side types.OrderSide, qty float64, lev int, reduceOnly bool) error {
-	if e.AccountLeverage[e.SymbolMapU2L[symbol]] != lev {
-		if err := e.UpdateAccountLeverage(symbol, lev, false); err != nil {
-			return err
-		}
-	}
 	isBuy := side == types.OrderSideBuy
 
 	// get mid price
@@ -269,7 +289,19 @@ func (e *HplExchange) OpenMarketOrder(symbol string, side types.OrderSide, qty f
 	if isBuy {
 		limitPrice = midPrice * 1.1
 	}
-	limitPrice = utils.RoundToSigFigs(limitPrice, MAX_PRICE_SIG_FIGURE)
+	// @dev: the mid price is the reference of the min notional, the limit price only bounds the slippage
+	mkt := e.GetMarket(symbol)
+	_, qty, err = mkt.NormalizeOrder(types.OrderMarket, midPrice, qty, reduceOnly)
+	if err != nil {
+		return fmt.Errorf("fail to open market order %v %v: %w", side, symbol, err)
+	}
+	limitPrice = mkt.RoundPrice(utils.RoundToSigFigs(limitPrice, MAX_PRICE_SIG_FIGURE))
+
+	if e.AccountLeverage[e.ToLocSymbol(symbol)] != lev {
+		if err := e.UpdateAccountLeverage(symbol, lev, false); err != nil {
+			return err
+		}
+	}
 
 	symbol = e.ToLocSymbol(symbol)
 	orderType := orderTypeWire{
@@ -346,7 +378,12 @@ func (e *HplExchange) OpenMarketOrder(symbol string, side types.OrderSide, qty f
 }
 
 func (e *HplExchange) OpenLimitOrder(symbol string, side types.OrderSide, price float64, qty float64, lev int, reduceOnly bool, tif types.OrderTIF, cloId string) (string, error) {
-	if e.AccountLeverage[e.SymbolMapU2L[symbol]] != lev {
+	// ref: https://hyperliquid.gitbook.io/hyperliquid-docs/for-developers/api/tick-and-lot-size
+	price, qty, err := e.GetMarket(symbol).NormalizeOrder(types.OrderLimit, utils.RoundToSigFigs(price, MAX_PRICE_SIG_FIGURE), qty, reduceOnly)
+	if err != nil {
+		return "", fmt.Errorf("fail to open limit order %v %v: %w", side, symbol, err)
+	}
+	if e.AccountLeverage[e.ToLocSymbol(symbol)] != lev {
 		if err := e.UpdateAccountLeverage(symbol, lev, false); err != nil {
 			return "", err
 		}
@@ -354,8 +391,6 @@ func (e *HplExchange) OpenLimitOrder(symbol string, side types.OrderSide, price
 
 	// convert
 	symbol = e.ToLocSymbol(symbol)
-	// ref: https://hyperliquid.gitbook.io/hyperliquid-docs/for-developers/api/tick-and-lot-size
-	price = utils.RoundToSigFigs(price, MAX_PRICE_SIG_FIGURE)
 	orderTif, err := convertOrderTif(tif)
 	if err != nil {
 		return "", err
@@ -435,8 +470,9 @@ func (e *HplExchange) OpenLimitOrder(symbol string, side types.OrderSide, price
 	return oId, nil
 }
 
+// OpenBatchLimitOrders returns one order id per input, "" for the orders that failed, whose errors are joined
 func (e *HplExchange) OpenBatchLimitOrders(symbol string, inputs []types.LimitOrderInput, lev int) ([]string, error) {
-	if e.AccountLeverage[e.SymbolMapU2L[symbol]] != lev {
+	if e.AccountLeverage[e.ToLocSymbol(symbol)] != lev {
 		if err := e.UpdateAccountLeverage(symbol, lev, false); err != nil {
 			return nil, err
 		}
@@ -461,10 +497,19 @@ func (e *HplExchange) OpenBatchLimitOrders(symbol string, inputs []types.LimitOr
 	if err != nil {
 		return nil, err
 	}
+	// @dev: orders failing the market filters are skipped
 	// ref: https://hyperliquid.gitbook.io/hyperliquid-docs/for-developers/api/tick-and-lot-size
+	mkt := e.GetMarket(symbol)
+	oIds := make([]string, len(inputs))
+	errs := []error{}
 	orders := make([]orderWire, 0, len(inputs))
-	for _, order := range inputs {
-		price := utils.RoundToSigFigs(order.Price, MAX_PRICE_SIG_FIGURE)
+	orderInputs := make([]int, 0, len(inputs)) // input index of each order
+	for i, order := range inputs {
+		price, qty, err := mkt.NormalizeOrder(types.OrderLimit, utils.RoundToSigFigs(order.Price, MAX_PRICE_SIG_FIGURE), order.Qty, order.ReduceOnly)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("order #%v: %w", i+1, err))
+			continue
+		}
 		isBuy := order.Side == types.OrderSideBuy
 
 		// params
@@ -472,10 +517,14 @@ func (e *HplExchange) OpenBatchLimitOrders(symbol string, inputs []types.LimitOr
 			Asset:      marketIdx,
 			IsBuy:      isBuy,
 			LimitPx:    utils.FloatToStr(price),
-			SizePx:     utils.FloatToStr(order.Qty),
-			ReduceOnly: false,
+			SizePx:     utils.FloatToStr(qty),
+			ReduceOnly: order.ReduceOnly,
 			OrderType:  orderType,
 		})
+		orderInputs = append(orderInputs, i)
+	}
+	if len(orders) == 0 {
+		return oIds, fmt.Errorf("fail to open any limit order in batch: %w", errors.Join(errs...))
 	}
 	nonce := getNonce()
 	action := orderAction{
@@ -513,18 +562,22 @@ func (e *HplExchange) OpenBatchLimitOrders(symbol string, inputs []types.LimitOr
 		log.Warnf("fail unmarshal response: %v", string(resBody))
 		return nil, err
 	}
-	for _, status := range res.Response.Data.Statuses {
-		if errMsg := status.Error; errMsg != "" {
-			log.Warnf("fail to open some limit order in batch: %s", errMsg)
+	// @dev: statuses are indexed like the orders of the request
+	statuses := res.Response.Data.Statuses
+	for j, i := range orderInputs {
+		switch {
+		case j >= len(statuses):
+			errs = append(errs, fmt.Errorf("order #%v: no status returned", i+1))
+		case statuses[j].Error != "":
+			errs = append(errs, fmt.Errorf("order #%v: %v", i+1, statuses[j].Error))
+		case statuses[j].Resting.Oid != 0:
+			oIds[i] = strconv.FormatInt(statuses[j].Resting.Oid, 10)
+		case statuses[j].Filled.Oid != 0:
+			oIds[i] = strconv.FormatInt(statuses[j].Filled.Oid, 10)
 		}
 	}
-	oIds := make([]string, 0, len(res.Response.Data.Statuses))
-	for _, status := range res.Response.Data.Statuses {
-		oId := strconv.FormatInt(status.Resting.Oid, 10)
-		if oId == "" {
-			continue
-		}
-		oIds = append(oIds, oId)
+	if len(errs) > 0 {
+		return oIds, fmt.Errorf("fail to open %v of %v limit orders in batch: %w", len(errs), len(inputs), errors.Join(errs...))
 	}
 	return oIds, nil
 }
@@ -667,7 +720,16 @@ func (e *HplExchange) CancelBatchOrders(symbol string, orderIds []string) error
 }
 
 func (e *HplExchange) CancelAllOrders(symbol string) error {
-	return fmt.Errorf("not implemented")
+	// HPL has no cancel-all action, cancel every pending order of the symbol in one batch
+	orders, err := e.GetPendingOrders(symbol)
+	if err != nil {
+		return fmt.Errorf("fail to get pending orders: %w", err)
+	}
+	orderIds := make([]string, 0, len(orders))
+	for _, order := range orders {
+		orderIds = append(orderIds, order.Id)
+	}
+	return e.CancelBatchOrders(symbol, orderIds)
 }
 
 func (e *HplExchange) UpdateAccountLeverage(symbol string, lev int, isCross bool) error {
@@ -1028,15 +1090,20 @@ func (e *HplExchange) SubscribeOrderStream(ctx context.Context, symbol string, o
 }
 
 func (e *HplExchange) ToUniSymbol(locSymbol string) string {
-	if locSymbol, ok := e.SymbolMapL2U[locSymbol]; ok {
-		return locSymbol
+	if uniSymbol, ok := e.Symbols.ToUni(locSymbol); ok {
+		return uniSymbol
 	}
 	log.Fatalf("fail to convert local symbol to universal symbol: %v", locSymbol)
 	return ""
 }
 
+func (e *HplExchange) HasSymbol(uniSymbol string) bool {
+	_, ok := e.Symbols.ToLoc(uniSymbol)
+	return ok
+}
+
 func (e *HplExchange) ToLocSymbol(uniSymbol string) string {
-	if locSymbol, ok := e.SymbolMapU2L[uniSymbol]; ok {
+	if locSymbol, ok := e.Symbols.ToLoc(uniSymbol); ok {
 		return locSymbol
 	}
 	log.Fatalf("fail to convert universal symbol to local symbol: %v", uniSymbol)
@@ -1072,8 +1139,15 @@ func (e *HplExchange) GetAccountBalance() (float64, error) {
 }
 
 func (e *HplExchange) GetActivePositionByMarket(symbol string) ([]types.Position, error) {
+	positions, err := e.GetActivePositions()
+	if err != nil {
+		return nil, err
+	}
+	return positions[symbol], nil
+}
+
+func (e *HplExchange) GetActivePositions() (map[string][]types.Position, error) {
 	// params
-	symbol = e.ToLocSymbol(symbol)
 	req := map[string]interface{}{
 		"type": "clearinghouseState",
 		"user": e.AccountAddress.String(),
@@ -1098,27 +1172,33 @@ func (e *HplExchange) GetActivePositionByMarket(symbol string) ([]types.Position
 		return nil, err
 	}
 
-	positions := []types.Position{}
+	positions := make(map[string][]types.Position)
 	for _, pos := range res.AssetPositions {
-		if pos.Position.Coin == symbol {
-			qty, err := utils.StrToFloat(pos.Position.Szi)
-			if err != nil {
-				return nil, err
-			}
-			entryPx, err := utils.StrToFloat(pos.Position.EntryPx)
-			if err != nil {
-				return nil, err
-			}
-			side := types.OrderSideBuy
-			if qty < 0 {
-				side = types.OrderSideSell
-			}
-			positions = append(positions, types.Position{
-				EntryPrice: entryPx,
-				Qty:        math.Abs(qty),
-				Side:       side,
-			})
+		qty, err := utils.StrToFloat(pos.Position.Szi)
+		if err != nil {
+			return nil, err
+		}
+		if qty == 0 {
+			continue
+		}
+		entryPx, err := utils.StrToFloat(pos.Position.EntryPx)
+		if err != nil {
+			return nil, err
+		}
+		side := types.OrderSideBuy
+		if qty < 0 {
+			side = types.OrderSideSell
 		}
+		// @dev: a position on a market missing from the symbol map is kept under its coin
+		symbol, ok := e.Symbols.ToUni(pos.Position.Coin)
+		if !ok {
+			symbol = pos.Position.Coin
+		}
+		positions[symbol] = append(positions[symbol], types.Position{
+			EntryPrice: entryPx,
+			Qty:        math.Abs(qty),
+			Side:       side,
+		})
 	}
 
 	return positions, nil
@@ -305,15 +305,18 @@ func (sm *HplStream) OpenLimitOrder(symbol string, side types.OrderSide, price f
 	if sm.isClosed {
 		return "", fmt.Errorf("fail to open limit order %v %v %v at price %v: websocket already closed", side, qty, symbol, price)
 	}
-	if sm.exchange.AccountLeverage[sm.exchange.SymbolMapU2L[symbol]] != lev {
+	// ref: https://hyperliquid.gitbook.io/hyperliquid-docs/for-developers/api/tick-and-lot-size
+	price, qty, err := sm.exchange.GetMarket(symbol).NormalizeOrder(types.OrderLimit, utils.RoundToSigFigs(price, MAX_PRICE_SIG_FIGURE), qty, reduceOnly)
+	if err != nil {
+		return "", fmt.Errorf("fail to open limit order %v %v: %w", side, symbol, err)
+	}
+	if sm.exchange.AccountLeverage[sm.exchange.ToLocSymbol(symbol)] != lev {
 		if err := sm.exchange.UpdateAccountLeverage(symbol, lev, false); err != nil {
 			return "", err
 		}
 	}
 	// convert
 	symbol = sm.exchange.ToLocSymbol(symbol)
-	// ref: https://hyperliquid.gitbook.io/hyperliquid-docs/for-developers/api/tick-and-lot-size
-	price = utils.RoundToSigFigs(price, MAX_PRICE_SIG_FIGURE)
 	orderTif, err := convertOrderTif(tif)
 	if err != nil {
 		return "", err
@@ -413,7 +416,7 @@ func (sm *HplStream) OpenBatchLimitOrders(symbol string, inputs []types.LimitOrd
 	if sm.isClosed {
 		return fmt.Errorf("fail to open %v batch limit orders: websocket already closed", len(inputs))
 	}
-	if sm.exchange.AccountLeverage[sm.exchange.SymbolMapU2L[symbol]] != lev {
+	if sm.exchange.AccountLeverage[sm.exchange.ToLocSymbol(symbol)] != lev {
 		if err := sm.exchange.UpdateAccountLeverage(symbol, lev, false); err != nil {
 			return err
 		}
@@ -438,10 +441,18 @@ func (sm *HplStream) OpenBatchLimitOrders(symbol string, inputs []types.LimitOrd
 	if err != nil {
 		return err
 	}
+	// @dev: orders failing the market filters are logged and skipped
 	// ref: https://hyperliquid.gitbook.io/hyperliquid-docs/for-developers/api/tick-and-lot-size
+	mkt := sm.exchange.GetMarket(symbol)
 	orders := make([]orderWire, 0, len(inputs))
+	var lastErr error
 	for _, order := range inputs {
-		price := utils.RoundToSigFigs(order.Price, MAX_PRICE_SIG_FIGURE)
+		price, qty, err := mkt.NormalizeOrder(types.OrderLimit, utils.RoundToSigFigs(order.Price, MAX_PRICE_SIG_FIGURE), order.Qty, order.ReduceOnly)
+		if err != nil {
+			sm.logger.Warnf("fail to open some limit order in batch: %v", err)
+			lastErr = err
+			continue
+		}
 		isBuy := order.Side == types.OrderSideBuy
 
 		// params
@@ -449,11 +460,14 @@ func (sm *HplStream) OpenBatchLimitOrders(symbol string, inputs []types.LimitOrd
 			Asset:      marketIdx,
 			IsBuy:      isBuy,
 			LimitPx:    utils.FloatToStr(price),
-			SizePx:     utils.FloatToStr(order.Qty),
-			ReduceOnly: false,
+			SizePx:     utils.FloatToStr(qty),
+			ReduceOnly: order.ReduceOnly,
 			OrderType:  orderType,
 		})
 	}
+	if len(orders) == 0 {
+		return fmt.Errorf("fail to open any limit order in batch: %w", lastErr)
+	}
 	nonce := getNonce()
 	action := orderAction{
 		Type:     "order",
@@ -593,15 +607,18 @@ func (sm *HplStream) ModifyOrder(symbol string, oId string, cloId string, side t
 	if sm.isClosed {
 		return fmt.Errorf("fail to open limit order %v %v %v at price %v: websocket already closed", side, qty, symbol, price)
 	}
-	if sm.exchange.AccountLeverage[sm.exchange.SymbolMapU2L[symbol]] != lev {
+	// ref: https://hyperliquid.gitbook.io/hyperliquid-docs/for-developers/api/tick-and-lot-size
+	price, qty, err := sm.exchange.GetMarket(symbol).NormalizeOrder(types.OrderLimit, utils.RoundToSigFigs(price, MAX_PRICE_SIG_FIGURE), qty, reduceOnly)
+	if err != nil {
+		return fmt.Errorf("fail to modify order %v of %v: %w", oId+cloId, symbol, err)
+	}
+	if sm.exchange.AccountLeverage[sm.exchange.ToLocSymbol(symbol)] != lev {
 		if err := sm.exchange.UpdateAccountLeverage(symbol, lev, false); err != nil {
 			return err
 		}
 	}
 	// convert
 	symbol = sm.exchange.ToLocSymbol(symbol)
-	// ref: https://hyperliquid.gitbook.io/hyperliquid-docs/for-developers/api/tick-and-lot-size
-	price = utils.RoundToSigFigs(price, MAX_PRICE_SIG_FIGURE)
 	orderTif, err := convertOrderTif(tif)
 	if err != nil {
 		return err
@@ -795,11 +812,6 @@ func (sm *HplStream) OpenMarketOrder(symbol string, side types.OrderSide, qty fl
 		return fmt.Errorf("fail to open market order %v %v %v: websocket already closed", side, qty, symbol)
 	}
 
-	if sm.exchange.AccountLeverage[sm.exchange.SymbolMapU2L[symbol]] != lev {
-		if err := sm.exchange.UpdateAccountLeverage(symbol, lev, false); err != nil {
-			return err
-		}
-	}
 	isBuy := side == types.OrderSideBuy
 
 	// get mid price
@@ -815,7 +827,19 @@ func (sm *HplStream) OpenMarketOrder(symbol string, side types.OrderSide, qty fl
 	if isBuy {
 		limitPrice = midPrice * 1.1
 	}
-	limitPrice = utils.RoundToSigFigs(limitPrice, MAX_PRICE_SIG_FIGURE)
+	// @dev: the mid price is the reference of the min notional, the limit price only bounds the slippage
+	mkt := sm.exchange.GetMarket(symbol)
+	_, qty, err = mkt.NormalizeOrder(types.OrderMarket, midPrice, qty, reduceOnly)
+	if err != nil {
+		return fmt.Errorf("fail to open market order %v %v: %w", side, symbol, err)
+	}
+	limitPrice = mkt.RoundPrice(utils.RoundToSigFigs(limitPrice, MAX_PRICE_SIG_FIGURE))
+
+	if sm.exchange.AccountLeverage[sm.exchange.ToLocSymbol(symbol)] != lev {
+		if err := sm.exchange.UpdateAccountLeverage(symbol, lev, false); err != nil {
+			return err
+		}
+	}
 
 	symbol = sm.exchange.ToLocSymbol(symbol)
 	orderType := orderTypeWire{
@@ -153,6 +153,7 @@ type universe struct {
 	Name         string `json:"name"`
 	MaxLeverage  int    `json:"maxLeverage"`
 	OnlyIsolated bool   `json:"onlyIsolated"`
+	IsDelisted   bool   `json:"isDelisted"`
 }
 
 type tifType string
@@ -257,6 +258,11 @@ type marketInfoResponse struct {
 	Universe []universe `json:"universe"`
 }
 
+type userFeesResponse struct {
+	UserCrossRate string `json:"userCrossRate"` // taker fee
+	UserAddRate   string `json:"userAddRate"`   // maker fee
+}
+
 type openOrderResponse struct {
 	Status   string `json:"status"`
 	Response struct {
@@ -267,6 +273,9 @@ type openOrderResponse struct {
 				Resting struct {
 					Oid int64 `json:"oid,omitempty"`
 				} `json:"resting,omitempty"`
+				Filled struct {
+					Oid int64 `json:"oid,omitempty"`
+				} `json:"filled,omitempty"`
 			} `json:"statuses"`
 		} `json:"data"`
 	} `json:"response"`
@@ -6,16 +6,27 @@ import (
 	"lfg/pkg/http"
 	"lfg/pkg/market"
 	"lfg/pkg/types"
+	"lfg/pkg/utils"
 	"math"
 
 	"github.com/ethereum/go-ethereum/common/hexutil"
+	log "github.com/sirupsen/logrus"
 )
 
 // ref: https://hyperliquid.gitbook.io/hyperliquid-docs/for-developers/api/tick-and-lot-size
 const MAX_PRICE_SIG_FIGURE = 5
 const MAX_PRICE_DECIMALS = 6
 
-func loadMarkets(baseUrl string) (map[string]*market.Market, error) {
+// ref: https://hyperliquid.gitbook.io/hyperliquid-docs/trading/fees
+const BASE_MAKER_FEE_PCT = 0.0001  // 1 bps, used when the user fees are unavailable
+const BASE_TAKER_FEE_PCT = 0.00035 // 3.5 bps, used when the user fees are unavailable
+
+// deriveUniSymbol returns the universal symbol of a market e.g. `XYZ` -> `XYZ_USD`
+func deriveUniSymbol(locSymbol string) (string, bool) {
+	return locSymbol + "_USD", locSymbol != ""
+}
+
+func loadMarkets(baseUrl string, address string) (map[string]*market.Market, error) {
 	// retrieve market filters from api
 	var marketInfos marketInfoResponse
 	reqBody, err := json.Marshal(map[string]string{
@@ -34,11 +45,19 @@ func loadMarkets(baseUrl string) (map[string]*market.Market, error) {
 	if err := json.Unmarshal(resBody, &marketInfos); err != nil {
 		return nil, err
 	}
+	makerFeePct, takerFeePct, err := getUserFees(baseUrl, address)
+	if err != nil {
+		log.Warnf("fail to get user fees, default to the base fee tier: %v", err)
+	}
 
 	// map into market.Market
 	var markets = make(map[string]*market.Market)
 	for id, marketFilter := range marketInfos.Universe {
-		// TODO: complete all field mapping
+		// @dev: delisted markets stay in the universe to keep the asset index of the others
+		if marketFilter.IsDelisted {
+			continue
+		}
+		// HPL has no max order size, LotMaxQty & MarketLotMaxQty are not enforced
 		market := market.New(types.ExchangeHpl, int64(id), marketFilter.Name)
 		market.LotMinQty = math.Pow(10, float64(-marketFilter.SzDecimals))
 		market.LotStepSize = math.Pow(10, float64(-marketFilter.SzDecimals))
@@ -47,17 +66,48 @@ func loadMarkets(baseUrl string) (map[string]*market.Market, error) {
 		priceDecimals := MAX_PRICE_DECIMALS - marketFilter.SzDecimals
 		market.TickSize = math.Pow(10, float64(-priceDecimals))
 		market.MaxLeverage = float64(marketFilter.MaxLeverage)
+		market.MakerFeePct = makerFeePct
+		market.TakerFeePct = takerFeePct
 
 		// ref: https://hyperliquid.gitbook.io/hyperliquid-docs/trading/fees
 		market.MinNotional = 10
-		market.MakerFeePct = 0.0001  // 1 bps
-		market.TakerFeePct = 0.00035 // 3.5 bps
 
 		markets[market.Symbol] = market
 	}
 	return markets, nil
 }
 
+// getUserFees returns the maker & taker fees of the account fee tier; base tier fees are returned on error
+func getUserFees(baseUrl string, address string) (float64, float64, error) {
+	reqBody, err := json.Marshal(metadataRequest{
+		Type: "userFees",
+		User: address,
+	})
+	if err != nil {
+		return BASE_MAKER_FEE_PCT, BASE_TAKER_FEE_PCT, err
+	}
+	status, resBody, err := http.PostRequest(fmt.Sprintf("%s/info", baseUrl), "", reqBody)
+	if err != nil {
+		return BASE_MAKER_FEE_PCT, BASE_TAKER_FEE_PCT, err
+	}
+	if status != "200 OK" {
+		return BASE_MAKER_FEE_PCT, BASE_TAKER_FEE_PCT, fmt.Errorf("status: %v: %v", status, string(resBody))
+	}
+	var res userFeesResponse
+	if err := json.Unmarshal(resBody, &res); err != nil {
+		return BASE_MAKER_FEE_PCT, BASE_TAKER_FEE_PCT, err
+	}
+	makerFeePct, err := utils.StrToFloat(res.UserAddRate)
+	if err != nil {
+		return BASE_MAKER_FEE_PCT, BASE_TAKER_FEE_PCT, err
+	}
+	takerFeePct, err := utils.StrToFloat(res.UserCrossRate)
+	if err != nil {
+		return BASE_MAKER_FEE_PCT, BASE_TAKER_FEE_PCT, err
+	}
+	return makerFeePct, takerFeePct, nil
+}
+
 func getRsvSignature(r [32]byte, s [32]byte, v byte) RsvSignature {
 	return RsvSignature{
 		R: hexutil.Encode(r[:]),
@@ -5,18 +5,15 @@ import (
 	"lfg/pkg/types"
 )
 
-func CalculateRVI(klines []types.KLineEvent) (float64, error) {
-
-	period := len(klines)
-
-	if period == 0 {
-		return 0, fmt.Errorf("no klines data available")
+func CalculateRVI(klines []types.KLineEvent, window int) (float64, error) {
+	if len(klines) < window+1 {
+		return 0, fmt.Errorf("insufficient klines candle: have %v/%v", len(klines), window+1)
 	}
 
-	upMoves := make([]float64, period)
-	downMoves := make([]float64, period)
+	upMoves := make([]float64, window)
+	downMoves := make([]float64, window)
 
-	for i := 1; i <= period; i++ {
+	for i := 1; i <= window; i++ {
 		change := klines[len(klines)-i].Kline.C - klines[len(klines)-i-1].Kline.C
 		if change > 0 {
 			upMoves[i-1] = change
@@ -29,6 +26,9 @@ func CalculateRVI(klines []types.KLineEvent) (float64, error) {
 
 	upStdDev := CalculateSD(upMoves, CalculateAverage(upMoves))
 	downStdDev := CalculateSD(downMoves, CalculateAverage(downMoves))
+	if upStdDev+downStdDev == 0 {
+		return 50, nil // flat price, no direction
+	}
 
 	rvi := 100 * upStdDev / (upStdDev + downStdDev)
 	return rvi, nil
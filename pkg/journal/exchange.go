@@ -0,0 +1,86 @@
+package journal
+
+import (
+	"lfg/pkg/exchange"
+	"lfg/pkg/types"
+)
+
+// Exchange records every order sent through the wrapped exchange, including failed ones
+type Exchange struct {
+	exchange.Exchange
+	recorder *Recorder
+}
+
+func NewExchange(exchg exchange.Exchange, recorder *Recorder) *Exchange {
+	return &Exchange{Exchange: exchg, recorder: recorder}
+}
+
+// WithScope returns the exchange recording its orders under the run & task
+func (e *Exchange) WithScope(runId string, task string) *Exchange {
+	return &Exchange{Exchange: e.Exchange, recorder: e.recorder.WithScope(runId, task)}
+}
+
+func (e *Exchange) OpenMarketOrder(symbol string, side types.OrderSide, qty float64, lev int, reduceOnly bool) error {
+	err := e.Exchange.OpenMarketOrder(symbol, side, qty, lev, reduceOnly)
+	e.record(OrderRecord{Action: "open", Symbol: symbol, Side: side, Type: types.OrderMarket, Qty: qty, Lev: lev, ReduceOnly: reduceOnly}, err)
+	return err
+}
+
+func (e *Exchange) OpenLimitOrder(symbol string, side types.OrderSide, price float64, qty float64, lev int, reduceOnly bool, tif types.OrderTIF, cloId string) (string, error) {
+	oId, err := e.Exchange.OpenLimitOrder(symbol, side, price, qty, lev, reduceOnly, tif, cloId)
+	record := OrderRecord{Action: "open", Symbol: symbol, Side: side, Type: types.OrderLimit, Price: price, Qty: qty, Lev: lev, ReduceOnly: reduceOnly}
+	if oId != "" {
+		record.OrderIds = []string{oId}
+	}
+	e.record(record, err)
+	return oId, err
+}
+
+// each order of the batch is recorded separately, with the batch error if it has no id
+func (e *Exchange) OpenBatchLimitOrders(symbol string, inputs []types.LimitOrderInput, lev int) ([]string, error) {
+	oIds, err := e.Exchange.OpenBatchLimitOrders(symbol, inputs, lev)
+	for i, input := range inputs {
+		record := OrderRecord{Action: "open", Symbol: symbol, Side: input.Side, Type: types.OrderLimit, Price: input.Price, Qty: input.Qty, Lev: lev, ReduceOnly: input.ReduceOnly}
+		if i < len(oIds) && oIds[i] != "" {
+			record.OrderIds = []string{oIds[i]}
+			e.record(record, nil)
+			continue
+		}
+		e.record(record, err)
+	}
+	return oIds, err
+}
+
+func (e *Exchange) CancelOrder(symbol string, orderId string, cloId string) error {
+	err := e.Exchange.CancelOrder(symbol, orderId, cloId)
+	e.record(OrderRecord{Action: "cancel", Symbol: symbol, OrderIds: []string{orderId}}, err)
+	return err
+}
+
+// no order id means all orders of the symbol
+func (e *Exchange) CancelAllOrders(symbol string) error {
+	err := e.Exchange.CancelAllOrders(symbol)
+	e.record(OrderRecord{Action: "cancel", Symbol: symbol}, err)
+	return err
+}
+
+func (e *Exchange) CancelBatchOrders(symbol string, orderIds []string) error {
+	err := e.Exchange.CancelBatchOrders(symbol, orderIds)
+	e.record(OrderRecord{Action: "cancel", Symbol: symbol, OrderIds: orderIds}, err)
+	return err
+}
+
+func (e *Exchange) CloseActivePositionByMarket(symbol string, lev int) error {
+	err := e.Exchange.CloseActivePositionByMarket(symbol, lev)
+	e.record(OrderRecord{Action: "close", Symbol: symbol, Type: types.OrderMarket, Lev: lev, ReduceOnly: true}, err)
+	return err
+}
+
+func (e *Exchange) record(order OrderRecord, err error) {
+	order.Exchange = e.Name()
+	entry := Entry{Kind: types.JournalOrder, Order: &order}
+	if err != nil {
+		entry.Error = err.Error()
+	}
+	e.recorder.Record(entry)
+}
@@ -0,0 +1,261 @@
+package journal
+
+import (
+	"encoding/json"
+	"fmt"
+	"lfg/pkg/storage"
+	"lfg/pkg/types"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	JOURNAL_SIZE    = 5000  // entries kept in memory across all agents
+	MAX_TEXT_CHARS  = 20000 // prompts & responses are truncated beyond, prompts keep their end
+	MAX_VALUE_CHARS = 2000  // memory values are truncated beyond
+)
+
+// Entry is a decision of an agent: a model call, a memory write or an order
+type Entry struct {
+	Time    time.Time         `json:"time"`
+	AgentId string            `json:"agentId"`
+	RunId   string            `json:"runId"` // `plan-<time>` while planning
+	Task    string            `json:"task"`  // task or tool name, `planner` while planning
+	Kind    types.JournalKind `json:"kind"`
+
+	// llm only
+	Model            string  `json:"model,omitempty"`
+	Prompt           string  `json:"prompt,omitempty"` // json encoded messages, the latest are kept if truncated
+	Response         string  `json:"response,omitempty"`
+	PromptTokens     int64   `json:"promptTokens,omitempty"`
+	CompletionTokens int64   `json:"completionTokens,omitempty"`
+	LatencyMs        int64   `json:"latencyMs,omitempty"`
+	CostUsd          float64 `json:"costUsd,omitempty"` // estimated from the model pricing
+
+	// memory only
+	Key   string `json:"key,omitempty"`
+	Value string `json:"value,omitempty"`
+
+	// order only
+	Order *OrderRecord `json:"order,omitempty"`
+
+	Error string `json:"error,omitempty"`
+}
+
+type OrderRecord struct {
+	Exchange   types.ExchangeName `json:"exchange"`
+	Action     string             `json:"action"` // `open` | `cancel` | `close`
+	Symbol     string             `json:"symbol"`
+	Side       types.OrderSide    `json:"side,omitempty"`
+	Type       types.OrderType    `json:"type,omitempty"`
+	Price      float64            `json:"price,omitempty"`
+	Qty        float64            `json:"qty,omitempty"`
+	Lev        int                `json:"lev,omitempty"`
+	ReduceOnly bool               `json:"reduceOnly,omitempty"`
+	OrderIds   []string           `json:"orderIds,omitempty"`
+}
+
+// Journal keeps the latest entries of all agents; with a store, the entries of each
+// run (and planning) are also persisted to `journal/<agentId>/<runId>.json`
+type Journal struct {
+	mu      sync.RWMutex
+	entries []Entry            // oldest first
+	runs    map[string][]Entry // `<agentId>/<runId>` -> entries of the started runs, until persisted
+	store   storage.Store
+}
+
+// store is optional
+func New(store storage.Store) *Journal {
+	return &Journal{
+		entries: []Entry{},
+		runs:    make(map[string][]Entry),
+		store:   store,
+	}
+}
+
+func (j *Journal) Add(entry Entry) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.entries = append(j.entries, entry)
+	if len(j.entries) > JOURNAL_SIZE {
+		j.entries = j.entries[len(j.entries)-JOURNAL_SIZE:]
+	}
+	// @dev: runs are buffered apart from the shared entries, which may drop the
+	// start of a long run while other agents are recording
+	if runEntries, started := j.runs[runKey(entry.AgentId, entry.RunId)]; started {
+		j.runs[runKey(entry.AgentId, entry.RunId)] = append(runEntries, entry)
+	}
+}
+
+// Filter fields are ignored when empty
+type Filter struct {
+	AgentId string
+	RunId   string
+	Task    string
+	Kind    types.JournalKind
+	Limit   int
+}
+
+func (f Filter) match(entry Entry) bool {
+	return (f.AgentId == "" || entry.AgentId == f.AgentId) &&
+		(f.RunId == "" || entry.RunId == f.RunId) &&
+		(f.Task == "" || entry.Task == f.Task) &&
+		(f.Kind == "" || entry.Kind == f.Kind)
+}
+
+// Query returns the matching entries, most recent first
+func (j *Journal) Query(filter Filter) []Entry {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+	entries := []Entry{}
+	for i := len(j.entries) - 1; i >= 0; i-- {
+		if filter.Limit > 0 && len(entries) >= filter.Limit {
+			break
+		}
+		if filter.match(j.entries[i]) {
+			entries = append(entries, j.entries[i])
+		}
+	}
+	return entries
+}
+
+type Usage struct {
+	Calls            int     `json:"calls"`
+	PromptTokens     int64   `json:"promptTokens"`
+	CompletionTokens int64   `json:"completionTokens"`
+	CostUsd          float64 `json:"costUsd"`
+}
+
+// Summarize totals the model usage of the entries
+func Summarize(entries []Entry) Usage {
+	usage := Usage{}
+	for _, entry := range entries {
+		if entry.Kind != types.JournalLLM {
+			continue
+		}
+		usage.Calls++
+		usage.PromptTokens += entry.PromptTokens
+		usage.CompletionTokens += entry.CompletionTokens
+		usage.CostUsd += entry.CostUsd
+	}
+	return usage
+}
+
+func runKey(agentId string, runId string) string {
+	return agentId + "/" + runId
+}
+
+// startRun buffers the entries of the run to persist them; no-op without a store
+func (j *Journal) startRun(agentId string, runId string) {
+	if j.store == nil {
+		return
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if _, started := j.runs[runKey(agentId, runId)]; !started {
+		j.runs[runKey(agentId, runId)] = []Entry{}
+	}
+}
+
+// endRun returns the buffered entries of the run, oldest first, and stops buffering it
+func (j *Journal) endRun(agentId string, runId string) []Entry {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	entries := j.runs[runKey(agentId, runId)]
+	delete(j.runs, runKey(agentId, runId))
+	return entries
+}
+
+// persist writes the entries of the run to the store
+func (j *Journal) persist(agentId string, runId string, entries []Entry) error {
+	if j.store == nil || len(entries) == 0 {
+		return nil
+	}
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return fmt.Errorf("fail to marshal journal: %w", err)
+	}
+	if err := j.store.Put(fmt.Sprintf("journal/%v/%v.json", agentId, runId), data); err != nil {
+		return fmt.Errorf("fail to store journal: %w", err)
+	}
+	return nil
+}
+
+// MARK: Recorder
+
+// Recorder records the entries of an agent under a run & task; a recorder is never
+// rescoped, WithScope returns a new one for each run or task so concurrent callers do not mix
+// their scopes. Methods are no-op on a nil recorder
+type Recorder struct {
+	journal *Journal
+	agentId string
+	runId   string
+	task    string
+}
+
+func (j *Journal) NewRecorder(agentId string) *Recorder {
+	return &Recorder{journal: j, agentId: agentId}
+}
+
+// WithScope returns a recorder of the agent recording under the run & task
+func (r *Recorder) WithScope(runId string, task string) *Recorder {
+	if r == nil {
+		return nil
+	}
+	return &Recorder{journal: r.journal, agentId: r.agentId, runId: runId, task: task}
+}
+
+// Record adds the entry, filling the time, agent, run & task when not set
+func (r *Recorder) Record(entry Entry) {
+	if r == nil {
+		return
+	}
+	if entry.RunId == "" && entry.Task == "" {
+		entry.RunId, entry.Task = r.runId, r.task
+	}
+	if entry.Time.IsZero() {
+		entry.Time = time.Now()
+	}
+	entry.AgentId = r.agentId
+	// @dev: the question, data & tool results explaining a decision are at the end of the prompt
+	entry.Prompt = truncateHead(entry.Prompt, MAX_TEXT_CHARS)
+	entry.Response = truncate(entry.Response, MAX_TEXT_CHARS)
+	entry.Value = truncate(entry.Value, MAX_VALUE_CHARS)
+	r.journal.Add(entry)
+}
+
+// StartRun keeps the entries of the run until EndRun persists them
+func (r *Recorder) StartRun(runId string) {
+	if r == nil || runId == "" {
+		return
+	}
+	r.journal.startRun(r.agentId, runId)
+}
+
+// EndRun persists the entries of the run recorded since StartRun
+func (r *Recorder) EndRun(runId string) {
+	if r == nil || runId == "" {
+		return
+	}
+	if err := r.journal.persist(r.agentId, runId, r.journal.endRun(r.agentId, runId)); err != nil {
+		log.WithFields(log.Fields{"agent": r.agentId}).Warnf("fail to persist journal of run %v: %v", runId, err)
+	}
+}
+
+// truncate keeps the first maxChars characters
+func truncate(str string, maxChars int) string {
+	if len(str) > maxChars {
+		return str[:maxChars] + "...(truncated)"
+	}
+	return str
+}
+
+// truncateHead keeps the last maxChars characters
+func truncateHead(str string, maxChars int) string {
+	if len(str) > maxChars {
+		return "(truncated)..." + str[len(str)-maxChars:]
+	}
+	return str
+}
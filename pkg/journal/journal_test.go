@@ -0,0 +1,243 @@
+package journal
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"lfg/pkg/exchange"
+	"lfg/pkg/storage"
+	"lfg/pkg/types"
+	"strings"
+	"sync"
+	"testing"
+)
+
+type memStore struct {
+	mu   sync.Mutex
+	data map[string][]byte
+}
+
+func (s *memStore) Get(key string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	data, exists := s.data[key]
+	if !exists {
+		return nil, storage.ErrNotFound
+	}
+	return data, nil
+}
+
+func (s *memStore) Put(key string, data []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data[key] = data
+	return nil
+}
+
+func (s *memStore) entries(t *testing.T, key string) []Entry {
+	data, err := s.Get(key)
+	if err != nil {
+		t.Fatalf("fail to get %v: %v", key, err)
+	}
+	entries := []Entry{}
+	if err := json.Unmarshal(data, &entries); err != nil {
+		t.Fatalf("fail to unmarshal %v: %v", key, err)
+	}
+	return entries
+}
+
+func TestRecorder(t *testing.T) {
+	j := New(nil)
+	recorder := j.NewRecorder("agent-1")
+
+	scoped := recorder.WithScope("run-1", "askAI")
+	scoped.Record(Entry{Kind: types.JournalLLM, Prompt: strings.Repeat("p", MAX_TEXT_CHARS) + "question", Response: "yes"})
+	scoped.Record(Entry{Kind: types.JournalMemory, Key: "klines", Value: strings.Repeat("v", MAX_VALUE_CHARS+1)})
+	scoped.Record(Entry{Kind: types.JournalMemory, RunId: "run-0", Task: "trigger", Key: "event"})
+
+	entries := j.Query(Filter{})
+	if len(entries) != 3 {
+		t.Fatalf("entries = %v, want 3", len(entries))
+	}
+	// most recent first
+	if entries[0].RunId != "run-0" || entries[0].Task != "trigger" {
+		t.Fatalf("explicit scope = %v/%v, want run-0/trigger", entries[0].RunId, entries[0].Task)
+	}
+	for _, entry := range entries[1:] {
+		if entry.AgentId != "agent-1" || entry.RunId != "run-1" || entry.Task != "askAI" || entry.Time.IsZero() {
+			t.Fatalf("entry = %+v, want agent-1 run-1/askAI at a time", entry)
+		}
+	}
+	if value := entries[1].Value; !strings.HasSuffix(value, "...(truncated)") || len(value) != MAX_VALUE_CHARS+len("...(truncated)") {
+		t.Fatalf("memory value not truncated: %v chars", len(value))
+	}
+	// the end of the prompt holds the question
+	if prompt := entries[2].Prompt; !strings.HasPrefix(prompt, "(truncated)...") || !strings.HasSuffix(prompt, "question") {
+		t.Fatalf("prompt not truncated from its head: %v...", prompt[:20])
+	}
+
+	// scoping returns a new recorder
+	recorder.Record(Entry{Kind: types.JournalMemory, Key: "snapshot"})
+	if entry := j.Query(Filter{Limit: 1})[0]; entry.RunId != "" || entry.Task != "" {
+		t.Fatalf("unscoped entry = %v/%v, want no run", entry.RunId, entry.Task)
+	}
+
+	var nilRecorder *Recorder
+	nilRecorder.WithScope("run-1", "askAI").Record(Entry{Kind: types.JournalLLM})
+	nilRecorder.StartRun("run-1")
+	nilRecorder.EndRun("run-1")
+}
+
+func TestQuery(t *testing.T) {
+	j := New(nil)
+	for _, entry := range []Entry{
+		{AgentId: "a", RunId: "run-1", Task: "getKlines", Kind: types.JournalMemory},
+		{AgentId: "a", RunId: "run-1", Task: "askAI", Kind: types.JournalLLM},
+		{AgentId: "b", RunId: "run-2", Task: "askAI", Kind: types.JournalLLM},
+		{AgentId: "a", RunId: "run-3", Task: "openMarketLongPositionIf", Kind: types.JournalOrder},
+	} {
+		j.Add(entry)
+	}
+
+	count := func(filter Filter) int { return len(j.Query(filter)) }
+	if n := count(Filter{AgentId: "a"}); n != 3 {
+		t.Errorf("agent a: %v entries, want 3", n)
+	}
+	if n := count(Filter{AgentId: "a", Kind: types.JournalLLM}); n != 1 {
+		t.Errorf("llm calls of agent a: %v entries, want 1", n)
+	}
+	if n := count(Filter{Task: "askAI"}); n != 2 {
+		t.Errorf("askAI: %v entries, want 2", n)
+	}
+	if entries := j.Query(Filter{AgentId: "a", Limit: 2}); len(entries) != 2 || entries[0].RunId != "run-3" || entries[1].RunId != "run-1" {
+		t.Errorf("latest 2 entries of agent a = %+v", entries)
+	}
+
+	for i := 0; i < JOURNAL_SIZE; i++ {
+		j.Add(Entry{AgentId: "c"})
+	}
+	if n := count(Filter{}); n != JOURNAL_SIZE {
+		t.Errorf("journal keeps %v entries, want %v", n, JOURNAL_SIZE)
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	usage := Summarize([]Entry{
+		{Kind: types.JournalLLM, PromptTokens: 1000, CompletionTokens: 100, CostUsd: 0.002},
+		{Kind: types.JournalMemory, Key: "signal"},
+		{Kind: types.JournalLLM, PromptTokens: 500, CompletionTokens: 50, CostUsd: 0.001},
+	})
+	if usage.Calls != 2 || usage.PromptTokens != 1500 || usage.CompletionTokens != 150 || usage.CostUsd < 0.003-1e-12 || usage.CostUsd > 0.003+1e-12 {
+		t.Fatalf("usage = %+v", usage)
+	}
+}
+
+func TestEndRunPersistsTheRun(t *testing.T) {
+	store := &memStore{data: make(map[string][]byte)}
+	j := New(store)
+	recorder := j.NewRecorder("agent-1")
+	other := j.NewRecorder("agent-2")
+
+	recorder.StartRun("plan-1")
+	recorder.WithScope("plan-1", "planner").Record(Entry{Kind: types.JournalLLM, Response: "plan"})
+	recorder.EndRun("plan-1")
+	if entries := store.entries(t, "journal/agent-1/plan-1.json"); len(entries) != 1 || entries[0].Task != "planner" {
+		t.Fatalf("planning journal = %+v", entries)
+	}
+
+	recorder.StartRun("run-1")
+	other.StartRun("run-1")
+	recorder.WithScope("run-1", "getKlines").Record(Entry{Kind: types.JournalMemory, Key: "klines"})
+	other.WithScope("run-1", "askAI").Record(Entry{Kind: types.JournalLLM})
+	// the run outlives the shared entries
+	for i := 0; i < JOURNAL_SIZE; i++ {
+		j.Add(Entry{AgentId: "agent-3"})
+	}
+	recorder.WithScope("run-1", "askAI").Record(Entry{Kind: types.JournalLLM})
+	recorder.EndRun("run-1")
+
+	entries := store.entries(t, "journal/agent-1/run-1.json")
+	if len(entries) != 2 || entries[0].Task != "getKlines" || entries[1].Task != "askAI" {
+		t.Fatalf("run journal = %+v, want the 2 entries of agent-1 oldest first", entries)
+	}
+	if _, err := store.Get("journal/agent-2/run-1.json"); err == nil {
+		t.Fatalf("run of agent-2 persisted before it ended")
+	}
+
+	// an ended run is no longer buffered
+	recorder.WithScope("run-1", "late").Record(Entry{Kind: types.JournalMemory})
+	if entries := j.endRun("agent-1", "run-1"); len(entries) != 0 {
+		t.Fatalf("entries buffered after the run ended: %+v", entries)
+	}
+}
+
+func TestConcurrentScopes(t *testing.T) {
+	j := New(&memStore{data: make(map[string][]byte)})
+	recorder := j.NewRecorder("agent-1")
+	recorder.StartRun("run-1")
+	recorder.StartRun("run-2")
+
+	// e.g. a trigger run while a scheduled run is in progress
+	var wg sync.WaitGroup
+	for _, runId := range []string{"run-1", "run-2"} {
+		wg.Add(1)
+		go func(scoped *Recorder) {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				scoped.Record(Entry{Kind: types.JournalMemory})
+			}
+		}(recorder.WithScope(runId, "task-"+runId))
+	}
+	wg.Wait()
+
+	for _, runId := range []string{"run-1", "run-2"} {
+		entries := j.endRun("agent-1", runId)
+		if len(entries) != 100 {
+			t.Fatalf("%v: %v entries, want 100", runId, len(entries))
+		}
+		for _, entry := range entries {
+			if entry.RunId != runId || entry.Task != "task-"+runId {
+				t.Fatalf("%v: entry recorded under %v/%v", runId, entry.RunId, entry.Task)
+			}
+		}
+	}
+}
+
+// batchExchange places every other order of a batch
+type batchExchange struct {
+	exchange.Exchange
+}
+
+func (e *batchExchange) Name() types.ExchangeName { return types.ExchangeDummy }
+
+func (e *batchExchange) OpenBatchLimitOrders(symbol string, inputs []types.LimitOrderInput, lev int) ([]string, error) {
+	oIds := make([]string, len(inputs))
+	for i := 0; i < len(inputs); i += 2 {
+		oIds[i] = fmt.Sprint(i + 1)
+	}
+	return oIds, errors.New("order #2: rejected")
+}
+
+func TestExchangeRecordsBatchOrders(t *testing.T) {
+	j := New(nil)
+	exchg := NewExchange(&batchExchange{}, j.NewRecorder("agent-1").WithScope("run-1", "openLimitLadderIf"))
+	inputs := []types.LimitOrderInput{{Price: 100, Qty: 1}, {Price: 99, Qty: 1}, {Price: 98, Qty: 1}}
+	if _, err := exchg.OpenBatchLimitOrders("BTC_USD", inputs, 1); err == nil {
+		t.Fatalf("want the batch error")
+	}
+
+	entries := j.Query(Filter{Kind: types.JournalOrder})
+	if len(entries) != 3 {
+		t.Fatalf("entries = %+v, want one per order", entries)
+	}
+	// most recent first
+	for i, want := range []struct {
+		oId     string
+		wantErr bool
+	}{{"3", false}, {"", true}, {"1", false}} {
+		entry := entries[i]
+		if hasErr := entry.Error != ""; hasErr != want.wantErr || (want.oId != "" && (len(entry.Order.OrderIds) != 1 || entry.Order.OrderIds[0] != want.oId)) {
+			t.Fatalf("entry #%v = %+v (order %+v), want id %q, error %v", i, entry, entry.Order, want.oId, want.wantErr)
+		}
+	}
+}
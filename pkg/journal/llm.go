@@ -0,0 +1,55 @@
+package journal
+
+import (
+	"context"
+	"encoding/json"
+	"lfg/pkg/llm"
+	"lfg/pkg/types"
+	"time"
+)
+
+// LLMClient records every completion of the wrapped client
+type LLMClient struct {
+	llm.Client
+	recorder *Recorder
+	pricing  llm.Pricing
+}
+
+func NewLLMClient(client llm.Client, recorder *Recorder, pricing llm.Pricing) *LLMClient {
+	return &LLMClient{Client: client, recorder: recorder, pricing: pricing}
+}
+
+// WithScope returns the client recording its completions under the run & task
+func (c *LLMClient) WithScope(runId string, task string) *LLMClient {
+	return &LLMClient{Client: c.Client, recorder: c.recorder.WithScope(runId, task), pricing: c.pricing}
+}
+
+func (c *LLMClient) Complete(ctx context.Context, req llm.Request) (llm.Response, error) {
+	start := time.Now()
+	res, err := c.Client.Complete(ctx, req)
+	entry := Entry{
+		Kind:             types.JournalLLM,
+		Model:            c.Model(),
+		PromptTokens:     res.Usage.PromptTokens,
+		CompletionTokens: res.Usage.CompletionTokens,
+		LatencyMs:        time.Since(start).Milliseconds(),
+		CostUsd:          c.pricing.Cost(res.Usage),
+	}
+	if res.Model != "" {
+		entry.Model = res.Model
+	}
+	if prompt, jsonErr := json.Marshal(req.Messages); jsonErr == nil {
+		entry.Prompt = string(prompt)
+	}
+	entry.Response = res.Message.Content
+	if len(res.Message.ToolCalls) > 0 {
+		if toolCalls, jsonErr := json.Marshal(res.Message.ToolCalls); jsonErr == nil {
+			entry.Response = string(toolCalls)
+		}
+	}
+	if err != nil {
+		entry.Error = err.Error()
+	}
+	c.recorder.Record(entry)
+	return res, err
+}
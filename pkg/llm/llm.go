@@ -0,0 +1,100 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"lfg/config"
+	"lfg/pkg/types"
+)
+
+const (
+	DEFAULT_MODEL       = "openai/gpt-4o-mini"
+	DEFAULT_TEMPERATURE = 0.23
+	DEFAULT_BASE_URL    = "https://openrouter.ai/api/v1/"
+	DEFAULT_API_KEY_ENV = "OPENAI_API_KEY"
+)
+
+type Role string
+
+const (
+	RoleSystem    = Role("system")
+	RoleUser      = Role("user")
+	RoleAssistant = Role("assistant")
+	RoleTool      = Role("tool")
+)
+
+type Message struct {
+	Role       Role       `json:"role"`
+	Content    string     `json:"content"`
+	ToolCalls  []ToolCall `json:"toolCalls,omitempty"`  // assistant only
+	ToolCallId string     `json:"toolCallId,omitempty"` // tool only: the call this message answers
+}
+
+// Tool is a function the model may call; Parameters is a JSON schema
+type Tool struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Parameters  any    `json:"parameters"`
+}
+
+type ToolCall struct {
+	Id        string `json:"id"`
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"` // json encoded
+}
+
+// ResponseFormat constrains the response content to a JSON schema
+type ResponseFormat struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Schema      any    `json:"schema"`
+}
+
+type Request struct {
+	Messages       []Message       `json:"messages"`
+	Tools          []Tool          `json:"tools,omitempty"`
+	ResponseFormat *ResponseFormat `json:"responseFormat,omitempty"` // free text if nil
+}
+
+type Usage struct {
+	PromptTokens     int64 `json:"promptTokens"`
+	CompletionTokens int64 `json:"completionTokens"`
+}
+
+type Response struct {
+	Message Message `json:"message"`
+	Model   string  `json:"model"`
+	Usage   Usage   `json:"usage"`
+}
+
+// Client is a chat completion model with its settings (model, temperature, ...)
+type Client interface {
+	Model() string
+	Complete(ctx context.Context, req Request) (Response, error)
+}
+
+// creates a client based on the llm config; a nil config means the default openai client
+func New(llmConfig *config.LLMConfig) (Client, error) {
+	if llmConfig == nil {
+		llmConfig = &config.LLMConfig{}
+	}
+	switch llmConfig.Provider {
+	case "", types.LLMProviderOpenAI:
+		return NewOpenAIClient(llmConfig)
+	case types.LLMProviderScripted:
+		return NewScriptedClient(llmConfig.Script)
+	default:
+		return nil, fmt.Errorf("unsupported llm provider: %v", llmConfig.Provider)
+	}
+}
+
+// Text is a shorthand for a single user prompt answered with free text
+func Text(ctx context.Context, client Client, prompt string) (string, error) {
+	res, err := client.Complete(ctx, Request{
+		Messages: []Message{{Role: RoleUser, Content: prompt}},
+	})
+	if err != nil {
+		return "", err
+	}
+	return res.Message.Content, nil
+}
@@ -0,0 +1,168 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"lfg/config"
+	"os"
+
+	"github.com/openai/openai-go"
+	"github.com/openai/openai-go/option"
+)
+
+type OpenAIClient struct {
+	client      *openai.Client
+	model       string
+	temperature float64
+}
+
+func NewOpenAIClient(llmConfig *config.LLMConfig) (*OpenAIClient, error) {
+	baseUrl := llmConfig.BaseUrl
+	if baseUrl == "" {
+		baseUrl = os.Getenv("OPENAI_BASE_URL")
+	}
+	if baseUrl == "" {
+		baseUrl = DEFAULT_BASE_URL
+	}
+	apiKeyEnv := llmConfig.ApiKeyEnv
+	if apiKeyEnv == "" {
+		apiKeyEnv = DEFAULT_API_KEY_ENV
+	}
+	apiKey := os.Getenv(apiKeyEnv)
+	if apiKey == "" {
+		return nil, fmt.Errorf("%v is not set", apiKeyEnv)
+	}
+
+	c := &OpenAIClient{
+		client: openai.NewClient(
+			option.WithBaseURL(baseUrl),
+			option.WithAPIKey(apiKey),
+		),
+		model:       DEFAULT_MODEL,
+		temperature: DEFAULT_TEMPERATURE,
+	}
+	if llmConfig.Model != "" {
+		c.model = llmConfig.Model
+	}
+	if llmConfig.Temperature != nil {
+		c.temperature = *llmConfig.Temperature
+	}
+	return c, nil
+}
+
+func (c *OpenAIClient) Model() string {
+	return c.model
+}
+
+func (c *OpenAIClient) Complete(ctx context.Context, req Request) (Response, error) {
+	params := openai.ChatCompletionNewParams{
+		Messages:    openai.F(toOpenAIMessages(req.Messages)),
+		Model:       openai.F(c.model),
+		Temperature: openai.F(c.temperature),
+	}
+	if req.ResponseFormat != nil {
+		params.ResponseFormat = openai.F[openai.ChatCompletionNewParamsResponseFormatUnion](
+			openai.ResponseFormatJSONSchemaParam{
+				Type: openai.F(openai.ResponseFormatJSONSchemaTypeJSONSchema),
+				JSONSchema: openai.F(openai.ResponseFormatJSONSchemaJSONSchemaParam{
+					Name:        openai.F(req.ResponseFormat.Name),
+					Description: openai.F(req.ResponseFormat.Description),
+					Schema:      openai.F(req.ResponseFormat.Schema),
+				}),
+			},
+		)
+	}
+	if len(req.Tools) > 0 {
+		tools := []openai.ChatCompletionToolParam{}
+		for _, tool := range req.Tools {
+			parameters, err := toFunctionParameters(tool.Parameters)
+			if err != nil {
+				return Response{}, fmt.Errorf("invalid parameters of tool %v: %w", tool.Name, err)
+			}
+			tools = append(tools, openai.ChatCompletionToolParam{
+				Type: openai.F(openai.ChatCompletionToolTypeFunction),
+				Function: openai.F(openai.FunctionDefinitionParam{
+					Name:        openai.F(tool.Name),
+					Description: openai.F(tool.Description),
+					Parameters:  openai.F(parameters),
+				}),
+			})
+		}
+		params.Tools = openai.F(tools)
+	}
+
+	chatCompletion, err := c.client.Chat.Completions.New(ctx, params)
+	if err != nil {
+		return Response{}, err
+	}
+	if len(chatCompletion.Choices) == 0 {
+		return Response{}, fmt.Errorf("no completion found")
+	}
+
+	message := chatCompletion.Choices[0].Message
+	res := Response{
+		Message: Message{Role: RoleAssistant, Content: message.Content},
+		Model:   chatCompletion.Model,
+		Usage: Usage{
+			PromptTokens:     chatCompletion.Usage.PromptTokens,
+			CompletionTokens: chatCompletion.Usage.CompletionTokens,
+		},
+	}
+	for _, toolCall := range message.ToolCalls {
+		res.Message.ToolCalls = append(res.Message.ToolCalls, ToolCall{
+			Id:        toolCall.ID,
+			Name:      toolCall.Function.Name,
+			Arguments: toolCall.Function.Arguments,
+		})
+	}
+	return res, nil
+}
+
+func toOpenAIMessages(messages []Message) []openai.ChatCompletionMessageParamUnion {
+	res := []openai.ChatCompletionMessageParamUnion{}
+	for _, message := range messages {
+		switch message.Role {
+		case RoleSystem:
+			res = append(res, openai.SystemMessage(message.Content))
+		case RoleAssistant:
+			assistantMessage := openai.AssistantMessage(message.Content)
+			if len(message.ToolCalls) > 0 {
+				toolCalls := []openai.ChatCompletionMessageToolCallParam{}
+				for _, toolCall := range message.ToolCalls {
+					toolCalls = append(toolCalls, openai.ChatCompletionMessageToolCallParam{
+						ID:   openai.F(toolCall.Id),
+						Type: openai.F(openai.ChatCompletionMessageToolCallTypeFunction),
+						Function: openai.F(openai.ChatCompletionMessageToolCallFunctionParam{
+							Name:      openai.F(toolCall.Name),
+							Arguments: openai.F(toolCall.Arguments),
+						}),
+					})
+				}
+				assistantMessage.ToolCalls = openai.F(toolCalls)
+			}
+			res = append(res, assistantMessage)
+		case RoleTool:
+			res = append(res, openai.ToolMessage(message.ToolCallId, message.Content))
+		default:
+			res = append(res, openai.UserMessage(message.Content))
+		}
+	}
+	return res
+}
+
+// the sdk expects the schema as a plain map
+func toFunctionParameters(schema any) (openai.FunctionParameters, error) {
+	if schema == nil {
+		return openai.FunctionParameters{"type": "object", "properties": map[string]any{}}, nil
+	}
+	data, err := json.Marshal(schema)
+	if err != nil {
+		return nil, err
+	}
+	var parameters openai.FunctionParameters
+	if err := json.Unmarshal(data, &parameters); err != nil {
+		return nil, err
+	}
+	return parameters, nil
+}
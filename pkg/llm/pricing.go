@@ -0,0 +1,35 @@
+package llm
+
+import "lfg/config"
+
+// Pricing is the USD price per million tokens
+type Pricing struct {
+	Input  float64 `json:"input"`
+	Output float64 `json:"output"`
+}
+
+// prices of known models, used unless the llm config sets them
+var MODEL_PRICING = map[string]Pricing{
+	"openai/gpt-4o-mini": {Input: 0.15, Output: 0.6},
+	"openai/gpt-4o":      {Input: 2.5, Output: 10},
+	"gpt-4o-mini":        {Input: 0.15, Output: 0.6},
+	"gpt-4o":             {Input: 2.5, Output: 10},
+	SCRIPTED_MODEL:       {},
+}
+
+// GetPricing returns the pricing of the model, zero if it is neither configured nor known
+func GetPricing(llmConfig *config.LLMConfig, model string) Pricing {
+	pricing := MODEL_PRICING[model]
+	if llmConfig != nil && llmConfig.InputPrice > 0 {
+		pricing.Input = llmConfig.InputPrice
+	}
+	if llmConfig != nil && llmConfig.OutputPrice > 0 {
+		pricing.Output = llmConfig.OutputPrice
+	}
+	return pricing
+}
+
+// Cost is the estimated USD cost of the usage
+func (p Pricing) Cost(usage Usage) float64 {
+	return (float64(usage.PromptTokens)*p.Input + float64(usage.CompletionTokens)*p.Output) / 1e6
+}
@@ -0,0 +1,122 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+const SCRIPTED_MODEL = "scripted"
+
+// ScriptedClient replays canned responses so planning and runtime flows can run offline.
+//
+// The script maps a response format name (e.g. `ExecutionPlan`, `Feedback`) to the responses
+// returned in order; `text` is used for free text requests and `tool` for requests with tools.
+// The last response of a list is repeated once the list is exhausted.
+//
+//	ExecutionPlan:
+//	  - '{"Reasoning": "...", "Tasks": [...], "InitState": [...]}'
+//	Feedback:
+//	  - '{"Type": "CORRECT", "Feedback": "..."}'
+//	text:
+//	  - "yes"
+//	tool:
+//	  - '[{"name": "setMemory", "arguments": {"key": "symbol", "value": "BTC_USD"}}]'
+//	  - "done"
+//
+// A `tool` response that is a json array of calls is returned as tool calls.
+type ScriptedClient struct {
+	mu        sync.Mutex
+	responses map[string][]string
+	calls     map[string]int
+}
+
+func NewScriptedClient(scriptPath string) (*ScriptedClient, error) {
+	if scriptPath == "" {
+		return nil, fmt.Errorf("scripted llm requires a script")
+	}
+	data, err := os.ReadFile(scriptPath)
+	if err != nil {
+		return nil, fmt.Errorf("fail to read llm script: %w", err)
+	}
+	responses := make(map[string][]string)
+	if err := yaml.Unmarshal(data, &responses); err != nil {
+		return nil, fmt.Errorf("fail to parse llm script: %w", err)
+	}
+	return NewScriptedClientFromResponses(responses), nil
+}
+
+func NewScriptedClientFromResponses(responses map[string][]string) *ScriptedClient {
+	return &ScriptedClient{
+		responses: responses,
+		calls:     make(map[string]int),
+	}
+}
+
+func (c *ScriptedClient) Model() string {
+	return SCRIPTED_MODEL
+}
+
+func (c *ScriptedClient) Complete(ctx context.Context, req Request) (Response, error) {
+	name := "text"
+	if req.ResponseFormat != nil {
+		name = req.ResponseFormat.Name
+	} else if len(req.Tools) > 0 {
+		name = "tool"
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	responses := c.responses[name]
+	if len(responses) == 0 {
+		return Response{}, fmt.Errorf("no scripted response for %v", name)
+	}
+	idx := min(c.calls[name], len(responses)-1)
+	c.calls[name]++
+
+	res := Response{
+		Message: Message{Role: RoleAssistant, Content: responses[idx]},
+		Model:   SCRIPTED_MODEL,
+	}
+	if name == "tool" {
+		toolCalls, err := parseScriptedToolCalls(responses[idx], c.calls[name])
+		if err != nil {
+			return Response{}, err
+		}
+		if len(toolCalls) > 0 {
+			res.Message = Message{Role: RoleAssistant, ToolCalls: toolCalls}
+		}
+	}
+	return res, nil
+}
+
+func parseScriptedToolCalls(content string, call int) ([]ToolCall, error) {
+	if !strings.HasPrefix(strings.TrimSpace(content), "[") {
+		return nil, nil
+	}
+	var scripted []struct {
+		Name      string         `json:"name"`
+		Arguments map[string]any `json:"arguments"`
+	}
+	if err := json.Unmarshal([]byte(content), &scripted); err != nil {
+		return nil, fmt.Errorf("invalid scripted tool calls: %w", err)
+	}
+	toolCalls := []ToolCall{}
+	for i, toolCall := range scripted {
+		arguments, err := json.Marshal(toolCall.Arguments)
+		if err != nil {
+			return nil, fmt.Errorf("invalid scripted tool call arguments: %w", err)
+		}
+		toolCalls = append(toolCalls, ToolCall{
+			Id:        fmt.Sprintf("call_%d_%d", call, i),
+			Name:      toolCall.Name,
+			Arguments: string(arguments),
+		})
+	}
+	return toolCalls, nil
+}
@@ -2,6 +2,7 @@ package market
 
 import "lfg/pkg/types"
 
+// @dev: zero filters are unknown (or not applicable on the exchange) and not enforced
 type Market struct {
 	Id           int64 // market id (or index), usually for API usage
 	ExchangeName types.ExchangeName
@@ -18,7 +19,7 @@ type Market struct {
 	MaxLeverage       float64 // max leverage gearing
 
 	MakerFeePct float64 // maker fee (e.g. 0.0001 means 0.01% aka 1bps)
-	TakerFeePct float64 // maker fee (e.g. 0.0005 means 0.05% aka 5bps)
+	TakerFeePct float64 // taker fee (e.g. 0.0005 means 0.05% aka 5bps)
 }
 
 func New(exchangeName types.ExchangeName, id int64, symbol string) *Market {
@@ -0,0 +1,106 @@
+package market
+
+import (
+	"errors"
+	"fmt"
+	"lfg/pkg/types"
+	"lfg/pkg/utils"
+	"math"
+	"strings"
+)
+
+// ErrInvalidOrder is wrapped by every validation error of the normalizer,
+// so orders failing the market filters can be told apart from exchange rejections
+var ErrInvalidOrder = errors.New("invalid order")
+
+var (
+	ErrUnknownMarket = fmt.Errorf("%w: unknown market", ErrInvalidOrder)
+	ErrInvalidPrice  = fmt.Errorf("%w: invalid price", ErrInvalidOrder)
+	ErrMinQty        = fmt.Errorf("%w: qty below min", ErrInvalidOrder)
+	ErrMaxQty        = fmt.Errorf("%w: qty above max", ErrInvalidOrder)
+	ErrMinNotional   = fmt.Errorf("%w: notional below min", ErrInvalidOrder)
+	ErrMaxLeverage   = fmt.Errorf("%w: leverage above max", ErrInvalidOrder)
+)
+
+// RoundPrice rounds the price to the nearest tick, if known
+func (m *Market) RoundPrice(price float64) float64 {
+	if m.TickSize <= 0 {
+		return price
+	}
+	return utils.RoundFloat(math.Round(price/m.TickSize)*m.TickSize, stepDecimals(m.TickSize))
+}
+
+// RoundQty rounds the quantity down to the lot step size of the order type, if known
+func (m *Market) RoundQty(qty float64, orderType types.OrderType) float64 {
+	step := m.LotStepSize
+	if orderType == types.OrderMarket && m.MarketLotStepSize > 0 {
+		step = m.MarketLotStepSize
+	}
+	if step <= 0 {
+		return qty
+	}
+	// @dev: the epsilon keeps quantities already on a step from being floored to the step below
+	return utils.RoundFloat(math.Floor(qty/step+1e-9)*step, stepDecimals(step))
+}
+
+// NormalizeOrder returns the price & qty of the order rounded to the market filters,
+// or an ErrInvalidOrder error if the order would be rejected by the exchange.
+// `price` is the limit price; market orders pass a reference price to check the
+// min notional, or 0 to skip it. Reduce-only orders are exempt from the min notional.
+func (m *Market) NormalizeOrder(orderType types.OrderType, price float64, qty float64, reduceOnly bool) (float64, float64, error) {
+	if m == nil {
+		return 0, 0, ErrUnknownMarket
+	}
+	if orderType == types.OrderLimit && price <= 0 {
+		return 0, 0, fmt.Errorf("%w: %v of %v must be positive", ErrInvalidPrice, price, m.Symbol)
+	}
+	if price < 0 {
+		return 0, 0, fmt.Errorf("%w: %v of %v must not be negative", ErrInvalidPrice, price, m.Symbol)
+	}
+	if orderType == types.OrderLimit {
+		rounded := m.RoundPrice(price)
+		if rounded <= 0 {
+			return 0, 0, fmt.Errorf("%w: %v of %v rounds to 0 with tick size %v", ErrInvalidPrice, price, m.Symbol, m.TickSize)
+		}
+		price = rounded
+	}
+
+	rawQty := qty
+	qty = m.RoundQty(qty, orderType)
+	minQty, maxQty := m.LotMinQty, m.LotMaxQty
+	if orderType == types.OrderMarket {
+		if m.MarketLotMinQty > 0 {
+			minQty = m.MarketLotMinQty
+		}
+		if m.MarketLotMaxQty > 0 {
+			maxQty = m.MarketLotMaxQty
+		}
+	}
+	if qty <= 0 || qty < minQty {
+		return 0, 0, fmt.Errorf("%w: %v of %v is below %v once rounded to the lot step", ErrMinQty, rawQty, m.Symbol, minQty)
+	}
+	if maxQty > 0 && qty > maxQty {
+		return 0, 0, fmt.Errorf("%w: %v of %v is above %v", ErrMaxQty, qty, m.Symbol, maxQty)
+	}
+	if !reduceOnly && price > 0 && m.MinNotional > 0 && price*qty < m.MinNotional {
+		return 0, 0, fmt.Errorf("%w: %v USD of %v is below %v USD", ErrMinNotional, price*qty, m.Symbol, m.MinNotional)
+	}
+	return price, qty, nil
+}
+
+// CheckLeverage returns an ErrMaxLeverage error if the leverage exceeds the max leverage of the market, if known
+func (m *Market) CheckLeverage(lev int) error {
+	if m != nil && m.MaxLeverage > 0 && float64(lev) > m.MaxLeverage {
+		return fmt.Errorf("%w: %vx of %v exceeds %vx", ErrMaxLeverage, lev, m.Symbol, m.MaxLeverage)
+	}
+	return nil
+}
+
+// stepDecimals returns the decimals of a step size (e.g. 0.25 has 2), to drop float noise after rounding
+func stepDecimals(step float64) int64 {
+	str := utils.FloatToStr(step)
+	if idx := strings.IndexByte(str, '.'); idx >= 0 {
+		return int64(len(str) - idx - 1)
+	}
+	return 0
+}
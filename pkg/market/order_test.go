@@ -0,0 +1,195 @@
+package market
+
+import (
+	"errors"
+	"lfg/pkg/types"
+	"testing"
+)
+
+// btcusdt has stricter filters for market orders, like Binance futures
+var btcusdt = Market{
+	Symbol:            "BTCUSDT",
+	TickSize:          0.1,
+	MinNotional:       5,
+	LotMinQty:         0.001,
+	LotMaxQty:         1000,
+	LotStepSize:       0.001,
+	MarketLotMinQty:   0.01,
+	MarketLotMaxQty:   100,
+	MarketLotStepSize: 0.01,
+	MaxLeverage:       20,
+}
+
+func TestRoundPrice(t *testing.T) {
+	tests := []struct {
+		name     string
+		tickSize float64
+		price    float64
+		want     float64
+	}{
+		{"on tick", 0.1, 100.1, 100.1},
+		{"rounded down", 0.1, 100.14, 100.1},
+		{"rounded up", 0.1, 100.15, 100.2},
+		{"no float noise", 0.01, 0.29, 0.29},
+		{"tick above 1", 5, 1002.4, 1000},
+		{"unknown tick", 0, 100.123456, 100.123456},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &Market{TickSize: tt.tickSize}
+			if got := m.RoundPrice(tt.price); got != tt.want {
+				t.Fatalf("RoundPrice(%v) = %v, want %v", tt.price, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRoundQty(t *testing.T) {
+	tests := []struct {
+		name      string
+		orderType types.OrderType
+		qty       float64
+		want      float64
+	}{
+		{"limit on step", types.OrderLimit, 0.123, 0.123},
+		{"limit floored", types.OrderLimit, 0.1239, 0.123},
+		{"limit float noise on step", types.OrderLimit, 0.3 - 0.1 + 0.1, 0.3},
+		{"market uses the market step", types.OrderMarket, 0.1239, 0.12},
+		{"below step", types.OrderLimit, 0.0009, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := btcusdt.RoundQty(tt.qty, tt.orderType); got != tt.want {
+				t.Fatalf("RoundQty(%v) = %v, want %v", tt.qty, got, tt.want)
+			}
+		})
+	}
+	if got := (&Market{}).RoundQty(0.123456, types.OrderLimit); got != 0.123456 {
+		t.Fatalf("RoundQty without step = %v, want 0.123456", got)
+	}
+}
+
+func TestNormalizeOrder(t *testing.T) {
+	tests := []struct {
+		name       string
+		orderType  types.OrderType
+		price      float64
+		qty        float64
+		reduceOnly bool
+		wantPrice  float64
+		wantQty    float64
+		wantErr    error
+	}{
+		{
+			name:      "limit rounded",
+			orderType: types.OrderLimit, price: 100.04, qty: 0.0509,
+			wantPrice: 100, wantQty: 0.05,
+		},
+		{
+			name:      "limit without price",
+			orderType: types.OrderLimit, price: 0, qty: 1,
+			wantErr: ErrInvalidPrice,
+		},
+		{
+			name:      "limit price rounds to 0",
+			orderType: types.OrderLimit, price: 0.04, qty: 1000,
+			wantErr: ErrInvalidPrice,
+		},
+		{
+			name:      "negative reference price",
+			orderType: types.OrderMarket, price: -1, qty: 1,
+			wantErr: ErrInvalidPrice,
+		},
+		{
+			name:      "limit qty below min once rounded",
+			orderType: types.OrderLimit, price: 10000, qty: 0.0009,
+			wantErr: ErrMinQty,
+		},
+		{
+			name:      "market qty below market min",
+			orderType: types.OrderMarket, price: 10000, qty: 0.009,
+			wantErr: ErrMinQty,
+		},
+		{
+			name:      "limit qty above max",
+			orderType: types.OrderLimit, price: 10, qty: 1001,
+			wantErr: ErrMaxQty,
+		},
+		{
+			name:      "market qty above market max",
+			orderType: types.OrderMarket, price: 10, qty: 101,
+			wantErr: ErrMaxQty,
+		},
+		{
+			name:      "limit below min notional",
+			orderType: types.OrderLimit, price: 100, qty: 0.049,
+			wantErr: ErrMinNotional,
+		},
+		{
+			name:      "limit at min notional",
+			orderType: types.OrderLimit, price: 100, qty: 0.05,
+			wantPrice: 100, wantQty: 0.05,
+		},
+		{
+			name:      "reduce-only below min notional",
+			orderType: types.OrderLimit, price: 100, qty: 0.01,
+			reduceOnly: true,
+			wantPrice:  100, wantQty: 0.01,
+		},
+		{
+			name:      "market below min notional at the reference price",
+			orderType: types.OrderMarket, price: 100, qty: 0.04,
+			wantErr: ErrMinNotional,
+		},
+		{
+			name:      "market without reference price skips min notional",
+			orderType: types.OrderMarket, price: 0, qty: 0.04,
+			wantPrice: 0, wantQty: 0.04,
+		},
+		{
+			name:      "market price is not rounded",
+			orderType: types.OrderMarket, price: 100.04, qty: 0.1,
+			wantPrice: 100.04, wantQty: 0.1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			price, qty, err := btcusdt.NormalizeOrder(tt.orderType, tt.price, tt.qty, tt.reduceOnly)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) || !errors.Is(err, ErrInvalidOrder) {
+					t.Fatalf("error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if price != tt.wantPrice || qty != tt.wantQty {
+				t.Fatalf("NormalizeOrder = (%v, %v), want (%v, %v)", price, qty, tt.wantPrice, tt.wantQty)
+			}
+		})
+	}
+}
+
+func TestNormalizeOrderUnknownMarket(t *testing.T) {
+	var m *Market
+	if _, _, err := m.NormalizeOrder(types.OrderLimit, 100, 1, false); !errors.Is(err, ErrUnknownMarket) {
+		t.Fatalf("error = %v, want %v", err, ErrUnknownMarket)
+	}
+}
+
+func TestCheckLeverage(t *testing.T) {
+	if err := btcusdt.CheckLeverage(20); err != nil {
+		t.Fatalf("leverage at max: unexpected error: %v", err)
+	}
+	if err := btcusdt.CheckLeverage(21); !errors.Is(err, ErrMaxLeverage) || !errors.Is(err, ErrInvalidOrder) {
+		t.Fatalf("leverage above max: error = %v, want %v", err, ErrMaxLeverage)
+	}
+	if err := (&Market{}).CheckLeverage(100); err != nil {
+		t.Fatalf("unknown max leverage: unexpected error: %v", err)
+	}
+	var unknown *Market
+	if err := unknown.CheckLeverage(100); err != nil {
+		t.Fatalf("unknown market: unexpected error: %v", err)
+	}
+}
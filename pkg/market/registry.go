@@ -0,0 +1,106 @@
+package market
+
+import (
+	"context"
+	"fmt"
+	"lfg/pkg/types"
+	"sync"
+	"time"
+)
+
+// Registry keeps the markets of an exchange by local symbol. A refresh replaces the markets
+// instead of updating them, so a *Market read from the registry never changes.
+type Registry struct {
+	exchangeName types.ExchangeName
+	load         func() (map[string]*Market, error)
+
+	mu          sync.RWMutex
+	markets     map[string]*Market
+	subscribers map[int64]func(types.MarketEvent)
+	nextSubId   int64
+}
+
+// NewRegistry loads the markets once; `load` is called again on every refresh
+func NewRegistry(exchangeName types.ExchangeName, load func() (map[string]*Market, error)) (*Registry, error) {
+	markets, err := load()
+	if err != nil {
+		return nil, err
+	}
+	return &Registry{
+		exchangeName: exchangeName,
+		load:         load,
+		markets:      markets,
+		subscribers:  make(map[int64]func(types.MarketEvent)),
+	}, nil
+}
+
+// Get returns the market of the local symbol, nil if not listed
+func (r *Registry) Get(locSymbol string) *Market {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.markets[locSymbol]
+}
+
+// All returns the listed markets by local symbol; the map must not be modified
+func (r *Registry) All() map[string]*Market {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.markets
+}
+
+// Refresh reloads the markets and emits an event to the subscribers for every listing & delisting;
+// the markets are kept as is if the load fails
+func (r *Registry) Refresh() error {
+	markets, err := r.load()
+	if err != nil {
+		return fmt.Errorf("fail to load markets of %v: %w", r.exchangeName, err)
+	}
+
+	r.mu.Lock()
+	// @dev: an empty response is more likely an API hiccup than every market being delisted
+	if len(markets) == 0 && len(r.markets) > 0 {
+		r.mu.Unlock()
+		return fmt.Errorf("fail to load markets of %v: no market listed", r.exchangeName)
+	}
+	now := time.Now()
+	events := []types.MarketEvent{}
+	for locSymbol := range markets {
+		if _, exists := r.markets[locSymbol]; !exists {
+			events = append(events, types.MarketEvent{Type: types.MarketListed, Time: now, Exchange: r.exchangeName, LocSymbol: locSymbol})
+		}
+	}
+	for locSymbol := range r.markets {
+		if _, exists := markets[locSymbol]; !exists {
+			events = append(events, types.MarketEvent{Type: types.MarketDelisted, Time: now, Exchange: r.exchangeName, LocSymbol: locSymbol})
+		}
+	}
+	r.markets = markets
+	subscribers := make([]func(types.MarketEvent), 0, len(r.subscribers))
+	for _, onEvent := range r.subscribers {
+		subscribers = append(subscribers, onEvent)
+	}
+	r.mu.Unlock()
+
+	for _, event := range events {
+		for _, onEvent := range subscribers {
+			onEvent(event)
+		}
+	}
+	return nil
+}
+
+// Subscribe invokes onEvent on every listing & delisting until ctx is done
+func (r *Registry) Subscribe(ctx context.Context, onEvent func(types.MarketEvent)) {
+	r.mu.Lock()
+	subId := r.nextSubId
+	r.nextSubId++
+	r.subscribers[subId] = onEvent
+	r.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		delete(r.subscribers, subId)
+	}()
+}
@@ -0,0 +1,58 @@
+package market
+
+import "sync"
+
+// SymbolMap maps the universal symbols (e.g. `BTC_USD`) of an exchange to its local symbols and back.
+// The static map of the exchange is extended with the symbols of the markets missing from it, e.g. new listings.
+type SymbolMap struct {
+	mu  sync.RWMutex
+	u2l map[string]string
+	l2u map[string]string
+}
+
+func NewSymbolMap(u2l map[string]string) *SymbolMap {
+	m := &SymbolMap{
+		u2l: make(map[string]string, len(u2l)),
+		l2u: make(map[string]string, len(u2l)),
+	}
+	for uniSymbol, locSymbol := range u2l {
+		m.u2l[uniSymbol] = locSymbol
+		m.l2u[locSymbol] = uniSymbol
+	}
+	return m
+}
+
+func (m *SymbolMap) ToLoc(uniSymbol string) (string, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	locSymbol, ok := m.u2l[uniSymbol]
+	return locSymbol, ok
+}
+
+func (m *SymbolMap) ToUni(locSymbol string) (string, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	uniSymbol, ok := m.l2u[locSymbol]
+	return uniSymbol, ok
+}
+
+// Extend maps the markets missing from the map to the universal symbol returned by `derive`;
+// a derived symbol already mapped to another market is skipped, so the static map always wins
+func (m *SymbolMap) Extend(markets map[string]*Market, derive func(locSymbol string) (string, bool)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for locSymbol := range markets {
+		if _, exists := m.l2u[locSymbol]; exists {
+			continue
+		}
+		uniSymbol, ok := derive(locSymbol)
+		if !ok {
+			continue
+		}
+		if _, exists := m.u2l[uniSymbol]; exists {
+			continue
+		}
+		m.u2l[uniSymbol] = locSymbol
+		m.l2u[locSymbol] = uniSymbol
+	}
+}
@@ -0,0 +1,292 @@
+package risk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"lfg/config"
+	"lfg/pkg/exchange"
+	"lfg/pkg/stream"
+	"lfg/pkg/types"
+	"math"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+var ErrRejected = errors.New("order rejected by risk policy")
+
+// Guard wraps the exchange of an agent and checks every order opening or increasing
+// a position against the agent risk policy before it reaches the exchange, including the
+// orders sent through its order management streams.
+// Exits (reduce-only orders & closing positions) always pass through.
+type Guard struct {
+	exchange.Exchange
+	pnlReporter    exchange.RealizedPnLReporter // set if the policy has a max daily loss
+	policy         *config.RiskConfig
+	entryCooldown  time.Duration
+	allowedSymbols map[string]bool
+	logger         *log.Entry
+
+	mu        sync.Mutex
+	lastEntry time.Time
+}
+
+func NewGuard(agentId string, exchg exchange.Exchange, policy *config.RiskConfig) (*Guard, error) {
+	g := &Guard{
+		Exchange:       exchg,
+		policy:         policy,
+		allowedSymbols: make(map[string]bool),
+		logger: log.WithFields(log.Fields{
+			"agent":    agentId,
+			"exchange": exchg.Name(),
+		}),
+	}
+	if policy.MaxOrderNotional < 0 || policy.MaxTotalNotional < 0 || policy.MaxLeverage < 0 || policy.MaxOpenPositions < 0 || policy.MaxDailyLoss < 0 {
+		return nil, fmt.Errorf("risk limits must not be negative")
+	}
+	if policy.MaxDailyLoss > 0 {
+		pnlReporter, ok := exchg.(exchange.RealizedPnLReporter)
+		if !ok {
+			return nil, fmt.Errorf("max daily loss is not supported on %v: realized PnL is not reported", exchg.Name())
+		}
+		g.pnlReporter = pnlReporter
+	}
+	if policy.EntryCooldown != "" {
+		cooldown, err := time.ParseDuration(policy.EntryCooldown)
+		if err != nil || cooldown < 0 {
+			return nil, fmt.Errorf("invalid entry cooldown '%v'", policy.EntryCooldown)
+		}
+		g.entryCooldown = cooldown
+	}
+	for _, symbol := range policy.AllowedSymbols {
+		if !exchg.HasSymbol(symbol) {
+			return nil, fmt.Errorf("allowed symbol '%v' is not listed on %v", symbol, exchg.Name())
+		}
+		g.allowedSymbols[symbol] = true
+	}
+	return g, nil
+}
+
+// ╔═════════════╗
+//      Order
+// ╚═════════════╝
+
+func (g *Guard) OpenMarketOrder(symbol string, side types.OrderSide, qty float64, lev int, reduceOnly bool) error {
+	if !reduceOnly {
+		if err := g.checkMarketEntry(symbol, qty, lev); err != nil {
+			return err
+		}
+	}
+	if err := g.Exchange.OpenMarketOrder(symbol, side, qty, lev, reduceOnly); err != nil {
+		return err
+	}
+	g.recordOrder(reduceOnly)
+	return nil
+}
+
+func (g *Guard) OpenLimitOrder(symbol string, side types.OrderSide, price float64, qty float64, lev int, reduceOnly bool, tif types.OrderTIF, cloId string) (string, error) {
+	if !reduceOnly {
+		if err := g.checkEntry(symbol, qty*price, lev); err != nil {
+			return "", err
+		}
+	}
+	oId, err := g.Exchange.OpenLimitOrder(symbol, side, price, qty, lev, reduceOnly, tif, cloId)
+	if err != nil {
+		return "", err
+	}
+	g.recordOrder(reduceOnly)
+	return oId, nil
+}
+
+func (g *Guard) OpenBatchLimitOrders(symbol string, inputs []types.LimitOrderInput, lev int) ([]string, error) {
+	if err := g.checkBatchEntry(symbol, inputs, lev); err != nil {
+		return nil, err
+	}
+	// @dev: a partially placed batch is an entry too
+	oIds, err := g.Exchange.OpenBatchLimitOrders(symbol, inputs, lev)
+	for _, oId := range oIds {
+		if oId != "" {
+			g.recordOrder(isReduceOnlyBatch(inputs))
+			break
+		}
+	}
+	return oIds, err
+}
+
+// ConnectOrderMgmtStream connects the order management stream of the exchange; the orders
+// sent through the returned stream, or the one passed to the callbacks, are checked as well
+func (g *Guard) ConnectOrderMgmtStream(ctx context.Context, symbol string, onConn func(stream.Stream), onEvent func(stream.Stream, types.OrderEvent), onClose func(stream.Stream)) (stream.Stream, error) {
+	var guardedOnConn, guardedOnClose func(stream.Stream)
+	var guardedOnEvent func(stream.Stream, types.OrderEvent)
+	if onConn != nil {
+		guardedOnConn = func(s stream.Stream) { onConn(g.guardStream(s)) }
+	}
+	if onEvent != nil {
+		guardedOnEvent = func(s stream.Stream, evt types.OrderEvent) { onEvent(g.guardStream(s), evt) }
+	}
+	if onClose != nil {
+		guardedOnClose = func(s stream.Stream) { onClose(g.guardStream(s)) }
+	}
+	s, err := g.Exchange.ConnectOrderMgmtStream(ctx, symbol, guardedOnConn, guardedOnEvent, guardedOnClose)
+	if err != nil {
+		return nil, err
+	}
+	return g.guardStream(s), nil
+}
+
+// ╔═════════════╗
+//     Checks
+// ╚═════════════╝
+
+// checkEntry returns an ErrRejected error if a new order of `notional` USD would break the policy
+func (g *Guard) checkEntry(symbol string, notional float64, lev int) error {
+	if err := g.checkOrder(symbol, notional, lev, true); err != nil {
+		return g.reject(symbol, err)
+	}
+	if err := g.checkExposure(symbol, notional, ""); err != nil {
+		return g.reject(symbol, err)
+	}
+	return nil
+}
+
+// checkModify returns an ErrRejected error if the resting order `oId` modified to `notional` USD would
+// break the policy; the modification does not count as a new entry for the cooldown
+func (g *Guard) checkModify(symbol string, oId string, notional float64, lev int) error {
+	if err := g.checkOrder(symbol, notional, lev, false); err != nil {
+		return g.reject(symbol, err)
+	}
+	if err := g.checkExposure(symbol, notional, oId); err != nil {
+		return g.reject(symbol, err)
+	}
+	return nil
+}
+
+// checkMarketEntry checks a market order at the last 1m kline close
+func (g *Guard) checkMarketEntry(symbol string, qty float64, lev int) error {
+	klines, err := g.GetKLines(symbol, types.Interval1m, 1)
+	if err != nil {
+		return fmt.Errorf("fail to get price for risk check: %w", err)
+	}
+	if len(klines) == 0 {
+		return fmt.Errorf("fail to get price for risk check: no kline")
+	}
+	return g.checkEntry(symbol, qty*klines[len(klines)-1].Kline.C, lev)
+}
+
+// checkBatchEntry checks a batch as a single order of its total value, unless every order is reduce-only
+func (g *Guard) checkBatchEntry(symbol string, inputs []types.LimitOrderInput, lev int) error {
+	if isReduceOnlyBatch(inputs) {
+		return nil
+	}
+	notional := 0.0
+	for _, input := range inputs {
+		if !input.ReduceOnly {
+			notional += input.Qty * input.Price
+		}
+	}
+	return g.checkEntry(symbol, notional, lev)
+}
+
+func (g *Guard) checkOrder(symbol string, notional float64, lev int, isEntry bool) error {
+	policy := g.policy
+	if len(g.allowedSymbols) > 0 && !g.allowedSymbols[symbol] {
+		return fmt.Errorf("symbol %v is not allowed", symbol)
+	}
+	if policy.MaxLeverage > 0 && lev > policy.MaxLeverage {
+		return fmt.Errorf("leverage %vx exceeds max leverage %vx", lev, policy.MaxLeverage)
+	}
+	if policy.MaxOrderNotional > 0 && notional > policy.MaxOrderNotional {
+		return fmt.Errorf("order value %.2f USD exceeds max order notional %.2f USD", notional, policy.MaxOrderNotional)
+	}
+	if isEntry && g.entryCooldown > 0 {
+		g.mu.Lock()
+		lastEntry := g.lastEntry
+		g.mu.Unlock()
+		if elapsed := time.Since(lastEntry); !lastEntry.IsZero() && elapsed < g.entryCooldown {
+			return fmt.Errorf("last entry was %v ago, cooldown is %v", elapsed.Round(time.Second), g.entryCooldown)
+		}
+	}
+	if policy.MaxDailyLoss > 0 {
+		// @dev: the realized PnL of the account since 00:00 UTC is read from the exchange on every
+		// check rather than tracked by the guard, so it survives restarts & counts every fill
+		realizedPnL, err := g.pnlReporter.GetRealizedPnL(time.Now().UTC().Truncate(24 * time.Hour))
+		if err != nil {
+			return fmt.Errorf("fail to get realized PnL to check the daily loss: %w", err)
+		}
+		if loss := -realizedPnL; loss >= policy.MaxDailyLoss {
+			return fmt.Errorf("daily realized loss %.2f USD reached max daily loss %.2f USD, entries are rejected until 00:00 UTC", loss, policy.MaxDailyLoss)
+		}
+	}
+	return nil
+}
+
+// checkExposure checks the open positions & resting orders of every market of the account;
+// the resting order `replacedOId` is left out as the order replaces it
+func (g *Guard) checkExposure(symbol string, notional float64, replacedOId string) error {
+	policy := g.policy
+	if policy.MaxTotalNotional <= 0 && policy.MaxOpenPositions <= 0 {
+		return nil
+	}
+
+	positions, err := g.GetActivePositions()
+	if err != nil {
+		return fmt.Errorf("fail to get positions: %w", err)
+	}
+	totalNotional := notional
+	openPositions := 0
+	for _, symbolPositions := range positions {
+		for _, position := range symbolPositions {
+			if position.Qty == 0 {
+				continue
+			}
+			totalNotional += math.Abs(position.Qty) * position.EntryPrice
+			openPositions++
+		}
+	}
+	if policy.MaxTotalNotional > 0 {
+		orders, err := g.GetAllPendingOrders()
+		if err != nil {
+			return fmt.Errorf("fail to get pending orders: %w", err)
+		}
+		for _, order := range orders {
+			if replacedOId != "" && order.Id == replacedOId {
+				continue
+			}
+			totalNotional += order.RemainingQty * order.Price
+		}
+	}
+
+	if policy.MaxTotalNotional > 0 && totalNotional > policy.MaxTotalNotional {
+		return fmt.Errorf("total exposure %.2f USD would exceed max total notional %.2f USD", totalNotional, policy.MaxTotalNotional)
+	}
+	if policy.MaxOpenPositions > 0 && len(positions[symbol]) == 0 && openPositions >= policy.MaxOpenPositions {
+		return fmt.Errorf("%v positions are open, max open positions is %v", openPositions, policy.MaxOpenPositions)
+	}
+	return nil
+}
+
+func (g *Guard) reject(symbol string, err error) error {
+	g.logger.Warnf("🛑 %v order rejected: %v", symbol, err)
+	return fmt.Errorf("%w: %v", ErrRejected, err)
+}
+
+// recordOrder starts the entry cooldown on an order opening or increasing a position
+func (g *Guard) recordOrder(reduceOnly bool) {
+	if reduceOnly {
+		return
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.lastEntry = time.Now()
+}
+
+func isReduceOnlyBatch(inputs []types.LimitOrderInput) bool {
+	for _, input := range inputs {
+		if !input.ReduceOnly {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,285 @@
+package risk
+
+import (
+	"context"
+	"errors"
+	"lfg/config"
+	"lfg/pkg/exchange"
+	"lfg/pkg/order"
+	"lfg/pkg/stream"
+	"lfg/pkg/types"
+	"testing"
+	"time"
+)
+
+// stubExchange quotes every symbol at `price` and counts the orders reaching it;
+// the guard calls no other method
+type stubExchange struct {
+	exchange.Exchange
+	name        types.ExchangeName
+	price       float64
+	positions   map[string][]types.Position
+	orders      map[string][]order.Order
+	realizedPnL float64
+	opened      int
+}
+
+func (s *stubExchange) Name() types.ExchangeName        { return s.name }
+func (s *stubExchange) HasSymbol(uniSymbol string) bool { return uniSymbol != "DOGE_USD" }
+
+func (s *stubExchange) GetKLines(symbol string, interval types.Interval, window int) ([]types.KLineEvent, error) {
+	return []types.KLineEvent{{Kline: types.KLine{C: s.price}}}, nil
+}
+
+func (s *stubExchange) GetActivePositions() (map[string][]types.Position, error) {
+	return s.positions, nil
+}
+
+func (s *stubExchange) GetAllPendingOrders() ([]order.Order, error) {
+	orders := []order.Order{}
+	for _, symbolOrders := range s.orders {
+		orders = append(orders, symbolOrders...)
+	}
+	return orders, nil
+}
+
+func (s *stubExchange) GetRealizedPnL(since time.Time) (float64, error) {
+	return s.realizedPnL, nil
+}
+
+func (s *stubExchange) ConnectOrderMgmtStream(ctx context.Context, symbol string, onConn func(stream.Stream), onEvent func(stream.Stream, types.OrderEvent), onClose func(stream.Stream)) (stream.Stream, error) {
+	orderStream := &stubStream{exchange: s}
+	onConn(orderStream)
+	return orderStream, nil
+}
+
+func (s *stubExchange) OpenMarketOrder(symbol string, side types.OrderSide, qty float64, lev int, reduceOnly bool) error {
+	s.opened++
+	return nil
+}
+
+func (s *stubExchange) OpenLimitOrder(symbol string, side types.OrderSide, price float64, qty float64, lev int, reduceOnly bool, tif types.OrderTIF, cloId string) (string, error) {
+	s.opened++
+	return "1", nil
+}
+
+// stubStream counts the orders sent & modified through it on its exchange
+type stubStream struct {
+	stream.Stream
+	exchange *stubExchange
+}
+
+func (s *stubStream) OpenMarketOrder(symbol string, side types.OrderSide, qty float64, lev int, reduceOnly bool) error {
+	return s.exchange.OpenMarketOrder(symbol, side, qty, lev, reduceOnly)
+}
+
+func (s *stubStream) OpenLimitOrder(symbol string, side types.OrderSide, price float64, qty float64, lev int, reduceOnly bool, tif types.OrderTIF, cloId string) (string, error) {
+	return s.exchange.OpenLimitOrder(symbol, side, price, qty, lev, reduceOnly, tif, cloId)
+}
+
+func (s *stubStream) OpenBatchLimitOrders(symbol string, inputs []types.LimitOrderInput, lev int) error {
+	s.exchange.opened += len(inputs)
+	return nil
+}
+
+func (s *stubStream) ModifyOrder(symbol string, oId string, cloId string, side types.OrderSide, price float64, qty float64, lev int, reduceOnly bool, tif types.OrderTIF) error {
+	s.exchange.opened++
+	return nil
+}
+
+func TestNewGuard(t *testing.T) {
+	bnf := &stubExchange{name: types.ExchangeBnf}
+	for _, policy := range []config.RiskConfig{
+		{},
+		{MaxOrderNotional: 500, MaxLeverage: 5, EntryCooldown: "15m", AllowedSymbols: []string{"BTC_USD"}},
+		{MaxDailyLoss: 100},
+	} {
+		if _, err := NewGuard("agent", bnf, &policy); err != nil {
+			t.Errorf("%+v: unexpected error: %v", policy, err)
+		}
+	}
+
+	invalid := map[string]config.RiskConfig{
+		"negative limit":          {MaxOrderNotional: -1},
+		"invalid cooldown":        {EntryCooldown: "soon"},
+		"negative cooldown":       {EntryCooldown: "-1m"},
+		"unlisted allowed symbol": {AllowedSymbols: []string{"DOGE_USD"}},
+	}
+	for name, policy := range invalid {
+		if _, err := NewGuard("agent", bnf, &policy); err == nil {
+			t.Errorf("%v: want error", name)
+		}
+	}
+
+	// the daily loss is read from the exchange, which must report the realized PnL
+	hpl := struct{ exchange.Exchange }{&stubExchange{name: types.ExchangeHpl}}
+	if _, err := NewGuard("agent", hpl, &config.RiskConfig{MaxDailyLoss: 100}); err == nil {
+		t.Errorf("daily loss without realized PnL: want error")
+	}
+}
+
+func TestGuardEntry(t *testing.T) {
+	shortEth := map[string][]types.Position{"ETH_USD": {{EntryPrice: 50, Qty: -8, Side: types.OrderSideSell}}}
+
+	tests := []struct {
+		name       string
+		policy     config.RiskConfig
+		positions  map[string][]types.Position
+		orders     map[string][]order.Order
+		qty        float64 // BTC_USD market buy at 100 USD
+		lev        int
+		reduceOnly bool
+		wantReject bool
+	}{
+		{name: "no limit", qty: 10, lev: 10},
+		{name: "symbol not allowed", policy: config.RiskConfig{AllowedSymbols: []string{"ETH_USD"}}, qty: 1, lev: 1, wantReject: true},
+		{name: "leverage above max", policy: config.RiskConfig{MaxLeverage: 5}, qty: 1, lev: 10, wantReject: true},
+		{name: "order notional above max", policy: config.RiskConfig{MaxOrderNotional: 500}, qty: 6, lev: 1, wantReject: true},
+		{name: "order notional at max", policy: config.RiskConfig{MaxOrderNotional: 500}, qty: 5, lev: 1},
+		{name: "reduce-only bypasses the policy", policy: config.RiskConfig{MaxOrderNotional: 500, MaxLeverage: 5}, qty: 100, lev: 10, reduceOnly: true},
+		{
+			name:      "total notional includes positions & resting orders",
+			policy:    config.RiskConfig{MaxTotalNotional: 1000},
+			positions: shortEth,
+			orders:    map[string][]order.Order{"BTC_USD": {{Price: 90, RemainingQty: 2}}},
+			qty:       5, lev: 1,
+			wantReject: true,
+		},
+		{name: "total notional within max", policy: config.RiskConfig{MaxTotalNotional: 1000}, positions: shortEth, qty: 5, lev: 1},
+		{name: "max open positions reached", policy: config.RiskConfig{MaxOpenPositions: 1}, positions: shortEth, qty: 1, lev: 1, wantReject: true},
+		{
+			// e.g. opened by hand or by another agent
+			name:      "positions outside the allowed symbols count",
+			policy:    config.RiskConfig{MaxOpenPositions: 1, AllowedSymbols: []string{"BTC_USD"}},
+			positions: shortEth,
+			qty:       1, lev: 1,
+			wantReject: true,
+		},
+		{
+			name:      "adding to an open position",
+			policy:    config.RiskConfig{MaxOpenPositions: 1},
+			positions: map[string][]types.Position{"BTC_USD": {{EntryPrice: 50, Qty: 1, Side: types.OrderSideBuy}}},
+			qty:       1, lev: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exchg := &stubExchange{name: types.ExchangeBnf, price: 100, positions: tt.positions, orders: tt.orders}
+			guard, err := NewGuard("agent", exchg, &tt.policy)
+			if err != nil {
+				t.Fatalf("fail to create guard: %v", err)
+			}
+			err = guard.OpenMarketOrder("BTC_USD", types.OrderSideBuy, tt.qty, tt.lev, tt.reduceOnly)
+			if rejected := errors.Is(err, ErrRejected); rejected != tt.wantReject || (err != nil && !rejected) {
+				t.Fatalf("error = %v, want rejected %v", err, tt.wantReject)
+			}
+			if sent := exchg.opened == 1; sent == tt.wantReject {
+				t.Fatalf("order sent to the exchange: %v, want %v", sent, !tt.wantReject)
+			}
+		})
+	}
+}
+
+func TestGuardEntryCooldown(t *testing.T) {
+	exchg := &stubExchange{name: types.ExchangeBnf}
+	guard, err := NewGuard("agent", exchg, &config.RiskConfig{EntryCooldown: "1h"})
+	if err != nil {
+		t.Fatalf("fail to create guard: %v", err)
+	}
+	if _, err := guard.OpenLimitOrder("BTC_USD", types.OrderSideBuy, 100, 1, 1, false, types.OrderTIFGTC, ""); err != nil {
+		t.Fatalf("first entry: unexpected error: %v", err)
+	}
+	if _, err := guard.OpenLimitOrder("BTC_USD", types.OrderSideSell, 110, 1, 1, true, types.OrderTIFGTC, ""); err != nil {
+		t.Fatalf("exit during cooldown: unexpected error: %v", err)
+	}
+	if _, err := guard.OpenLimitOrder("ETH_USD", types.OrderSideBuy, 100, 1, 1, false, types.OrderTIFGTC, ""); !errors.Is(err, ErrRejected) {
+		t.Fatalf("entry during cooldown: error = %v, want rejection", err)
+	}
+}
+
+func TestGuardDailyLoss(t *testing.T) {
+	exchg := &stubExchange{name: types.ExchangeBnf, price: 100}
+	policy := &config.RiskConfig{MaxDailyLoss: 100}
+	guard, err := NewGuard("agent", exchg, policy)
+	if err != nil {
+		t.Fatalf("fail to create guard: %v", err)
+	}
+
+	// realized PnL of the day, and whether the next entry is rejected
+	for i, step := range []struct {
+		realizedPnL float64
+		wantReject  bool
+	}{
+		{-60, false},
+		{-99, false},
+		{-100, true},
+		{-50, false},
+	} {
+		exchg.realizedPnL = step.realizedPnL
+		err := guard.OpenMarketOrder("BTC_USD", types.OrderSideBuy, 1, 1, false)
+		if rejected := errors.Is(err, ErrRejected); rejected != step.wantReject {
+			t.Fatalf("step %d: error = %v, want rejected %v", i, err, step.wantReject)
+		}
+	}
+
+	// a restarted guard reads the loss of the day from the exchange
+	exchg.realizedPnL = -150
+	restarted, err := NewGuard("agent", exchg, policy)
+	if err != nil {
+		t.Fatalf("fail to create guard: %v", err)
+	}
+	if err := restarted.OpenMarketOrder("BTC_USD", types.OrderSideBuy, 1, 1, false); !errors.Is(err, ErrRejected) {
+		t.Fatalf("entry after restart: error = %v, want rejection", err)
+	}
+}
+
+func TestGuardStream(t *testing.T) {
+	exchg := &stubExchange{
+		name:   types.ExchangeBnf,
+		price:  100,
+		orders: map[string][]order.Order{"BTC_USD": {{Id: "7", Price: 100, RemainingQty: 4}}},
+	}
+	guard, err := NewGuard("agent", exchg, &config.RiskConfig{MaxOrderNotional: 500, MaxTotalNotional: 800})
+	if err != nil {
+		t.Fatalf("fail to create guard: %v", err)
+	}
+	var connected stream.Stream
+	orderStream, err := guard.ConnectOrderMgmtStream(context.Background(), "BTC_USD", func(s stream.Stream) { connected = s }, nil, nil)
+	if err != nil {
+		t.Fatalf("fail to connect stream: %v", err)
+	}
+
+	for _, s := range []stream.Stream{orderStream, connected} {
+		exchg.opened = 0
+		if err := s.OpenMarketOrder("BTC_USD", types.OrderSideBuy, 6, 1, false); !errors.Is(err, ErrRejected) {
+			t.Fatalf("market entry above max: error = %v, want rejection", err)
+		}
+		if _, err := s.OpenLimitOrder("BTC_USD", types.OrderSideBuy, 100, 6, 1, false, types.OrderTIFGTC, ""); !errors.Is(err, ErrRejected) {
+			t.Fatalf("limit entry above max: error = %v, want rejection", err)
+		}
+		batch := []types.LimitOrderInput{{Price: 100, Qty: 3}, {Price: 99, Qty: 3}}
+		if err := s.OpenBatchLimitOrders("BTC_USD", batch, 1); !errors.Is(err, ErrRejected) {
+			t.Fatalf("batch entry above max: error = %v, want rejection", err)
+		}
+		if err := s.ModifyOrder("BTC_USD", "7", "", types.OrderSideBuy, 100, 6, 1, false, types.OrderTIFGTC); !errors.Is(err, ErrRejected) {
+			t.Fatalf("modification above max: error = %v, want rejection", err)
+		}
+		if exchg.opened != 0 {
+			t.Fatalf("%v rejected orders sent to the exchange", exchg.opened)
+		}
+
+		if err := s.OpenMarketOrder("BTC_USD", types.OrderSideSell, 6, 1, true); err != nil {
+			t.Fatalf("reduce-only market order: unexpected error: %v", err)
+		}
+		// 500 USD replacing the 400 USD of order 7 is within the max total notional, a new order is not
+		if err := s.ModifyOrder("BTC_USD", "7", "", types.OrderSideBuy, 100, 5, 1, false, types.OrderTIFGTC); err != nil {
+			t.Fatalf("modification: unexpected error: %v", err)
+		}
+		if _, err := s.OpenLimitOrder("BTC_USD", types.OrderSideBuy, 100, 5, 1, false, types.OrderTIFGTC, ""); !errors.Is(err, ErrRejected) {
+			t.Fatalf("limit entry above max total: error = %v, want rejection", err)
+		}
+		if exchg.opened != 2 {
+			t.Fatalf("orders sent to the exchange = %v, want 2", exchg.opened)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package risk
+
+import (
+	"lfg/pkg/stream"
+	"lfg/pkg/types"
+)
+
+// guardedStream checks the orders sent through an order management stream as the guard does
+type guardedStream struct {
+	stream.Stream
+	guard *Guard
+}
+
+func (g *Guard) guardStream(s stream.Stream) stream.Stream {
+	if s == nil {
+		return nil
+	}
+	return &guardedStream{Stream: s, guard: g}
+}
+
+func (s *guardedStream) OpenMarketOrder(symbol string, side types.OrderSide, qty float64, lev int, reduceOnly bool) error {
+	if !reduceOnly {
+		if err := s.guard.checkMarketEntry(symbol, qty, lev); err != nil {
+			return err
+		}
+	}
+	if err := s.Stream.OpenMarketOrder(symbol, side, qty, lev, reduceOnly); err != nil {
+		return err
+	}
+	s.guard.recordOrder(reduceOnly)
+	return nil
+}
+
+func (s *guardedStream) OpenLimitOrder(symbol string, orderSide types.OrderSide, price float64, qty float64, lev int, reduceOnly bool, orderTif types.OrderTIF, cloId string) (string, error) {
+	if !reduceOnly {
+		if err := s.guard.checkEntry(symbol, qty*price, lev); err != nil {
+			return "", err
+		}
+	}
+	oId, err := s.Stream.OpenLimitOrder(symbol, orderSide, price, qty, lev, reduceOnly, orderTif, cloId)
+	if err != nil {
+		return "", err
+	}
+	s.guard.recordOrder(reduceOnly)
+	return oId, nil
+}
+
+func (s *guardedStream) OpenBatchLimitOrders(symbol string, inputs []types.LimitOrderInput, lev int) error {
+	if err := s.guard.checkBatchEntry(symbol, inputs, lev); err != nil {
+		return err
+	}
+	if err := s.Stream.OpenBatchLimitOrders(symbol, inputs, lev); err != nil {
+		return err
+	}
+	s.guard.recordOrder(isReduceOnlyBatch(inputs))
+	return nil
+}
+
+func (s *guardedStream) ModifyOrder(symbol string, oId string, cloId string, orderSide types.OrderSide, price float64, qty float64, lev int, reduceOnly bool, orderTif types.OrderTIF) error {
+	if !reduceOnly {
+		if err := s.guard.checkModify(symbol, oId, qty*price, lev); err != nil {
+			return err
+		}
+	}
+	return s.Stream.ModifyOrder(symbol, oId, cloId, orderSide, price, qty, lev, reduceOnly, orderTif)
+}
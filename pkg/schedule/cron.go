@@ -0,0 +1,169 @@
+package schedule
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CronSchedule is a standard 5-field cron expression (minute hour day-of-month month day-of-week)
+// evaluated in UTC. Each field supports `*`, `a`, `a-b`, `*/n`, `a-b/n` and comma separated lists.
+type CronSchedule struct {
+	expr   string
+	minute uint64 // bitset of allowed values
+	hour   uint64
+	dom    uint64
+	month  uint64
+	dow    uint64
+
+	// @dev: cron semantic: if both dom and dow are restricted, a day matches when EITHER matches
+	domAny bool
+	dowAny bool
+
+	delay time.Duration
+}
+
+type cronField struct {
+	name string
+	min  int
+	max  int
+}
+
+var cronFields = []cronField{
+	{name: "minute", min: 0, max: 59},
+	{name: "hour", min: 0, max: 23},
+	{name: "day-of-month", min: 1, max: 31},
+	{name: "month", min: 1, max: 12},
+	{name: "day-of-week", min: 0, max: 7}, // both 0 and 7 are sunday
+}
+
+// maximum search horizon for the next tick; protects against impossible dates e.g. `0 0 30 2 *`
+const cronMaxSearchYears = 5
+
+func ParseCron(expr string) (*CronSchedule, error) {
+	parts := strings.Fields(expr)
+	if len(parts) != len(cronFields) {
+		return nil, fmt.Errorf("invalid cron expression '%v': expected %v fields, got %v", expr, len(cronFields), len(parts))
+	}
+
+	bitsets := make([]uint64, len(cronFields))
+	for i, part := range parts {
+		bits, err := parseCronField(part, cronFields[i])
+		if err != nil {
+			return nil, fmt.Errorf("invalid cron expression '%v': %w", expr, err)
+		}
+		bitsets[i] = bits
+	}
+
+	// fold sunday=7 into sunday=0
+	dow := bitsets[4]
+	if dow&(1<<7) != 0 {
+		dow = (dow | 1) &^ (1 << 7)
+	}
+
+	return &CronSchedule{
+		expr:   expr,
+		minute: bitsets[0],
+		hour:   bitsets[1],
+		dom:    bitsets[2],
+		month:  bitsets[3],
+		dow:    dow,
+		domAny: parts[2] == "*",
+		dowAny: parts[4] == "*",
+	}, nil
+}
+
+func parseCronField(field string, spec cronField) (uint64, error) {
+	var bits uint64
+	for _, item := range strings.Split(field, ",") {
+		rangePart, step := item, 1
+		if idx := strings.Index(item, "/"); idx >= 0 {
+			rangePart = item[:idx]
+			s, err := strconv.Atoi(item[idx+1:])
+			if err != nil || s <= 0 {
+				return 0, fmt.Errorf("invalid step in %v field: %v", spec.name, item)
+			}
+			step = s
+		}
+
+		lo, hi := spec.min, spec.max
+		switch {
+		case rangePart == "*":
+		case strings.Contains(rangePart, "-"):
+			bounds := strings.SplitN(rangePart, "-", 2)
+			var err error
+			if lo, err = strconv.Atoi(bounds[0]); err != nil {
+				return 0, fmt.Errorf("invalid range in %v field: %v", spec.name, item)
+			}
+			if hi, err = strconv.Atoi(bounds[1]); err != nil {
+				return 0, fmt.Errorf("invalid range in %v field: %v", spec.name, item)
+			}
+		default:
+			v, err := strconv.Atoi(rangePart)
+			if err != nil {
+				return 0, fmt.Errorf("invalid value in %v field: %v", spec.name, item)
+			}
+			lo, hi = v, v
+			// `a/n` means from a to max
+			if step > 1 {
+				hi = spec.max
+			}
+		}
+		if lo < spec.min || hi > spec.max || lo > hi {
+			return 0, fmt.Errorf("%v field out of range [%v-%v]: %v", spec.name, spec.min, spec.max, item)
+		}
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+func (s *CronSchedule) String() string {
+	return s.expr
+}
+
+func (s *CronSchedule) Next(t time.Time) time.Time {
+	// cron has minute granularity; a configured delay shifts every tick
+	base := t.Add(-s.delay).UTC()
+	next := base.Truncate(time.Minute).Add(time.Minute)
+	limit := base.AddDate(cronMaxSearchYears, 0, 0)
+
+	for next.Before(limit) {
+		if s.month&(1<<uint(next.Month())) == 0 {
+			next = time.Date(next.Year(), next.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+			continue
+		}
+		if !s.matchDay(next) {
+			next = time.Date(next.Year(), next.Month(), next.Day()+1, 0, 0, 0, 0, time.UTC)
+			continue
+		}
+		if s.hour&(1<<uint(next.Hour())) == 0 {
+			next = next.Truncate(time.Hour).Add(time.Hour)
+			continue
+		}
+		if s.minute&(1<<uint(next.Minute())) == 0 {
+			next = next.Add(time.Minute)
+			continue
+		}
+		return next.Add(s.delay).In(t.Location())
+	}
+	// unreachable for valid calendars; park the schedule far in the future
+	return limit.In(t.Location())
+}
+
+func (s *CronSchedule) matchDay(t time.Time) bool {
+	domMatch := s.dom&(1<<uint(t.Day())) != 0
+	dowMatch := s.dow&(1<<uint(t.Weekday())) != 0
+	switch {
+	case s.domAny && s.dowAny:
+		return true
+	case s.domAny:
+		return dowMatch
+	case s.dowAny:
+		return domMatch
+	default:
+		return domMatch || dowMatch
+	}
+}
@@ -0,0 +1,100 @@
+package schedule
+
+import (
+	"fmt"
+	"lfg/config"
+	"lfg/pkg/types"
+	"lfg/pkg/utils"
+	"time"
+)
+
+type Schedule interface {
+	// Next returns the first tick strictly after t
+	Next(t time.Time) time.Time
+}
+
+// creates a schedule from the agent schedule config; either `every` or `cron` must be set
+func New(scheduleConfig *config.ScheduleConfig) (Schedule, error) {
+	delay := time.Duration(0)
+	if scheduleConfig.Delay != "" {
+		d, err := time.ParseDuration(scheduleConfig.Delay)
+		if err != nil {
+			return nil, fmt.Errorf("invalid schedule delay '%v': %w", scheduleConfig.Delay, err)
+		}
+		if d < 0 {
+			return nil, fmt.Errorf("schedule delay must not be negative: %v", scheduleConfig.Delay)
+		}
+		delay = d
+	}
+
+	switch {
+	case scheduleConfig.Every != "" && scheduleConfig.Cron != "":
+		return nil, fmt.Errorf("schedule must set either 'every' or 'cron', not both")
+	case scheduleConfig.Every != "":
+		every, err := parseEvery(scheduleConfig.Every)
+		if err != nil {
+			return nil, err
+		}
+		return &intervalSchedule{
+			start:         time.Now(),
+			every:         every,
+			alignToCandle: scheduleConfig.AlignToCandle,
+			delay:         delay,
+		}, nil
+	case scheduleConfig.Cron != "":
+		if scheduleConfig.AlignToCandle {
+			return nil, fmt.Errorf("'alignToCandle' is only valid with 'every'")
+		}
+		cron, err := ParseCron(scheduleConfig.Cron)
+		if err != nil {
+			return nil, err
+		}
+		cron.delay = delay
+		return cron, nil
+	default:
+		return nil, fmt.Errorf("schedule must set either 'every' or 'cron'")
+	}
+}
+
+// accepts kline intervals (e.g. `15m`, `1d`) as well as go durations (e.g. `90s`)
+func parseEvery(every string) (time.Duration, error) {
+	d, err := utils.IntervalToDuration(types.Interval(every))
+	if err != nil {
+		d, err = time.ParseDuration(every)
+		if err != nil {
+			return 0, fmt.Errorf("invalid schedule interval '%v': %w", every, err)
+		}
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("schedule interval must be positive: %v", every)
+	}
+	return d, nil
+}
+
+// MARK: intervalSchedule
+
+type intervalSchedule struct {
+	start         time.Time // ticks are anchored to the schedule creation unless aligned to candles
+	every         time.Duration
+	alignToCandle bool          // tick on candle close i.e. multiples of `every` since UTC midnight
+	delay         time.Duration // settle delay added after the tick e.g. to let the closed candle be published
+}
+
+func (s *intervalSchedule) Next(t time.Time) time.Time {
+	if !s.alignToCandle {
+		// @dev: ticks are computed from the start rather than from t, so neither the delay
+		// nor the latency of the previous tick accumulate
+		next := s.start.Add(s.every + s.delay)
+		if next.After(t) {
+			return next
+		}
+		return next.Add((t.Sub(next)/s.every + 1) * s.every)
+	}
+	// @dev: time.Truncate works on absolute time since the zero time (UTC midnight), so
+	// any interval dividing a day (1m, 15m, 4h, ...) lands exactly on candle boundaries
+	next := t.Truncate(s.every).Add(s.delay)
+	for !next.After(t) {
+		next = next.Add(s.every)
+	}
+	return next
+}
@@ -0,0 +1,95 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"lfg/config"
+	"lfg/pkg/s3client"
+	"lfg/pkg/types"
+	"lfg/pkg/utils"
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+var ErrNotFound = errors.New("object not found")
+
+// Store is a minimal blob store for persisted state (plans, checkpoints, ...)
+type Store interface {
+	Get(key string) ([]byte, error) // returns ErrNotFound if the key does not exist
+	Put(key string, data []byte) error
+}
+
+// creates a store based on the persistence config; storage defaults to `local`
+func New(persistenceConfig *config.PersistenceConfig) (Store, error) {
+	switch persistenceConfig.Storage {
+	case "", types.StorageLocal:
+		dir := persistenceConfig.Dir
+		if dir == "" {
+			dir = "data"
+		}
+		return &LocalStore{Dir: dir}, nil
+	case types.StorageS3:
+		if persistenceConfig.Bucket == "" {
+			return nil, fmt.Errorf("s3 storage requires bucket")
+		}
+		client := s3client.Init(
+			utils.LoadEnv(persistenceConfig.EnvPrefix+"_ACCESS_KEY"),
+			utils.LoadEnv(persistenceConfig.EnvPrefix+"_SECRET_KEY"),
+		)
+		return &S3Store{client: client, bucket: persistenceConfig.Bucket, prefix: persistenceConfig.Prefix}, nil
+	default:
+		return nil, fmt.Errorf("unsupported storage: %v", persistenceConfig.Storage)
+	}
+}
+
+// MARK: LocalStore
+
+type LocalStore struct {
+	Dir string
+}
+
+func (s *LocalStore) Get(key string) ([]byte, error) {
+	data, err := os.ReadFile(filepath.Join(s.Dir, filepath.FromSlash(key)))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	return data, err
+}
+
+// writes through a temp file + rename so a crash never leaves a truncated object
+func (s *LocalStore) Put(key string, data []byte) error {
+	filePath := filepath.Join(s.Dir, filepath.FromSlash(key))
+	if err := os.MkdirAll(filepath.Dir(filePath), 0o755); err != nil {
+		return err
+	}
+	tmpPath := filePath + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, filePath)
+}
+
+// MARK: S3Store
+
+type S3Store struct {
+	client *s3.S3
+	bucket string
+	prefix string
+}
+
+func (s *S3Store) Get(key string) ([]byte, error) {
+	data, err := s3client.GetObject(s.client, s.bucket, path.Join(s.prefix, key))
+	var awsErr awserr.Error
+	if errors.As(err, &awsErr) && awsErr.Code() == s3.ErrCodeNoSuchKey {
+		return nil, ErrNotFound
+	}
+	return data, err
+}
+
+func (s *S3Store) Put(key string, data []byte) error {
+	return s3client.UploadObject(s.client, s.bucket, path.Join(s.prefix, key), data)
+}
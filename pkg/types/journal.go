@@ -0,0 +1,9 @@
+package types
+
+type JournalKind string
+
+const (
+	JournalLLM    = JournalKind("llm")    // a model request & response
+	JournalMemory = JournalKind("memory") // a memory write
+	JournalOrder  = JournalKind("order")  // an order sent to the exchange
+)
@@ -0,0 +1,8 @@
+package types
+
+type LLMProvider string
+
+const (
+	LLMProviderOpenAI   = LLMProvider("openai")   // any OpenAI compatible API (default: openrouter)
+	LLMProviderScripted = LLMProvider("scripted") // replays canned responses from a file, for offline runs
+)
@@ -36,8 +36,9 @@ const (
 )
 
 type LimitOrderInput struct {
-	Side  OrderSide `json:"side"`
-	Price float64   `json:"price"`
-	Qty   float64   `json:"qty"`
-	Tif   OrderTIF  `json:"tif"`
+	Side       OrderSide `json:"side"`
+	Price      float64   `json:"price"`
+	Qty        float64   `json:"qty"`
+	Tif        OrderTIF  `json:"tif"`
+	ReduceOnly bool      `json:"reduceOnly"`
 }
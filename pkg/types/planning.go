@@ -0,0 +1,17 @@
+package types
+
+type AgentMode string
+
+const (
+	AgentModePlan  = AgentMode("plan")  // run the approved plan on each run
+	AgentModeTools = AgentMode("tools") // the runtime model calls the tasks as tools on each run
+)
+
+type PlanningMode string
+
+const (
+	PlanningModeInteractive = PlanningMode("interactive") // user comments each round through stdin (local use only)
+	PlanningModeAuto        = PlanningMode("auto")        // accept the plan once the refiner returns CORRECT
+	PlanningModeApproval    = PlanningMode("approval")    // refiner CORRECT + explicit approval through the REST API
+	PlanningModeFailFast    = PlanningMode("failFast")    // fail unless the first plan is CORRECT
+)
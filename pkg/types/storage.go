@@ -0,0 +1,8 @@
+package types
+
+type StorageName string
+
+const (
+	StorageLocal = StorageName("local") // local directory
+	StorageS3    = StorageName("s3")    // AWS S3 bucket
+)
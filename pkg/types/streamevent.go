@@ -78,3 +78,19 @@ type Ask struct {
 	Price float64
 	Qty   float64
 }
+
+type MarketEventType string
+
+const (
+	MarketListed   = MarketEventType("listed")
+	MarketDelisted = MarketEventType("delisted")
+)
+
+// MarketEvent is emitted when a refresh of the markets finds a listing or a delisting
+type MarketEvent struct {
+	Type      MarketEventType
+	Time      time.Time
+	Exchange  ExchangeName
+	Symbol    string // universal symbol, empty if the market is not in the symbol map
+	LocSymbol string
+}
@@ -0,0 +1,17 @@
+package types
+
+type TaskErrorPolicy string
+
+const (
+	TaskErrorAbort = TaskErrorPolicy("abort") // stop the run (default)
+	TaskErrorSkip  = TaskErrorPolicy("skip")  // continue with the next task
+	TaskErrorRetry = TaskErrorPolicy("retry") // retry with backoff, then stop the run
+)
+
+type TaskStatus string
+
+const (
+	TaskStatusSucceeded = TaskStatus("succeeded")
+	TaskStatusFailed    = TaskStatus("failed")
+	TaskStatusNotRun    = TaskStatus("notRun") // an earlier task stopped the run
+)
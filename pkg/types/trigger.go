@@ -0,0 +1,18 @@
+package types
+
+type TriggerType string
+
+const (
+	TriggerKLineClose     = TriggerType("klineClose")     // a kline of the given interval has closed
+	TriggerMarkPriceCross = TriggerType("markPriceCross") // mark price crossed a level
+	TriggerOrderFill      = TriggerType("orderFill")      // an order has been fully filled
+	TriggerMarketListing  = TriggerType("marketListing")  // a market has been listed or delisted
+)
+
+type CrossDirection string
+
+const (
+	CrossDirectionUp   = CrossDirection("up")
+	CrossDirectionDown = CrossDirection("down")
+	CrossDirectionAny  = CrossDirection("any")
+)